@@ -1,21 +1,143 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/gin-gonic/gin"
 	"github.com/mxxmstar/learning/gate_server/gate_config"
+	grpc_server "github.com/mxxmstar/learning/gate_server/internal/grpc/server"
+	"github.com/mxxmstar/learning/gate_server/internal/server/handlers"
+	"github.com/mxxmstar/learning/gate_server/internal/server/websocket"
+	"github.com/mxxmstar/learning/pkg/logger"
 )
 
 func main() {
-	fmt.Println("Hello, World!")
+	name := flag.String("name", "gate_server_1", "gate 名称，对应配置 server.gate_servers 中的 name")
+	flag.Parse()
+
+	logger.InitLogger()
+
 	// 初始化配置
 	cfg, err := gate_config.Init()
 	if err != nil {
-		fmt.Printf("Error initializing config: %v\n", err)
-		return
+		log.Fatalf("Error initializing config: %v", err)
+	}
+	if err := cfg.UseGateServer(*name); err != nil {
+		log.Fatalf("Error selecting gate server: %v", err)
+	}
+
+	// 初始化 WebSocket 服务，连接管理、在线状态与消息投递都在其中创建
+	wsServer := websocket.InitWebSocketServer(cfg)
+
+	// 启动 gRPC 服务，供其他 gate 转发消息和后端服务推送
+	grpcServer := grpc_server.NewGRPCServer(cfg.GateServer.Name, cfg, wsServer.ConnManager(), wsServer.Router())
+	go func() {
+		if err := grpcServer.Start(); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}()
+
+	// 启动 HTTP 服务，提供 WebSocket 接入、健康检查与管理接口
+	mux := http.NewServeMux()
+	mux.Handle("/ws", wsServer)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if wsServer.Draining() {
+			http.Error(w, "draining", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	// 管理接口，配置了 token 时开启
+	if cfg.Admin.Token != "" {
+		mux.Handle("/admin/", newAdminEngine(cfg, wsServer))
+	}
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.GateServer.HttpConfig.Port),
+		Handler: mux,
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start http server: %v", err)
+		}
+	}()
+
+	// 启动 TCP 服务，接入原生客户端
+	if cfg.TCP.Enabled {
+		go func() {
+			if err := wsServer.ListenAndServeTCP(""); err != nil {
+				log.Fatalf("Failed to start tcp server: %v", err)
+			}
+		}()
 	}
 
-	// 打印配置
-	log.Printf("Config: %+v\n", cfg)
+	// 注册到 status_server 并定期上报心跳
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	reporter := newStatusReporter(cfg, wsServer.ConnManager())
+	go reporter.Run(ctx)
+
+	logger.FormatLog(ctx, "info", fmt.Sprintf("gate %s started", cfg.GateServer.Name))
+
+	// 收到退出信号后排空，再次收到信号时立即关闭剩余连接
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.Drain.Window+cfg.Drain.Timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-drainCtx.Done():
+		}
+	}()
+
+	shutdown(drainCtx, cfg, reporter, wsServer, grpcServer, httpServer)
+}
+
+// shutdown 排空连接后依次停止各个服务
+func shutdown(ctx context.Context, cfg *gate_config.Config, reporter *statusReporter, wsServer *websocket.WebsocketServer, grpcServer *grpc_server.GRPCServer, httpServer *http.Server) {
+	// 先通知 status_server 不再分配客户端，再拒绝新连接
+	reporter.SetStatus(ctx, statusDraining)
+
+	// 重连目标通过服务发现查询，排空中的 gate 不会被返回
+	wsServer.Drain(ctx, websocket.DrainOptions{Window: cfg.Drain.Window})
+	wsServer.Close()
+
+	// 停止 gRPC 服务，超时后强制关闭
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.Stop()
+		close(stopped)
+	}()
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), cfg.Drain.Timeout)
+	defer cancel()
+	select {
+	case <-stopped:
+	case <-timeoutCtx.Done():
+		logger.FormatLog(timeoutCtx, "warn", "gRPC server graceful stop timed out")
+	}
+
+	if err := httpServer.Shutdown(timeoutCtx); err != nil {
+		logger.FormatLog(timeoutCtx, "warn", fmt.Sprintf("http server shutdown: %v", err))
+	}
+	logger.FormatLog(context.Background(), "info", fmt.Sprintf("gate %s stopped", cfg.GateServer.Name))
+}
+
+// newAdminEngine 创建管理接口的 gin 服务，挂载在 /admin 下
+func newAdminEngine(cfg *gate_config.Config, wsServer *websocket.WebsocketServer) *gin.Engine {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	adminHandler := handlers.NewAdminHandler(wsServer.GateId(), wsServer.ConnManager(), wsServer.Router())
+	adminHandler.RegisterRoutes(engine.Group("/admin", handlers.AdminAuth(cfg.Admin.Token)))
+	return engine
 }
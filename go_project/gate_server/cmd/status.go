@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	http_status_client "github.com/mxxmstar/learning/gate_server/internal/http/status"
+	status_def "github.com/mxxmstar/learning/pkg/def/status"
+	"github.com/mxxmstar/learning/pkg/logger"
+	status_model "github.com/mxxmstar/learning/pkg/model/status"
+)
+
+const (
+	statusActive   = http_status_client.StatusActive
+	statusDraining = http_status_client.StatusDraining
+
+	// 心跳间隔，小于 status_server 的心跳超时时间
+	heartbeatInterval = status_model.HeartbeatInterval * time.Second / 3
+)
+
+// statusReporter 向 status_server 注册 gate 并定期上报心跳、状态与连接数
+// status_server 按连接数与最大连接数的比例选择负载最低的 gate
+type statusReporter struct {
+	cfg       *gate_config.Config
+	client    *http_status_client.StatusClient
+	mgr       conn.ConnectionManager
+	mu        sync.Mutex
+	serviceId string
+	status    string
+}
+
+func newStatusReporter(cfg *gate_config.Config, mgr conn.ConnectionManager) *statusReporter {
+	return &statusReporter{
+		cfg:       cfg,
+		client:    http_status_client.NewStatusClient(*cfg, &http.Client{Timeout: 5 * time.Second}),
+		mgr:       mgr,
+		serviceId: cfg.GateServer.Name,
+		status:    statusActive,
+	}
+}
+
+// Run 注册后定期上报心跳，注册失败时在下一次心跳重试
+func (r *statusReporter) Run(ctx context.Context) {
+	registered := r.register(ctx)
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !registered {
+				registered = r.register(ctx)
+				continue
+			}
+			// 心跳失败（如 status_server 重启后丢失注册信息）时重新注册
+			registered = r.heartbeat(ctx)
+		}
+	}
+}
+
+// SetStatus 修改上报的状态并立即上报
+func (r *statusReporter) SetStatus(ctx context.Context, status string) {
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+	r.heartbeat(ctx)
+}
+
+func (r *statusReporter) register(ctx context.Context) bool {
+	gate := r.cfg.GateServer
+	req := &status_def.ServiceRegisterRequest{
+		ServiceName: gate.Name,
+		ServiceType: http_status_client.GateServiceType,
+		ServiceId:   gate.Name,
+		Protocol:    []string{"grpc", "http", "websocket"},
+		GRPCAddress: &status_def.GRPCAddress{Host: gate.GRPCConfig.Host, Port: gate.GRPCConfig.Port},
+		HTTPAddress: &status_def.HTTPAddress{Host: gate.HttpConfig.Host, Port: gate.HttpConfig.Port},
+		Env:         r.cfg.ServerConfig.GlobalConfig.Env,
+		Weight:      gate.ServiceConfig.Weight,
+		Enable:      true,
+		Idc:         gate.ServiceConfig.Zone,
+	}
+	if r.cfg.TCP.Enabled {
+		req.Protocol = append(req.Protocol, "tcp")
+		req.Metadata = map[string]string{"tcp_addr": r.cfg.TCP.Addr}
+	}
+
+	serviceId, err := http_status_client.RegisterService(ctx, r.client, req)
+	if err != nil {
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("register to status server failed: %v", err))
+		return false
+	}
+	r.mu.Lock()
+	r.serviceId = serviceId
+	r.mu.Unlock()
+	return true
+}
+
+func (r *statusReporter) heartbeat(ctx context.Context) bool {
+	r.mu.Lock()
+	serviceId, status := r.serviceId, r.status
+	r.mu.Unlock()
+	load := r.mgr.Stats().Connections
+	if err := http_status_client.SendHeartbeat(ctx, r.client, http_status_client.GateServiceType, serviceId, status, load, r.cfg.Admission.MaxConns); err != nil {
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("heartbeat to status server failed: %v", err))
+		return false
+	}
+	return true
+}
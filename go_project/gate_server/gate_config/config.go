@@ -2,6 +2,7 @@ package gate_config
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/mxxmstar/learning/pkg/config"
@@ -10,8 +11,100 @@ import (
 type Config struct {
 	// 服务器配置
 	ServerConfig *config.ServerConfig `mapstructure:"server"`
+	// 当前 gate 的配置，启动时通过 UseGateServer 按名称选择
+	GateServer *config.GateServerConfig `mapstructure:"-"`
+	// redis 配置，用于订阅凭证吊销事件
+	Redis config.RedisConfig `mapstructure:"redis"`
 	// GateServer 特有配置
 	WebSocketConfig WebSocketConfig `mapstructure:"websocket_config"`
+	// 原生客户端的 TCP 接入
+	TCP TCPConfig `mapstructure:"tcp_config"`
+	// 用户验证配置
+	AuthConfig AuthConfig `mapstructure:"auth_config"`
+	// 多端登录策略
+	LoginPolicy LoginPolicyConfig `mapstructure:"login_policy"`
+	// 在线状态配置
+	Presence PresenceConfig `mapstructure:"presence"`
+	// 可靠投递配置
+	Reliable ReliableConfig `mapstructure:"reliable"`
+	// 上行消息限流
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	// 排空配置，用于滚动发布
+	Drain DrainConfig `mapstructure:"drain"`
+	// 连接数上限
+	Admission AdmissionConfig `mapstructure:"admission"`
+	// 管理接口配置
+	Admin AdminConfig `mapstructure:"admin"`
+	// 主题订阅权限
+	Topic TopicConfig `mapstructure:"topic"`
+}
+
+type TopicConfig struct {
+	PublicPrefixes []string `mapstructure:"public_prefixes"` // 已认证连接都可以订阅的主题前缀，其他主题只能订阅自己的 user:<用户 Id>
+}
+
+type AdmissionConfig struct {
+	MaxConns        int           `mapstructure:"max_conns"`          // 本 gate 的最大连接数，超过时返回 503，0 表示不限制，同时作为最大负载上报给 status_server
+	MaxConnsPerIP   int           `mapstructure:"max_conns_per_ip"`   // 每个来源 IP 的最大连接数，超过时返回 429，0 表示不限制
+	MaxConnsPerUser int           `mapstructure:"max_conns_per_user"` // 每个用户在本 gate 上的最大连接数，认证后检查，0 表示不限制
+	RedirectTTL     time.Duration `mapstructure:"redirect_ttl"`       // 从 status_server 查询的负载较低 gate 的缓存时间
+}
+
+type AdminConfig struct {
+	Token string `mapstructure:"token"` // 管理接口的 Bearer token，为空时不开启管理接口
+}
+
+type DrainConfig struct {
+	Window  time.Duration `mapstructure:"window"`  // 在该时间内逐步关闭所有连接，避免客户端同时重连
+	Timeout time.Duration `mapstructure:"timeout"` // 排空后等待 gRPC 等服务停止的最长时间
+}
+
+type RateLimitConfig struct {
+	Enabled         bool           `mapstructure:"enabled"`
+	Conn            RateLimitRules `mapstructure:"conn"`             // 每个连接的上行消息额度
+	User            RateLimitRules `mapstructure:"user"`             // 每个用户在本 gate 上所有连接的上行消息额度
+	IP              RateLimit      `mapstructure:"ip"`               // 每个来源 IP 建立连接的频率
+	MaxViolations   int            `mapstructure:"max_violations"`   // 窗口内超限次数达到该值时断开连接，0 表示不断开
+	ViolationWindow time.Duration  `mapstructure:"violation_window"` // 超限次数的统计窗口
+}
+
+type RateLimitRules struct {
+	Default RateLimit            `mapstructure:"default"` // 未单独配置的消息类型共用的额度
+	Types   map[string]RateLimit `mapstructure:"types"`   // 按消息类型单独计算的额度
+}
+
+type RateLimit struct {
+	Rate  float64 `mapstructure:"rate"`  // 每秒补充的令牌数，不大于 0 表示不限制
+	Burst int     `mapstructure:"burst"` // 令牌桶容量，允许的突发数量
+}
+
+type ReliableConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`     // 是否为推送消息分配 seq 并支持断线补发
+	BufferSize int           `mapstructure:"buffer_size"` // 每个设备最多保存的未确认消息数
+	BufferTTL  time.Duration `mapstructure:"buffer_ttl"`  // 未确认消息的保存时间
+}
+
+type PresenceConfig struct {
+	ConnTTL     time.Duration `mapstructure:"conn_ttl"`      // 连接在线记录的过期时间，由心跳续期，需大于心跳间隔
+	LastSeenTTL time.Duration `mapstructure:"last_seen_ttl"` // 最后在线时间的保留时间
+}
+
+type LoginPolicyConfig struct {
+	Default     string            `mapstructure:"default"`      // 默认登录策略 multi/per_device_type/single
+	DeviceTypes map[string]string `mapstructure:"device_types"` // 按设备类型覆盖默认策略，如 mobile: single
+}
+
+type AuthConfig struct {
+	Mode                string        `mapstructure:"mode"`                  // 验证方式 grpc/http/local，local 在本地验签 jwt，失败时回退到 grpc
+	VerdictCacheSize    int           `mapstructure:"verdict_cache_size"`    // 验证结果缓存容量
+	VerdictCacheTTL     time.Duration `mapstructure:"verdict_cache_ttl"`     // 验证结果缓存时间，必须小于 session 有效期
+	JWKSRefreshInterval time.Duration `mapstructure:"jwks_refresh_interval"` // jwt 验签公钥刷新间隔
+}
+
+type TCPConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`        // 是否监听 TCP 端口
+	Addr         string `mapstructure:"addr"`           // 监听地址
+	MaxFrameSize int    `mapstructure:"max_frame_size"` // 最大帧长度，不含 4 字节长度前缀
 }
 
 type WebSocketConfig struct {
@@ -21,6 +114,13 @@ type WebSocketConfig struct {
 	PingWait        time.Duration `mapstructure:"ping_wait"`         // ping 超时
 	PongWait        time.Duration `mapstructure:"pong_wait"`         // pong 超时
 	MaxMessageSize  int           `mapstructure:"max_message_size"`  // 最大消息长度
+	SendQueueSize   int           `mapstructure:"send_queue_size"`   // 每个连接的发送队列长度
+	OverflowPolicy  string        `mapstructure:"overflow_policy"`   // 发送队列满时的策略 block/drop_oldest/drop_newest/disconnect
+	SendTimeout     time.Duration `mapstructure:"send_timeout"`      // block 策略的最长等待时间
+	HandlerTimeout  time.Duration `mapstructure:"handler_timeout"`   // 每条消息的处理超时时间，0 表示不限制
+	Workers         int           `mapstructure:"workers"`           // 同时处理的最大消息数，0 表示在读协程中同步处理
+	WorkerQueueSize int           `mapstructure:"worker_queue_size"` // 每个连接排队与处理中的最大消息数，超过时拒绝该连接的新消息
+	ConnShards      int           `mapstructure:"conn_shards"`       // 连接管理器的分片数，1 表示使用单锁实现
 }
 
 func Init() (*Config, error) {
@@ -31,6 +131,7 @@ func Init() (*Config, error) {
 
 	cfg := &Config{
 		ServerConfig: &baseCfg.Server,
+		Redis:        baseCfg.Redis,
 		WebSocketConfig: WebSocketConfig{
 			AuthTimeout:     5 * time.Second,
 			ReadBufferSize:  1024,
@@ -38,11 +139,98 @@ func Init() (*Config, error) {
 			PingWait:        60 * time.Second,
 			PongWait:        60 * time.Second,
 			MaxMessageSize:  1024 * 1024, // 1M
+			SendQueueSize:   256,
+			OverflowPolicy:  "drop_newest",
+			SendTimeout:     100 * time.Millisecond,
+			HandlerTimeout:  10 * time.Second,
+			Workers:         64,
+			WorkerQueueSize: 64,
+			ConnShards:      64,
+		},
+		TCP: TCPConfig{
+			Enabled:      false,
+			Addr:         ":7100",
+			MaxFrameSize: 64 * 1024,
+		},
+		AuthConfig: AuthConfig{
+			Mode:                "local",
+			VerdictCacheSize:    10000,
+			VerdictCacheTTL:     time.Minute,
+			JWKSRefreshInterval: 5 * time.Minute,
+		},
+		LoginPolicy: LoginPolicyConfig{
+			Default: "per_device_type",
+		},
+		Presence: PresenceConfig{
+			ConnTTL:     90 * time.Second,
+			LastSeenTTL: 30 * 24 * time.Hour,
+		},
+		Reliable: ReliableConfig{
+			Enabled:    true,
+			BufferSize: 1000,
+			BufferTTL:  24 * time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Conn: RateLimitRules{
+				Default: RateLimit{Rate: 20, Burst: 40},
+				Types: map[string]RateLimit{
+					"signup": {Rate: 0.1, Burst: 3},
+					"login":  {Rate: 0.5, Burst: 5},
+					"ack":    {Rate: 100, Burst: 200}, // 确认推送消息，额度与推送速率相当
+					"pong":   {Rate: 1, Burst: 5},
+				},
+			},
+			User: RateLimitRules{
+				Default: RateLimit{Rate: 50, Burst: 100},
+			},
+			IP:              RateLimit{Rate: 5, Burst: 20},
+			MaxViolations:   20,
+			ViolationWindow: 10 * time.Second,
+		},
+		Drain: DrainConfig{
+			Window:  60 * time.Second,
+			Timeout: 10 * time.Second,
+		},
+		Admission: AdmissionConfig{
+			MaxConns:        100000,
+			MaxConnsPerIP:   100,
+			MaxConnsPerUser: 10,
+			RedirectTTL:     10 * time.Second,
+		},
+		Topic: TopicConfig{
+			PublicPrefixes: []string{"public:"},
 		},
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
+// overflowPolicies 发送队列满时可选的策略，与 conn.OverflowPolicy 一致
+var overflowPolicies = []string{"block", "drop_oldest", "drop_newest", "disconnect"}
+
+// loginPolicies 可选的多端登录策略，与 online.LoginPolicy 一致
+var loginPolicies = []string{"multi", "per_device_type", "single"}
+
+// Validate 检查配置的取值，启动时拒绝未知的取值
+func (c *Config) Validate() error {
+	if !slices.Contains(overflowPolicies, c.WebSocketConfig.OverflowPolicy) {
+		return fmt.Errorf("websocket_config.overflow_policy: unknown policy %q, expected one of %v", c.WebSocketConfig.OverflowPolicy, overflowPolicies)
+	}
+	// 默认策略为空时按 multi 处理
+	if p := c.LoginPolicy.Default; p != "" && !slices.Contains(loginPolicies, p) {
+		return fmt.Errorf("login_policy.default: unknown policy %q, expected one of %v", p, loginPolicies)
+	}
+	for deviceType, p := range c.LoginPolicy.DeviceTypes {
+		if !slices.Contains(loginPolicies, p) {
+			return fmt.Errorf("login_policy.device_types.%s: unknown policy %q, expected one of %v", deviceType, p, loginPolicies)
+		}
+	}
+	return nil
+}
+
 func (c *Config) GetGateServer(serverName string) *config.GateServerConfig {
 	for _, server := range c.ServerConfig.GateServers {
 		if server.Name == serverName {
@@ -52,6 +240,16 @@ func (c *Config) GetGateServer(serverName string) *config.GateServerConfig {
 	return nil
 }
 
+// UseGateServer 选择当前 gate 的配置
+func (c *Config) UseGateServer(serverName string) error {
+	gateServer := c.GetGateServer(serverName)
+	if gateServer == nil {
+		return fmt.Errorf("gate server %s not found in config", serverName)
+	}
+	c.GateServer = gateServer
+	return nil
+}
+
 func (c *Config) GetAllGateServersAddress() []string {
 	var gateServerAddress []string
 	for _, server := range c.ServerConfig.GateServers {
@@ -78,6 +276,17 @@ func (c *Config) GetVerifyHttpAddress(serverName string) string {
 	return ""
 }
 
+// VerifyServer 选择 verify 服务，优先使用状态为 active 的服务
+func (c *Config) VerifyServer() (*config.VerifyServerConfig, error) {
+	if active := c.GetActiveVerifyServers(); len(active) > 0 {
+		return &active[0], nil
+	}
+	if len(c.ServerConfig.VerifyServers) > 0 {
+		return &c.ServerConfig.VerifyServers[0], nil
+	}
+	return nil, fmt.Errorf("no verify server in config")
+}
+
 func (c *Config) GetActiveVerifyServers() []config.VerifyServerConfig {
 	var activeVerifyServers []config.VerifyServerConfig
 	for _, server := range c.ServerConfig.VerifyServers {
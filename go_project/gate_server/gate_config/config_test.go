@@ -0,0 +1,30 @@
+package gate_config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOverflowPolicy(t *testing.T) {
+	for _, policy := range overflowPolicies {
+		cfg := &Config{WebSocketConfig: WebSocketConfig{OverflowPolicy: policy}}
+		assert.NoError(t, cfg.Validate(), policy)
+	}
+	for _, policy := range []string{"", "drop", "Block"} {
+		cfg := &Config{WebSocketConfig: WebSocketConfig{OverflowPolicy: policy}}
+		assert.Error(t, cfg.Validate(), "未知的溢出策略应该被拒绝: %q", policy)
+	}
+}
+
+func TestValidateLoginPolicy(t *testing.T) {
+	newConfig := func(policy LoginPolicyConfig) *Config {
+		return &Config{WebSocketConfig: WebSocketConfig{OverflowPolicy: "block"}, LoginPolicy: policy}
+	}
+	assert.NoError(t, newConfig(LoginPolicyConfig{}).Validate(), "未配置时默认 multi")
+	for _, policy := range loginPolicies {
+		assert.NoError(t, newConfig(LoginPolicyConfig{Default: policy, DeviceTypes: map[string]string{"mobile": policy}}).Validate(), policy)
+	}
+	assert.Error(t, newConfig(LoginPolicyConfig{Default: "singel"}).Validate(), "拼错的默认策略应该被拒绝")
+	assert.Error(t, newConfig(LoginPolicyConfig{DeviceTypes: map[string]string{"mobile": ""}}).Validate(), "设备类型的策略不能为空")
+}
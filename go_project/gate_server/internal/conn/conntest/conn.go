@@ -0,0 +1,75 @@
+// Package conntest 提供测试用的假连接，实现 conn.Connection 与 conn.Kicker
+// 不能导入 conn 包，conn 包自己的测试也需要使用
+package conntest
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrClosed 向已关闭的连接发送消息
+var ErrClosed = errors.New("connection closed")
+
+// Conn 记录发送的消息与关闭、踢下线原因的假连接
+type Conn struct {
+	id     string
+	userId uint64
+
+	mu     sync.Mutex
+	sent   [][]byte
+	closed string
+	kicked string
+}
+
+func New(id string, userId uint64) *Conn {
+	return &Conn{id: id, userId: userId}
+}
+
+func (c *Conn) Id() string     { return c.id }
+func (c *Conn) UserId() uint64 { return c.userId }
+
+// Send 记录消息，连接关闭后返回 ErrClosed
+func (c *Conn) Send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed != "" {
+		return ErrClosed
+	}
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func (c *Conn) Close(reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = reason
+	return nil
+}
+
+func (c *Conn) Kick(reason string) error {
+	c.mu.Lock()
+	c.kicked = reason
+	c.mu.Unlock()
+	return c.Close(reason)
+}
+
+// Sent 已发送的消息
+func (c *Conn) Sent() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sent
+}
+
+// Closed 关闭原因，未关闭时为空
+func (c *Conn) Closed() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Kicked 踢下线原因，未被踢下线时为空
+func (c *Conn) Kicked() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.kicked
+}
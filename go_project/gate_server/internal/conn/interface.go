@@ -6,6 +6,8 @@ var (
 	ErrInvalidConnection  = errors.New("invalid connection")
 	ErrConnectionNotFound = errors.New("connection not found")
 	ErrConnectionClosed   = errors.New("connection closed")
+	ErrInvalidTopic       = errors.New("invalid topic")
+	ErrTooManyTopics      = errors.New("too many topics")
 )
 
 // Connection 定义连接接口
@@ -18,10 +20,60 @@ type Connection interface {
 }
 
 // ConnectionManager 定义连接管理器接口
-// 连接管理器接口定义了连接的注册、注销、获取连接和根据用户Id获取连接的操作
+// 连接管理器接口定义了连接的注册、注销、获取连接、根据用户Id获取连接和获取所有连接的操作
+// 以及主题的订阅、取消订阅与发布，连接注销时自动退出订阅的所有主题
 type ConnectionManager interface {
 	Register(conn Connection) error
 	UnRegister(conn Connection) error
 	GetConnection(connId string) (Connection, error)
 	GetConnectionsByUserId(userId uint64) []Connection
+	GetAllConnections() []Connection
+	// Range 遍历所有连接的快照，fn 返回 false 时停止，遍历期间不阻塞连接的注册与注销
+	Range(fn func(conn Connection) bool)
+	// Stats 连接、用户与主题的总数
+	Stats() ManagerStats
+
+	// Subscribe 将连接加入主题，重复订阅不报错
+	Subscribe(connId, topic string) error
+	// Unsubscribe 将连接移出主题，未订阅时不报错
+	Unsubscribe(connId, topic string) error
+	// Publish 发送到订阅了主题的所有连接，返回成功与失败的连接数
+	Publish(topic string, msg []byte) (delivered, failed int64)
+	// TopicMembers 主题的订阅连接数
+	TopicMembers(topic string) int
+	// TopicsOf 连接订阅的所有主题
+	TopicsOf(connId string) []string
+}
+
+// ManagerStats 连接管理器的统计
+type ManagerStats struct {
+	Connections int `json:"connections"` // 连接数
+	Users       int `json:"users"`       // 有连接的用户数
+	Topics      int `json:"topics"`      // 有订阅者的主题数
+}
+
+// Kicker 被踢下线前可以通知客户端的连接
+type Kicker interface {
+	Kick(reason string) error
+}
+
+// Kick 踢掉连接，支持 Kicker 的连接会先通知客户端再关闭
+func Kick(c Connection, reason string) error {
+	if k, ok := c.(Kicker); ok {
+		return k.Kick(reason)
+	}
+	return c.Close(reason)
+}
+
+// ReliableSender 支持可靠投递的连接，消息会分配 seq 并保存到客户端确认
+type ReliableSender interface {
+	SendReliable(msg []byte) error
+}
+
+// SendReliable 可靠投递消息，连接不支持时退化为普通发送
+func SendReliable(c Connection, msg []byte) error {
+	if r, ok := c.(ReliableSender); ok {
+		return r.SendReliable(msg)
+	}
+	return c.Send(msg)
 }
@@ -7,13 +7,37 @@ type manager struct {
 	conns map[string]Connection
 	// 用户连接注册表，管理每个用户的多端链接，内层的key与conn一致
 	userConns map[uint64]map[string]Connection
-	mu        sync.RWMutex
+	// 主题订阅表，内层的key与conn一致
+	topics map[string]map[string]Connection
+	// 连接订阅的主题，注销时据此退出所有主题
+	connTopics map[string]map[string]struct{}
+	// 主题订阅数变化时的回调
+	observer TopicObserver
+	mu       sync.RWMutex
 }
 
-func NewManager() ConnectionManager {
+// Option 连接管理器的可选配置
+type Option func(*options)
+
+type options struct {
+	observer TopicObserver
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func NewManager(opts ...Option) ConnectionManager {
 	return &manager{
-		conns:     make(map[string]Connection),
-		userConns: make(map[uint64]map[string]Connection),
+		conns:      make(map[string]Connection),
+		userConns:  make(map[uint64]map[string]Connection),
+		topics:     make(map[string]map[string]Connection),
+		connTopics: make(map[string]map[string]struct{}),
+		observer:   newOptions(opts).observer,
 	}
 }
 
@@ -52,6 +76,10 @@ func (m *manager) UnRegister(conn Connection) error {
 			delete(m.userConns, userId)
 		}
 	}
+	for topic := range m.connTopics[connId] {
+		m.leave(connId, topic)
+	}
+	delete(m.connTopics, connId)
 
 	return nil
 }
@@ -79,3 +107,35 @@ func (m *manager) GetConnectionsByUserId(userId uint64) []Connection {
 
 	return conns
 }
+
+func (m *manager) GetAllConnections() []Connection {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	conns := make([]Connection, 0, len(m.conns))
+	for _, conn := range m.conns {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
+
+// Range 单锁实现只能先复制全部连接，在锁外调用 fn
+func (m *manager) Range(fn func(conn Connection) bool) {
+	for _, conn := range m.GetAllConnections() {
+		if !fn(conn) {
+			return
+		}
+	}
+}
+
+func (m *manager) Stats() ManagerStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return ManagerStats{
+		Connections: len(m.conns),
+		Users:       len(m.userConns),
+		Topics:      len(m.topics),
+	}
+}
@@ -0,0 +1,29 @@
+package conn
+
+import "errors"
+
+// ErrSendQueueFull 发送队列已满，消息按溢出策略被丢弃或连接被断开
+var ErrSendQueueFull = errors.New("send queue full")
+
+// OverflowPolicy 发送队列满时的处理策略
+type OverflowPolicy string
+
+const (
+	OverflowBlock      OverflowPolicy = "block"       // 阻塞等待，超时后丢弃新消息
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // 丢弃队列中最早的消息
+	OverflowDropNewest OverflowPolicy = "drop_newest" // 丢弃新消息
+	OverflowDisconnect OverflowPolicy = "disconnect"  // 断开慢消费者
+)
+
+// QueueStats 连接发送队列的统计
+type QueueStats struct {
+	Depth    int    `json:"depth"`    // 当前队列长度
+	Capacity int    `json:"capacity"` // 队列容量
+	Sent     uint64 `json:"sent"`     // 已写入连接的消息数
+	Dropped  uint64 `json:"dropped"`  // 因队列满被丢弃的消息数
+}
+
+// QueueStatsProvider 可以提供发送队列统计的连接
+type QueueStatsProvider interface {
+	QueueStats() QueueStats
+}
@@ -0,0 +1,41 @@
+package conn
+
+import (
+	"time"
+
+	"github.com/mxxmstar/learning/pkg/revocation"
+)
+
+// Credential 连接认证时使用的凭证，凭证被吊销时用于找到受影响的连接
+type Credential struct {
+	TokenId   string    // jwt 的 jti，session 认证时为空
+	SessionId string    // session Id，jwt 认证时为空
+	IssuedAt  time.Time // jwt 签发时间，session 认证时为认证时间
+}
+
+// CredentialHolder 记录了认证凭证的连接
+type CredentialHolder interface {
+	Credential() Credential
+}
+
+// CloseRevoked 关闭受吊销事件影响的连接，返回关闭的连接数
+// 没有记录认证凭证的连接只会在用户级吊销时被关闭
+func CloseRevoked(mgr ConnectionManager, event revocation.Event) int {
+	closed := 0
+	for _, c := range mgr.GetConnectionsByUserId(event.UserId) {
+		holder, ok := c.(CredentialHolder)
+		if !ok {
+			if event.Type != revocation.EventUser {
+				continue
+			}
+		} else {
+			cred := holder.Credential()
+			if !event.Matches(cred.TokenId, cred.SessionId, cred.IssuedAt) {
+				continue
+			}
+		}
+		_ = c.Close("credential revoked")
+		closed++
+	}
+	return closed
+}
@@ -0,0 +1,339 @@
+package conn
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// 默认分片数
+const DefaultShards = 64
+
+// shardedManager 分片加锁的连接管理器，连接、用户与主题分别按 key 分片，注册与查询只锁定所在的分片
+// 同时需要多个分片时按 连接分片 -> 用户分片/主题分片 的顺序加锁，不会死锁
+type shardedManager struct {
+	mask   uint64
+	conns  []connShard
+	users  []userShard
+	topics []topicShard
+	// 主题订阅数变化时的回调，在主题分片的锁内调用，同一主题的变化按顺序通知
+	observer TopicObserver
+}
+
+type connShard struct {
+	mu    sync.RWMutex
+	conns map[string]Connection
+	// 连接订阅的主题，注销时据此退出所有主题
+	topics map[string]map[string]struct{}
+}
+
+type userShard struct {
+	mu    sync.RWMutex
+	users map[uint64]map[string]Connection
+}
+
+type topicShard struct {
+	mu     sync.RWMutex
+	topics map[string]map[string]Connection
+}
+
+// NewShardedManager 创建分片的连接管理器，shards 向上取整为 2 的幂，小于 1 时使用 DefaultShards
+// 适用于单个 gate 承载大量连接的场景，遍历所有连接时逐个分片复制
+func NewShardedManager(shards int, opts ...Option) ConnectionManager {
+	if shards < 1 {
+		shards = DefaultShards
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+
+	m := &shardedManager{
+		mask:     uint64(n - 1),
+		conns:    make([]connShard, n),
+		users:    make([]userShard, n),
+		topics:   make([]topicShard, n),
+		observer: newOptions(opts).observer,
+	}
+	for i := 0; i < n; i++ {
+		m.conns[i].conns = make(map[string]Connection)
+		m.conns[i].topics = make(map[string]map[string]struct{})
+		m.users[i].users = make(map[uint64]map[string]Connection)
+		m.topics[i].topics = make(map[string]map[string]Connection)
+	}
+	return m
+}
+
+func (m *shardedManager) connShard(connId string) *connShard {
+	return &m.conns[hashString(connId)&m.mask]
+}
+
+func (m *shardedManager) userShard(userId uint64) *userShard {
+	// 用户 Id 通常连续递增，混合高位后再取模
+	h := userId * 0x9e3779b97f4a7c15
+	return &m.users[(h>>32)&m.mask]
+}
+
+func (m *shardedManager) topicShard(topic string) *topicShard {
+	return &m.topics[hashString(topic)&m.mask]
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func (m *shardedManager) Register(conn Connection) error {
+	connId := conn.Id()
+	userId := conn.UserId()
+
+	cs := m.connShard(connId)
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.conns[connId] = conn
+
+	us := m.userShard(userId)
+	us.mu.Lock()
+	if _, exists := us.users[userId]; !exists {
+		us.users[userId] = make(map[string]Connection)
+	}
+	us.users[userId][connId] = conn
+	us.mu.Unlock()
+
+	return nil
+}
+
+func (m *shardedManager) UnRegister(conn Connection) error {
+	connId := conn.Id()
+	userId := conn.UserId()
+
+	cs := m.connShard(connId)
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if _, ok := cs.conns[connId]; !ok {
+		return ErrConnectionNotFound
+	}
+	delete(cs.conns, connId)
+
+	us := m.userShard(userId)
+	us.mu.Lock()
+	if userMap, exists := us.users[userId]; exists {
+		delete(userMap, connId)
+		if len(userMap) == 0 {
+			delete(us.users, userId)
+		}
+	}
+	us.mu.Unlock()
+
+	for topic := range cs.topics[connId] {
+		m.leave(connId, topic)
+	}
+	delete(cs.topics, connId)
+
+	return nil
+}
+
+func (m *shardedManager) GetConnection(connId string) (Connection, error) {
+	cs := m.connShard(connId)
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	conn, ok := cs.conns[connId]
+	if !ok {
+		return nil, ErrConnectionNotFound
+	}
+
+	return conn, nil
+}
+
+func (m *shardedManager) GetConnectionsByUserId(userId uint64) []Connection {
+	us := m.userShard(userId)
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	var conns []Connection
+	for _, conn := range us.users[userId] {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
+
+func (m *shardedManager) GetAllConnections() []Connection {
+	var conns []Connection
+	for i := range m.conns {
+		cs := &m.conns[i]
+		cs.mu.RLock()
+		for _, conn := range cs.conns {
+			conns = append(conns, conn)
+		}
+		cs.mu.RUnlock()
+	}
+	return conns
+}
+
+// Range 逐个分片复制后在锁外调用 fn，同一时刻最多锁定一个分片
+// 遍历期间注册与注销的连接可能遍历到也可能遍历不到
+func (m *shardedManager) Range(fn func(conn Connection) bool) {
+	var snapshot []Connection
+	for i := range m.conns {
+		cs := &m.conns[i]
+		cs.mu.RLock()
+		snapshot = snapshot[:0]
+		for _, conn := range cs.conns {
+			snapshot = append(snapshot, conn)
+		}
+		cs.mu.RUnlock()
+
+		for _, conn := range snapshot {
+			if !fn(conn) {
+				return
+			}
+		}
+	}
+}
+
+// Stats 逐个分片累加，各分片的计数不是同一时刻的快照
+func (m *shardedManager) Stats() ManagerStats {
+	var stats ManagerStats
+	for i := range m.conns {
+		m.conns[i].mu.RLock()
+		stats.Connections += len(m.conns[i].conns)
+		m.conns[i].mu.RUnlock()
+
+		m.users[i].mu.RLock()
+		stats.Users += len(m.users[i].users)
+		m.users[i].mu.RUnlock()
+
+		m.topics[i].mu.RLock()
+		stats.Topics += len(m.topics[i].topics)
+		m.topics[i].mu.RUnlock()
+	}
+	return stats
+}
+
+func (m *shardedManager) Subscribe(connId, topic string) error {
+	if topic == "" || len(topic) > maxTopicLen {
+		return ErrInvalidTopic
+	}
+
+	cs := m.connShard(connId)
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	conn, ok := cs.conns[connId]
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	subscribed := cs.topics[connId]
+	if _, exists := subscribed[topic]; exists {
+		return nil
+	}
+	if len(subscribed) >= MaxTopicsPerConn {
+		return ErrTooManyTopics
+	}
+
+	if subscribed == nil {
+		subscribed = make(map[string]struct{})
+		cs.topics[connId] = subscribed
+	}
+	subscribed[topic] = struct{}{}
+
+	// 持有连接分片的锁加入主题，与注销互斥，不会留下已注销的订阅者
+	ts := m.topicShard(topic)
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if _, exists := ts.topics[topic]; !exists {
+		ts.topics[topic] = make(map[string]Connection)
+	}
+	ts.topics[topic][connId] = conn
+	m.notify(ts, topic)
+
+	return nil
+}
+
+func (m *shardedManager) Unsubscribe(connId, topic string) error {
+	cs := m.connShard(connId)
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if _, ok := cs.conns[connId]; !ok {
+		return ErrConnectionNotFound
+	}
+	subscribed := cs.topics[connId]
+	if _, exists := subscribed[topic]; !exists {
+		return nil
+	}
+	delete(subscribed, topic)
+	if len(subscribed) == 0 {
+		delete(cs.topics, connId)
+	}
+	m.leave(connId, topic)
+
+	return nil
+}
+
+func (m *shardedManager) Publish(topic string, msg []byte) (delivered, failed int64) {
+	ts := m.topicShard(topic)
+	ts.mu.RLock()
+	conns := make([]Connection, 0, len(ts.topics[topic]))
+	for _, conn := range ts.topics[topic] {
+		conns = append(conns, conn)
+	}
+	ts.mu.RUnlock()
+
+	for _, conn := range conns {
+		if err := conn.Send(msg); err != nil {
+			failed++
+			continue
+		}
+		delivered++
+	}
+	return delivered, failed
+}
+
+func (m *shardedManager) TopicMembers(topic string) int {
+	ts := m.topicShard(topic)
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	return len(ts.topics[topic])
+}
+
+func (m *shardedManager) TopicsOf(connId string) []string {
+	cs := m.connShard(connId)
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	topics := make([]string, 0, len(cs.topics[connId]))
+	for topic := range cs.topics[connId] {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// leave 将连接移出主题，调用方需持有连接所在分片的写锁并自行维护订阅的主题
+func (m *shardedManager) leave(connId, topic string) {
+	ts := m.topicShard(topic)
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	members, ok := ts.topics[topic]
+	if !ok {
+		return
+	}
+	delete(members, connId)
+	if len(members) == 0 {
+		delete(ts.topics, topic)
+	}
+	m.notify(ts, topic)
+}
+
+// notify 调用方需持有主题分片的写锁
+func (m *shardedManager) notify(ts *topicShard, topic string) {
+	if m.observer != nil {
+		m.observer(topic, len(ts.topics[topic]))
+	}
+}
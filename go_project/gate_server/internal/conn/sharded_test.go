@@ -0,0 +1,125 @@
+package conn
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedManager(t *testing.T) {
+	mgr := NewShardedManager(8)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, mgr.Register(conntest.New(fmt.Sprintf("c%d", i), uint64(i%10))))
+	}
+	assert.Len(t, mgr.GetAllConnections(), 100)
+	assert.Len(t, mgr.GetConnectionsByUserId(3), 10, "应该按用户索引连接")
+
+	c, err := mgr.GetConnection("c42")
+	assert.NoError(t, err)
+	assert.NoError(t, mgr.UnRegister(c))
+	assert.ErrorIs(t, mgr.UnRegister(c), ErrConnectionNotFound, "重复注销应该返回错误")
+	assert.Len(t, mgr.GetConnectionsByUserId(2), 9, "注销后用户索引应该同步删除")
+	assert.Equal(t, ManagerStats{Connections: 99, Users: 10}, mgr.Stats())
+
+	visited := 0
+	mgr.Range(func(conn Connection) bool {
+		visited++
+		return visited < 10
+	})
+	assert.Equal(t, 10, visited, "fn 返回 false 时应该停止遍历")
+}
+
+func TestRangeDoesNotBlockRegister(t *testing.T) {
+	mgr := NewShardedManager(4)
+	for i := 0; i < 16; i++ {
+		assert.NoError(t, mgr.Register(conntest.New(fmt.Sprintf("c%d", i), 1)))
+	}
+
+	// 遍历的回调中注册与注销连接不会死锁，新注册的连接可能被遍历到
+	visited := make(map[string]bool)
+	mgr.Range(func(conn Connection) bool {
+		if !visited[conn.Id()] {
+			visited[conn.Id()] = true
+			assert.NoError(t, mgr.Register(conntest.New("new"+conn.Id(), 1)))
+		}
+		_ = mgr.UnRegister(conn)
+		return true
+	})
+	for i := 0; i < 16; i++ {
+		assert.True(t, visited[fmt.Sprintf("c%d", i)], "遍历开始前注册的连接都应该被遍历到")
+		_, err := mgr.GetConnection(fmt.Sprintf("c%d", i))
+		assert.ErrorIs(t, err, ErrConnectionNotFound)
+	}
+}
+
+func benchmarkManagers(b *testing.B, fn func(b *testing.B, mgr ConnectionManager)) {
+	for name, newManager := range managers {
+		b.Run(name, func(b *testing.B) { fn(b, newManager()) })
+	}
+}
+
+func BenchmarkRegister(b *testing.B) {
+	benchmarkManagers(b, func(b *testing.B, mgr ConnectionManager) {
+		var seq atomic.Uint64
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				n := seq.Add(1)
+				c := conntest.New(fmt.Sprintf("c%d", n), n)
+				_ = mgr.Register(c)
+				_ = mgr.UnRegister(c)
+			}
+		})
+	})
+}
+
+func BenchmarkGetConnection(b *testing.B) {
+	benchmarkManagers(b, func(b *testing.B, mgr ConnectionManager) {
+		ids := make([]string, 100000)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("c%d", i)
+			_ = mgr.Register(conntest.New(ids[i], uint64(i)))
+		}
+		var seq atomic.Uint64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_, _ = mgr.GetConnection(ids[seq.Add(1)%uint64(len(ids))])
+			}
+		})
+	})
+}
+
+// BenchmarkRegisterDuringRange 遍历 10 万连接的同时注册与注销
+func BenchmarkRegisterDuringRange(b *testing.B) {
+	benchmarkManagers(b, func(b *testing.B, mgr ConnectionManager) {
+		for i := 0; i < 100000; i++ {
+			_ = mgr.Register(conntest.New(fmt.Sprintf("c%d", i), uint64(i)))
+		}
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					mgr.Range(func(conn Connection) bool { return true })
+				}
+			}
+		}()
+
+		var seq atomic.Uint64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				n := seq.Add(1)
+				c := conntest.New(fmt.Sprintf("new%d", n), n)
+				_ = mgr.Register(c)
+				_ = mgr.UnRegister(c)
+			}
+		})
+	})
+}
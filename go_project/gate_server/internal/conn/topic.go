@@ -0,0 +1,129 @@
+package conn
+
+// 单个连接最多订阅的主题数
+const MaxTopicsPerConn = 256
+
+// 主题名称的最大长度
+const maxTopicLen = 128
+
+// TopicObserver 主题的订阅连接数变化时调用，members 为 0 表示主题已没有订阅者
+// 在连接管理器的锁内按变化顺序调用，不能阻塞或回调连接管理器
+type TopicObserver func(topic string, members int)
+
+// WithTopicObserver 主题的订阅连接数变化时通知 fn，用于跨 gate 同步主题成员
+func WithTopicObserver(fn TopicObserver) Option {
+	return func(o *options) {
+		o.observer = fn
+	}
+}
+
+func (m *manager) Subscribe(connId, topic string) error {
+	if topic == "" || len(topic) > maxTopicLen {
+		return ErrInvalidTopic
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn, ok := m.conns[connId]
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	subscribed := m.connTopics[connId]
+	if _, exists := subscribed[topic]; exists {
+		return nil
+	}
+	if len(subscribed) >= MaxTopicsPerConn {
+		return ErrTooManyTopics
+	}
+
+	if subscribed == nil {
+		subscribed = make(map[string]struct{})
+		m.connTopics[connId] = subscribed
+	}
+	subscribed[topic] = struct{}{}
+	if _, exists := m.topics[topic]; !exists {
+		m.topics[topic] = make(map[string]Connection)
+	}
+	m.topics[topic][connId] = conn
+	m.notify(topic)
+
+	return nil
+}
+
+func (m *manager) Unsubscribe(connId, topic string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.conns[connId]; !ok {
+		return ErrConnectionNotFound
+	}
+	subscribed := m.connTopics[connId]
+	if _, exists := subscribed[topic]; !exists {
+		return nil
+	}
+	delete(subscribed, topic)
+	if len(subscribed) == 0 {
+		delete(m.connTopics, connId)
+	}
+	m.leave(connId, topic)
+
+	return nil
+}
+
+// Publish 主题消息与广播相同，不分配 seq，断线期间的消息不会补发
+func (m *manager) Publish(topic string, msg []byte) (delivered, failed int64) {
+	m.mu.RLock()
+	conns := make([]Connection, 0, len(m.topics[topic]))
+	for _, conn := range m.topics[topic] {
+		conns = append(conns, conn)
+	}
+	m.mu.RUnlock()
+
+	// 在锁外发送，发送失败的连接会在关闭时注销
+	for _, conn := range conns {
+		if err := conn.Send(msg); err != nil {
+			failed++
+			continue
+		}
+		delivered++
+	}
+	return delivered, failed
+}
+
+func (m *manager) TopicMembers(topic string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.topics[topic])
+}
+
+func (m *manager) TopicsOf(connId string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	topics := make([]string, 0, len(m.connTopics[connId]))
+	for topic := range m.connTopics[connId] {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// leave 将连接移出主题的订阅表，调用方需持有写锁并自行维护 connTopics
+func (m *manager) leave(connId, topic string) {
+	members, ok := m.topics[topic]
+	if !ok {
+		return
+	}
+	delete(members, connId)
+	if len(members) == 0 {
+		delete(m.topics, topic)
+	}
+	m.notify(topic)
+}
+
+func (m *manager) notify(topic string) {
+	if m.observer != nil {
+		m.observer(topic, len(m.topics[topic]))
+	}
+}
@@ -0,0 +1,66 @@
+package conn
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/stretchr/testify/assert"
+)
+
+// 两种实现使用相同的用例
+var managers = map[string]func(opts ...Option) ConnectionManager{
+	"single":  NewManager,
+	"sharded": func(opts ...Option) ConnectionManager { return NewShardedManager(DefaultShards, opts...) },
+}
+
+func TestTopics(t *testing.T) {
+	for name, newManager := range managers {
+		t.Run(name, func(t *testing.T) { testTopics(t, newManager) })
+	}
+}
+
+func testTopics(t *testing.T, newManager func(opts ...Option) ConnectionManager) {
+	var changes []int
+	mgr := newManager(WithTopicObserver(func(topic string, members int) {
+		changes = append(changes, members)
+	}))
+	a, b := conntest.New("a", 1), conntest.New("b", 1)
+	assert.NoError(t, mgr.Register(a))
+	assert.NoError(t, mgr.Register(b))
+
+	assert.ErrorIs(t, mgr.Subscribe("c", "room"), ErrConnectionNotFound, "未注册的连接不能订阅")
+	assert.ErrorIs(t, mgr.Subscribe("a", ""), ErrInvalidTopic, "主题不能为空")
+	assert.NoError(t, mgr.Subscribe("a", "room"))
+	assert.NoError(t, mgr.Subscribe("a", "room"), "重复订阅不应该报错")
+	assert.NoError(t, mgr.Subscribe("b", "room"))
+	assert.Equal(t, 2, mgr.TopicMembers("room"), "重复订阅不应该增加订阅数")
+
+	delivered, failed := mgr.Publish("room", []byte("hi"))
+	assert.Equal(t, int64(2), delivered)
+	assert.Equal(t, int64(0), failed)
+	assert.Equal(t, 1, len(a.Sent()), "订阅者应该收到消息")
+
+	assert.NoError(t, mgr.Unsubscribe("b", "room"))
+	assert.Equal(t, 1, mgr.TopicMembers("room"))
+
+	assert.NoError(t, mgr.UnRegister(a))
+	assert.Equal(t, 0, mgr.TopicMembers("room"), "注销连接应该退出订阅的主题")
+	assert.Empty(t, mgr.TopicsOf("a"))
+	assert.Equal(t, []int{1, 2, 1, 0}, changes, "订阅数变化应该按顺序通知")
+}
+
+func TestTopicLimit(t *testing.T) {
+	for name, newManager := range managers {
+		t.Run(name, func(t *testing.T) { testTopicLimit(t, newManager) })
+	}
+}
+
+func testTopicLimit(t *testing.T, newManager func(opts ...Option) ConnectionManager) {
+	mgr := newManager()
+	assert.NoError(t, mgr.Register(conntest.New("a", 1)))
+	for i := 0; i < MaxTopicsPerConn; i++ {
+		assert.NoError(t, mgr.Subscribe("a", fmt.Sprintf("room:%d", i)))
+	}
+	assert.ErrorIs(t, mgr.Subscribe("a", "more"), ErrTooManyTopics, "超过上限后不能继续订阅")
+}
@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"context"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/online"
+	pb "github.com/mxxmstar/learning/proto"
+)
+
+// BroadcastLocal 只广播到本 gate 上的所有连接，返回成功与失败的连接数，集群广播需要调用每个 gate
+// 广播不分配 seq，断线期间的广播不会补发
+func (r *Router) BroadcastLocal(payload []byte) (delivered, failed int64) {
+	r.mgr.Range(func(c conn.Connection) bool {
+		if err := c.Send(payload); err != nil {
+			failed++
+			return true
+		}
+		delivered++
+		return true
+	})
+	return delivered, failed
+}
+
+// KickUser 踢掉用户在所有 gate 上的连接，客户端会先收到 kicked 消息
+func (r *Router) KickUser(ctx context.Context, userId uint64, reason string) ([]*pb.DeliveryResult, error) {
+	var connIds []string
+	if r.store != nil {
+		p, err := r.store.Query(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range p.Conns {
+			connIds = append(connIds, c.ConnId)
+		}
+	}
+	// 在线状态可能还没写入，本 gate 上的连接以连接管理器为准
+	for _, c := range r.mgr.GetConnectionsByUserId(userId) {
+		connIds = append(connIds, c.Id())
+	}
+	return r.disconnect(ctx, connIds, reason, true), nil
+}
+
+// CloseConnections 关闭指定连接，本 gate 上的连接直接关闭，其他 gate 上的连接通知其踢掉
+func (r *Router) CloseConnections(ctx context.Context, connIds []string, reason string) []*pb.DeliveryResult {
+	return r.disconnect(ctx, connIds, reason, false)
+}
+
+// disconnect kick 为 true 时本 gate 上的连接先通知客户端再关闭
+func (r *Router) disconnect(ctx context.Context, connIds []string, reason string, kick bool) []*pb.DeliveryResult {
+	results := make([]*pb.DeliveryResult, 0, len(connIds))
+	seen := make(map[string]struct{}, len(connIds))
+	for _, connId := range connIds {
+		if _, ok := seen[connId]; ok {
+			continue
+		}
+		seen[connId] = struct{}{}
+
+		gateId := online.GateIdOf(connId)
+		result := &pb.DeliveryResult{
+			ConnId: connId,
+			GateId: gateId,
+			Status: pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED,
+		}
+		if gateId == r.gateId {
+			c, err := r.mgr.GetConnection(connId)
+			if err != nil {
+				result.Status = pb.DeliveryStatus_DELIVERY_STATUS_NOT_FOUND
+				result.Error = err.Error()
+			} else {
+				result.UserId = c.UserId()
+				if kick {
+					err = conn.Kick(c, reason)
+				} else {
+					err = c.Close(reason)
+				}
+				if err != nil {
+					result.Status = pb.DeliveryStatus_DELIVERY_STATUS_CLOSED
+					result.Error = err.Error()
+				}
+			}
+		} else if r.store == nil {
+			result.Status = pb.DeliveryStatus_DELIVERY_STATUS_UNREACHABLE
+			result.Error = "no route to gate"
+		} else if err := r.store.Kick(ctx, connId, reason); err != nil {
+			result.Status = pb.DeliveryStatus_DELIVERY_STATUS_UNREACHABLE
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,72 @@
+package delivery
+
+import (
+	"errors"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	pb "github.com/mxxmstar/learning/proto"
+)
+
+// DeliverLocal 将消息投递到本 gate 上的连接，返回每个连接的投递结果
+// userIds 中的用户在本 gate 上没有连接时返回一条 NOT_FOUND 结果
+func DeliverLocal(mgr conn.ConnectionManager, gateId string, connIds []string, userIds []uint64, payload []byte) []*pb.DeliveryResult {
+	results := make([]*pb.DeliveryResult, 0, len(connIds)+len(userIds))
+	seen := make(map[string]struct{}, len(connIds))
+
+	for _, connId := range connIds {
+		if _, ok := seen[connId]; ok {
+			continue
+		}
+		seen[connId] = struct{}{}
+
+		c, err := mgr.GetConnection(connId)
+		if err != nil {
+			results = append(results, &pb.DeliveryResult{
+				ConnId: connId,
+				GateId: gateId,
+				Status: pb.DeliveryStatus_DELIVERY_STATUS_NOT_FOUND,
+				Error:  err.Error(),
+			})
+			continue
+		}
+		results = append(results, send(c, gateId, payload))
+	}
+
+	for _, userId := range userIds {
+		conns := mgr.GetConnectionsByUserId(userId)
+		if len(conns) == 0 {
+			results = append(results, &pb.DeliveryResult{
+				UserId: userId,
+				GateId: gateId,
+				Status: pb.DeliveryStatus_DELIVERY_STATUS_NOT_FOUND,
+				Error:  conn.ErrConnectionNotFound.Error(),
+			})
+			continue
+		}
+		for _, c := range conns {
+			if _, ok := seen[c.Id()]; ok {
+				continue
+			}
+			seen[c.Id()] = struct{}{}
+			results = append(results, send(c, gateId, payload))
+		}
+	}
+	return results
+}
+
+func send(c conn.Connection, gateId string, payload []byte) *pb.DeliveryResult {
+	result := &pb.DeliveryResult{
+		ConnId: c.Id(),
+		UserId: c.UserId(),
+		GateId: gateId,
+		Status: pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED,
+	}
+	if err := conn.SendReliable(c, payload); err != nil {
+		result.Status = pb.DeliveryStatus_DELIVERY_STATUS_CLOSED
+		if errors.Is(err, conn.ErrSendQueueFull) {
+			result.Status = pb.DeliveryStatus_DELIVERY_STATUS_QUEUE_FULL
+		}
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package delivery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/online"
+	pb "github.com/mxxmstar/learning/proto"
+)
+
+// 转发到其他 gate 的超时时间
+const relayTimeout = 3 * time.Second
+
+// Relay 将消息转发给其他 gate 投递
+type Relay interface {
+	Deliver(ctx context.Context, gateId string, req *pb.DeliverRequest) (*pb.DeliverResponse, error)
+	PublishTopic(ctx context.Context, gateId string, req *pb.PublishRequest) (*pb.PublishTopicResponse, error)
+}
+
+// Router 集群范围的消息投递，通过在线状态找到连接所在的 gate，
+// 本 gate 的连接直接投递，其他 gate 的连接通过 Relay 转发
+type Router struct {
+	gateId string
+	mgr    conn.ConnectionManager
+	store  online.Store
+	relay  Relay
+}
+
+func NewRouter(gateId string, mgr conn.ConnectionManager, store online.Store, relay Relay) *Router {
+	return &Router{
+		gateId: gateId,
+		mgr:    mgr,
+		store:  store,
+		relay:  relay,
+	}
+}
+
+// DeliverToUsers 投递到用户在所有 gate 上的连接，离线用户返回一条 NOT_FOUND 结果
+func (r *Router) DeliverToUsers(ctx context.Context, userIds []uint64, payload []byte) ([]*pb.DeliveryResult, error) {
+	presences, err := r.store.QueryMany(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*pb.DeliveryResult
+	byGate := make(map[string][]string)
+	for _, userId := range userIds {
+		p := presences[userId]
+		if p == nil || !p.Online {
+			results = append(results, &pb.DeliveryResult{
+				UserId: userId,
+				Status: pb.DeliveryStatus_DELIVERY_STATUS_NOT_FOUND,
+				Error:  "user offline",
+			})
+			continue
+		}
+		for _, c := range p.Conns {
+			byGate[c.GateId] = append(byGate[c.GateId], c.ConnId)
+		}
+	}
+	return append(results, r.deliver(ctx, byGate, payload)...), nil
+}
+
+// DeliverToConnections 投递到指定连接，连接所在的 gate 由连接 Id 解析
+func (r *Router) DeliverToConnections(ctx context.Context, connIds []string, payload []byte) []*pb.DeliveryResult {
+	byGate := make(map[string][]string)
+	for _, connId := range connIds {
+		gateId := online.GateIdOf(connId)
+		byGate[gateId] = append(byGate[gateId], connId)
+	}
+	return r.deliver(ctx, byGate, payload)
+}
+
+// deliver 按 gate 并发投递，汇总每个连接的结果
+func (r *Router) deliver(ctx context.Context, byGate map[string][]string, payload []byte) []*pb.DeliveryResult {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []*pb.DeliveryResult
+	)
+	collect := func(rs []*pb.DeliveryResult) {
+		mu.Lock()
+		results = append(results, rs...)
+		mu.Unlock()
+	}
+
+	for gateId, connIds := range byGate {
+		if gateId == r.gateId {
+			collect(DeliverLocal(r.mgr, r.gateId, connIds, nil, payload))
+			continue
+		}
+
+		wg.Add(1)
+		go func(gateId string, connIds []string) {
+			defer wg.Done()
+			collect(r.relayTo(ctx, gateId, connIds, payload))
+		}(gateId, connIds)
+	}
+	wg.Wait()
+	return results
+}
+
+func (r *Router) relayTo(ctx context.Context, gateId string, connIds []string, payload []byte) []*pb.DeliveryResult {
+	if r.relay != nil && gateId != "" {
+		ctx, cancel := context.WithTimeout(ctx, relayTimeout)
+		defer cancel()
+		rsp, err := r.relay.Deliver(ctx, gateId, &pb.DeliverRequest{ConnIds: connIds, Payload: payload})
+		if err == nil {
+			return rsp.GetResults()
+		}
+		return unreachable(gateId, connIds, err.Error())
+	}
+	return unreachable(gateId, connIds, "no route to gate")
+}
+
+func unreachable(gateId string, connIds []string, reason string) []*pb.DeliveryResult {
+	results := make([]*pb.DeliveryResult, 0, len(connIds))
+	for _, connId := range connIds {
+		results = append(results, &pb.DeliveryResult{
+			ConnId: connId,
+			GateId: gateId,
+			Status: pb.DeliveryStatus_DELIVERY_STATUS_UNREACHABLE,
+			Error:  reason,
+		})
+	}
+	return results
+}
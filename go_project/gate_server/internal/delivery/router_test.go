@@ -0,0 +1,83 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/mxxmstar/learning/gate_server/internal/online"
+	pb "github.com/mxxmstar/learning/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRelay gate2 可以访问，其他 gate 不可访问
+type fakeRelay struct{}
+
+func (fakeRelay) Deliver(ctx context.Context, gateId string, req *pb.DeliverRequest) (*pb.DeliverResponse, error) {
+	if gateId != "gate2" {
+		return nil, errors.New("connection refused")
+	}
+	var results []*pb.DeliveryResult
+	for _, connId := range req.ConnIds {
+		results = append(results, &pb.DeliveryResult{ConnId: connId, GateId: gateId, Status: pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED})
+	}
+	return &pb.DeliverResponse{Results: results}, nil
+}
+
+func (fakeRelay) PublishTopic(ctx context.Context, gateId string, req *pb.PublishRequest) (*pb.PublishTopicResponse, error) {
+	if gateId != "gate2" {
+		return nil, errors.New("connection refused")
+	}
+	return &pb.PublishTopicResponse{Delivered: 2, Failed: 1}, nil
+}
+
+// topicStore 只提供主题成员查询
+type topicStore struct {
+	online.Store
+	gates map[string]int
+}
+
+func (s topicStore) TopicGates(ctx context.Context, topic string) (map[string]int, error) {
+	return s.gates, nil
+}
+
+func TestDeliverToConnections(t *testing.T) {
+	mgr := conn.NewManager()
+	local := conntest.New("gate1#a", 1)
+	assert.NoError(t, mgr.Register(local))
+
+	router := NewRouter("gate1", mgr, nil, fakeRelay{})
+	results := router.DeliverToConnections(context.Background(), []string{"gate1#a", "gate1#b", "gate2#c", "gate3#d"}, []byte("hi"))
+
+	status := make(map[string]pb.DeliveryStatus)
+	for _, r := range results {
+		status[r.ConnId] = r.Status
+	}
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED, status["gate1#a"], "本地连接应该直接投递")
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_NOT_FOUND, status["gate1#b"], "本地不存在的连接应该返回 NOT_FOUND")
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED, status["gate2#c"], "其他 gate 的连接应该转发投递")
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_UNREACHABLE, status["gate3#d"], "无法访问的 gate 应该返回 UNREACHABLE")
+	assert.Equal(t, [][]byte{[]byte("hi")}, local.Sent(), "本地连接应该收到消息")
+}
+
+func TestPublish(t *testing.T) {
+	mgr := conn.NewManager()
+	local := conntest.New("gate1#a", 1)
+	assert.NoError(t, mgr.Register(local))
+	assert.NoError(t, mgr.Subscribe(local.Id(), "room:1"))
+
+	store := topicStore{gates: map[string]int{"gate1": 1, "gate2": 3, "gate3": 1}}
+	router := NewRouter("gate1", mgr, store, fakeRelay{})
+	rsp, err := router.Publish(context.Background(), "room:1", []byte("hi"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), rsp.Delivered, "应该汇总本地与其他 gate 的投递数")
+	assert.Equal(t, int64(1), rsp.Failed, "应该汇总其他 gate 的失败数")
+	assert.Equal(t, []string{"gate3"}, rsp.UnreachableGates, "无法访问的 gate 应该单独返回")
+	assert.Equal(t, [][]byte{[]byte("hi")}, local.Sent(), "本地订阅者应该收到消息")
+
+	members, err := router.TopicMembers(context.Background(), "room:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, members, "订阅数应该包含所有 gate")
+}
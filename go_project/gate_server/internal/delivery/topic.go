@@ -0,0 +1,80 @@
+package delivery
+
+import (
+	"context"
+	"sync"
+
+	pb "github.com/mxxmstar/learning/proto"
+)
+
+// Publish 发布到所有 gate 上订阅了主题的连接
+// 本 gate 的订阅者直接投递，其他 gate 通过 Store 中记录的主题成员找到并转发
+// 查询失败时返回错误，不会只投递到本 gate
+func (r *Router) Publish(ctx context.Context, topic string, payload []byte) (*pb.PublishResponse, error) {
+	var gates map[string]int
+	if r.store != nil {
+		var err error
+		if gates, err = r.store.TopicGates(ctx, topic); err != nil {
+			return nil, err
+		}
+	}
+
+	rsp := &pb.PublishResponse{}
+	rsp.Delivered, rsp.Failed = r.mgr.Publish(topic, payload)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for gateId := range gates {
+		if gateId == r.gateId {
+			continue
+		}
+		wg.Add(1)
+		go func(gateId string) {
+			defer wg.Done()
+			delivered, failed, ok := r.publishTo(ctx, gateId, topic, payload)
+			mu.Lock()
+			defer mu.Unlock()
+			if !ok {
+				rsp.UnreachableGates = append(rsp.UnreachableGates, gateId)
+				return
+			}
+			rsp.Delivered += delivered
+			rsp.Failed += failed
+		}(gateId)
+	}
+	wg.Wait()
+	return rsp, nil
+}
+
+// TopicMembers 主题在所有 gate 上的订阅连接数，本 gate 以连接管理器为准
+func (r *Router) TopicMembers(ctx context.Context, topic string) (int, error) {
+	members := r.mgr.TopicMembers(topic)
+	if r.store == nil {
+		return members, nil
+	}
+	gates, err := r.store.TopicGates(ctx, topic)
+	if err != nil {
+		return 0, err
+	}
+	for gateId, n := range gates {
+		if gateId != r.gateId {
+			members += n
+		}
+	}
+	return members, nil
+}
+
+func (r *Router) publishTo(ctx context.Context, gateId, topic string, payload []byte) (delivered, failed int64, ok bool) {
+	if r.relay == nil || gateId == "" {
+		return 0, 0, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, relayTimeout)
+	defer cancel()
+	rsp, err := r.relay.PublishTopic(ctx, gateId, &pb.PublishRequest{Topic: topic, Payload: payload})
+	if err != nil {
+		return 0, 0, false
+	}
+	return rsp.GetDelivered(), rsp.GetFailed(), true
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/pkg/internalauth"
 	"github.com/mxxmstar/learning/pkg/logger"
 	pb "github.com/mxxmstar/learning/proto"
 	"google.golang.org/grpc"
@@ -20,7 +21,11 @@ type AuthClient struct {
 func NewAuthClient(config *gate_config.Config) (*AuthClient, error) {
 	// 连接到 verify_server 的 gRPC 服务
 	// TODO: 获取 verify_server 的 gRPC 服务地址 ，查询自己维护的表 ，查询 status_server
-	dsn := fmt.Sprintf("%s:%d", config.VerifyServer.VerifyGRPCServerConfig.Host, config.VerifyServer.VerifyGRPCServerConfig.Port)
+	verifyServer, err := config.VerifyServer()
+	if err != nil {
+		return nil, err
+	}
+	dsn := fmt.Sprintf("%s:%d", verifyServer.GRPCConfig.Host, verifyServer.GRPCConfig.Port)
 	conn, err := grpc.NewClient(
 		dsn,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -30,14 +35,16 @@ func NewAuthClient(config *gate_config.Config) (*AuthClient, error) {
 		return nil, err
 	}
 
-	client := pb.NewAuthClient(conn)
+	return NewAuthClientWithConn(conn, config), nil
+}
 
-	authClient := &AuthClient{
+// NewAuthClientWithConn 使用已建立的连接创建客户端
+func NewAuthClientWithConn(conn *grpc.ClientConn, config *gate_config.Config) *AuthClient {
+	return &AuthClient{
 		conn:   conn,
-		client: client,
+		client: pb.NewAuthClient(conn),
 		config: config,
 	}
-	return authClient, nil
 }
 
 // 验证 session
@@ -64,14 +71,17 @@ func (c *AuthClient) RefreshSession(ctx context.Context, sessionId string) (*pb.
 	return c.client.RefreshSession(ctx, req)
 }
 
-// 通过邮箱登录
-func (c *AuthClient) LoginByEmail(ctx context.Context, email, password, DeviceId string) (*pb.LoginByEmailResponse, error) {
+// 通过邮箱登录，ipAddress 与 userAgent 为客户端的地址与 UA，用于登录限流
+func (c *AuthClient) LoginByEmail(ctx context.Context, email, password, DeviceId, ipAddress, userAgent string) (*pb.LoginByEmailResponse, error) {
 	req := &pb.LoginByEmailRequest{
-		Email:    email,
-		Password: password,
-		DeviceId: DeviceId,
+		Email:     email,
+		Password:  password,
+		DeviceId:  DeviceId,
+		IpAddress: ipAddress,
+		UserAgent: userAgent,
 	}
-	return c.client.LoginByEmail(ctx, req)
+	// 携带共享令牌，verify_server 才会使用透传的客户端 IP 限流
+	return c.client.LoginByEmail(c.withInternalToken(ctx), req)
 }
 
 // 注册
@@ -85,6 +95,64 @@ func (c *AuthClient) SignUp(ctx context.Context, username, email, password, conf
 	return c.client.SignUp(ctx, req)
 }
 
+// 使用刷新令牌换取新的 JWT
+func (c *AuthClient) RefreshJWT(ctx context.Context, refreshToken string) (*pb.RefreshJWTResponse, error) {
+	req := &pb.RefreshJWTRequest{
+		RefreshToken: refreshToken,
+	}
+	return c.client.RefreshJWT(ctx, req)
+}
+
+// 登出，删除 session 并吊销 jwt
+func (c *AuthClient) Logout(ctx context.Context, sessionId, jwtToken string) (*pb.LogoutResponse, error) {
+	req := &pb.LogoutRequest{
+		SessionId: sessionId,
+		JwtToken:  jwtToken,
+	}
+	return c.client.Logout(c.withInternalToken(ctx), req)
+}
+
+// 吊销用户已签发的所有令牌
+func (c *AuthClient) RevokeUserTokens(ctx context.Context, userId uint64) (*pb.RevokeUserTokensResponse, error) {
+	req := &pb.RevokeUserTokensRequest{
+		UserId: userId,
+	}
+	return c.client.RevokeUserTokens(c.withInternalToken(ctx), req)
+}
+
+// 列出调用方所有登录中的设备，sessionId 与 jwtToken 为调用方自己的凭证，二选一
+func (c *AuthClient) ListSessions(ctx context.Context, sessionId, jwtToken string) (*pb.ListSessionsResponse, error) {
+	req := &pb.ListSessionsRequest{
+		SessionId: sessionId,
+		JwtToken:  jwtToken,
+	}
+	return c.client.ListSessions(ctx, req)
+}
+
+// 注销调用方的某个 session，handle 取自 ListSessions
+func (c *AuthClient) RevokeSession(ctx context.Context, sessionId, jwtToken, handle string) (*pb.RevokeSessionResponse, error) {
+	req := &pb.RevokeSessionRequest{
+		SessionId: sessionId,
+		JwtToken:  jwtToken,
+		Handle:    handle,
+	}
+	return c.client.RevokeSession(ctx, req)
+}
+
+// 注销调用方除当前登录以外的所有 session
+func (c *AuthClient) RevokeOtherSessions(ctx context.Context, sessionId, jwtToken string) (*pb.RevokeOtherSessionsResponse, error) {
+	req := &pb.RevokeOtherSessionsRequest{
+		SessionId: sessionId,
+		JwtToken:  jwtToken,
+	}
+	return c.client.RevokeOtherSessions(ctx, req)
+}
+
+// withInternalToken 附加共享令牌，登出、吊销等内部方法需要携带
+func (c *AuthClient) withInternalToken(ctx context.Context) context.Context {
+	return internalauth.AppendToOutgoingContext(ctx, c.config.ServerConfig.GlobalConfig.InternalToken)
+}
+
 // 关闭客户端连接
 func (c *AuthClient) Close() error {
 	return c.conn.Close()
@@ -0,0 +1,80 @@
+package grpc_relay_client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/pkg/internalauth"
+	pb "github.com/mxxmstar/learning/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// RelayClient 连接其他 gate 的 GateRelay 服务，按 gate Id 复用连接
+type RelayClient struct {
+	config *gate_config.Config
+	mu     sync.Mutex
+	conns  map[string]*grpc.ClientConn
+}
+
+func NewRelayClient(config *gate_config.Config) *RelayClient {
+	return &RelayClient{
+		config: config,
+		conns:  make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Deliver 将消息交给 gateId 对应的 gate 投递
+func (c *RelayClient) Deliver(ctx context.Context, gateId string, req *pb.DeliverRequest) (*pb.DeliverResponse, error) {
+	conn, err := c.conn(gateId)
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewGateRelayClient(conn).Deliver(ctx, req)
+}
+
+// PublishTopic 将主题消息交给 gateId 对应的 gate 投递到订阅者
+func (c *RelayClient) PublishTopic(ctx context.Context, gateId string, req *pb.PublishRequest) (*pb.PublishTopicResponse, error) {
+	conn, err := c.conn(gateId)
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewGateRelayClient(conn).PublishTopic(ctx, req)
+}
+
+// Close 关闭所有到其他 gate 的连接
+func (c *RelayClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for gateId, conn := range c.conns {
+		_ = conn.Close()
+		delete(c.conns, gateId)
+	}
+}
+
+func (c *RelayClient) conn(gateId string) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conn, ok := c.conns[gateId]; ok {
+		return conn, nil
+	}
+
+	// gate Id 即配置中的 gate 名称
+	server := c.config.GetGateServer(gateId)
+	if server == nil {
+		return nil, fmt.Errorf("relay: unknown gate %s", gateId)
+	}
+	dsn := fmt.Sprintf("%s:%d", server.GRPCConfig.Host, server.GRPCConfig.Port)
+	conn, err := grpc.NewClient(
+		dsn,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(internalauth.UnaryClientInterceptor(c.config.ServerConfig.GlobalConfig.InternalToken)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("relay: connect to gate %s at %s: %w", gateId, dsn, err)
+	}
+	c.conns[gateId] = conn
+	return conn, nil
+}
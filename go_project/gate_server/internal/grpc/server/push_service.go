@@ -0,0 +1,86 @@
+package grpc_server
+
+import (
+	"context"
+
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
+	pb "github.com/mxxmstar/learning/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 未指定原因时的踢人原因
+const defaultKickReason = "kicked by server"
+
+// PushService 供后端服务推送消息与踢人，调用方需要携带共享令牌，只能通过内网访问
+type PushService struct {
+	pb.UnimplementedPushServer
+	router *delivery.Router
+}
+
+func NewPushService(router *delivery.Router) *PushService {
+	return &PushService{
+		router: router,
+	}
+}
+
+func (s *PushService) PushToUser(ctx context.Context, req *pb.PushToUserRequest) (*pb.PushResponse, error) {
+	if len(req.GetUserIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_ids is required")
+	}
+	results, err := s.router.DeliverToUsers(ctx, req.GetUserIds(), req.GetPayload())
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "query presence: %v", err)
+	}
+	return &pb.PushResponse{Results: results}, nil
+}
+
+func (s *PushService) PushToConnection(ctx context.Context, req *pb.PushToConnectionRequest) (*pb.PushResponse, error) {
+	if len(req.GetConnIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "conn_ids is required")
+	}
+	results := s.router.DeliverToConnections(ctx, req.GetConnIds(), req.GetPayload())
+	return &pb.PushResponse{Results: results}, nil
+}
+
+func (s *PushService) BroadcastLocal(ctx context.Context, req *pb.BroadcastRequest) (*pb.BroadcastResponse, error) {
+	delivered, failed := s.router.BroadcastLocal(req.GetPayload())
+	return &pb.BroadcastResponse{Delivered: delivered, Failed: failed}, nil
+}
+
+func (s *PushService) KickUser(ctx context.Context, req *pb.KickUserRequest) (*pb.KickResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	results, err := s.router.KickUser(ctx, req.GetUserId(), reasonOrDefault(req.GetReason()))
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "query presence: %v", err)
+	}
+	return &pb.KickResponse{Results: results}, nil
+}
+
+func (s *PushService) CloseConnection(ctx context.Context, req *pb.CloseConnectionRequest) (*pb.KickResponse, error) {
+	if req.GetConnId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "conn_id is required")
+	}
+	results := s.router.CloseConnections(ctx, []string{req.GetConnId()}, reasonOrDefault(req.GetReason()))
+	return &pb.KickResponse{Results: results}, nil
+}
+
+func (s *PushService) Publish(ctx context.Context, req *pb.PublishRequest) (*pb.PublishResponse, error) {
+	if req.GetTopic() == "" {
+		return nil, status.Error(codes.InvalidArgument, "topic is required")
+	}
+	rsp, err := s.router.Publish(ctx, req.GetTopic(), req.GetPayload())
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "query topic: %v", err)
+	}
+	return rsp, nil
+}
+
+func reasonOrDefault(reason string) string {
+	if reason == "" {
+		return defaultKickReason
+	}
+	return reason
+}
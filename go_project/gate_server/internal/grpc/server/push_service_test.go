@@ -0,0 +1,140 @@
+package grpc_server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
+	"github.com/mxxmstar/learning/gate_server/internal/online"
+	"github.com/mxxmstar/learning/pkg/internalauth"
+	pb "github.com/mxxmstar/learning/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// kickStore 记录用户在 gate1 与 gate2 上的连接，并记录发给其他 gate 的踢人通知
+type kickStore struct {
+	online.Store
+	kicked map[string]string
+}
+
+func (s *kickStore) Query(ctx context.Context, userId uint64) (*online.Presence, error) {
+	return &online.Presence{UserId: userId, Online: true, Conns: []online.ConnInfo{
+		{ConnId: "gate1#a", UserId: userId, GateId: "gate1"},
+		{ConnId: "gate2#b", UserId: userId, GateId: "gate2"},
+	}}, nil
+}
+
+func (s *kickStore) Kick(ctx context.Context, connId, reason string) error {
+	s.kicked[connId] = reason
+	return nil
+}
+
+func newPushService(t *testing.T, conns ...*conntest.Conn) (*PushService, *kickStore) {
+	mgr := conn.NewManager()
+	for _, c := range conns {
+		require.NoError(t, mgr.Register(c))
+	}
+	store := &kickStore{kicked: make(map[string]string)}
+	return NewPushService(delivery.NewRouter("gate1", mgr, store, nil)), store
+}
+
+func statusOf(results []*pb.DeliveryResult) map[string]pb.DeliveryStatus {
+	m := make(map[string]pb.DeliveryStatus)
+	for _, r := range results {
+		m[r.ConnId] = r.Status
+	}
+	return m
+}
+
+func TestPushServiceBroadcastLocal(t *testing.T) {
+	a := conntest.New("gate1#a", 1)
+	b := conntest.New("gate1#b", 2)
+	_ = b.Close("gone")
+	s, _ := newPushService(t, a, b)
+
+	rsp, err := s.BroadcastLocal(context.Background(), &pb.BroadcastRequest{Payload: []byte("hi")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rsp.Delivered)
+	assert.Equal(t, int64(1), rsp.Failed, "发送失败的连接应该计入失败数")
+	assert.Equal(t, [][]byte{[]byte("hi")}, a.Sent())
+}
+
+func TestPushServiceKickUser(t *testing.T) {
+	a := conntest.New("gate1#a", 1)
+	s, store := newPushService(t, a)
+
+	_, err := s.KickUser(context.Background(), &pb.KickUserRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "缺少 user_id 应该返回 InvalidArgument")
+
+	rsp, err := s.KickUser(context.Background(), &pb.KickUserRequest{UserId: 1})
+	require.NoError(t, err)
+	st := statusOf(rsp.Results)
+	assert.Len(t, rsp.Results, 2, "在线状态与连接管理器中重复的连接只处理一次")
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED, st["gate1#a"])
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED, st["gate2#b"])
+	assert.Equal(t, defaultKickReason, a.Kicked(), "本 gate 的连接应该先通知客户端再关闭")
+	assert.Equal(t, defaultKickReason, store.kicked["gate2#b"], "其他 gate 的连接应该通知所在 gate 踢掉")
+}
+
+func TestPushServiceCloseConnection(t *testing.T) {
+	a := conntest.New("gate1#a", 1)
+	s, store := newPushService(t, a)
+
+	_, err := s.CloseConnection(context.Background(), &pb.CloseConnectionRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "缺少 conn_id 应该返回 InvalidArgument")
+
+	rsp, err := s.CloseConnection(context.Background(), &pb.CloseConnectionRequest{ConnId: "gate1#a", Reason: "maintenance"})
+	require.NoError(t, err)
+	require.Len(t, rsp.Results, 1)
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED, rsp.Results[0].Status)
+	assert.Equal(t, uint64(1), rsp.Results[0].UserId)
+	assert.Equal(t, "maintenance", a.Closed())
+	assert.Empty(t, a.Kicked(), "关闭连接不应该发送 kicked 消息")
+
+	rsp, err = s.CloseConnection(context.Background(), &pb.CloseConnectionRequest{ConnId: "gate1#x"})
+	require.NoError(t, err)
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_NOT_FOUND, rsp.Results[0].Status, "本 gate 不存在的连接应该返回 NOT_FOUND")
+
+	rsp, err = s.CloseConnection(context.Background(), &pb.CloseConnectionRequest{ConnId: "gate2#b"})
+	require.NoError(t, err)
+	assert.Equal(t, pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED, rsp.Results[0].Status)
+	assert.Equal(t, defaultKickReason, store.kicked["gate2#b"], "其他 gate 的连接应该通知所在 gate 关闭")
+}
+
+func TestPushServiceRequiresInternalToken(t *testing.T) {
+	a := conntest.New("gate1#a", 1)
+	s, _ := newPushService(t, a)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.UnaryInterceptor(internalauth.UnaryServerInterceptor("token")))
+	pb.RegisterPushServer(server, s)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	dial := func(opts ...grpc.DialOption) pb.PushClient {
+		conn, err := grpc.NewClient(lis.Addr().String(), append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return pb.NewPushClient(conn)
+	}
+	req := &pb.BroadcastRequest{Payload: []byte("hi")}
+
+	_, err = dial().BroadcastLocal(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "没有令牌的调用应该被拒绝")
+	_, err = dial(grpc.WithUnaryInterceptor(internalauth.UnaryClientInterceptor("wrong"))).BroadcastLocal(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "令牌错误的调用应该被拒绝")
+	assert.Empty(t, a.Sent())
+
+	rsp, err := dial(grpc.WithUnaryInterceptor(internalauth.UnaryClientInterceptor("token"))).BroadcastLocal(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rsp.Delivered)
+}
@@ -0,0 +1,33 @@
+package grpc_server
+
+import (
+	"context"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
+	pb "github.com/mxxmstar/learning/proto"
+)
+
+// RelayService 接收其他 gate 转发的消息，投递到本 gate 上的连接
+type RelayService struct {
+	pb.UnimplementedGateRelayServer
+	gateId string
+	mgr    conn.ConnectionManager
+}
+
+func NewRelayService(gateId string, mgr conn.ConnectionManager) *RelayService {
+	return &RelayService{
+		gateId: gateId,
+		mgr:    mgr,
+	}
+}
+
+func (s *RelayService) Deliver(ctx context.Context, req *pb.DeliverRequest) (*pb.DeliverResponse, error) {
+	results := delivery.DeliverLocal(s.mgr, s.gateId, req.GetConnIds(), req.GetUserIds(), req.GetPayload())
+	return &pb.DeliverResponse{Results: results}, nil
+}
+
+func (s *RelayService) PublishTopic(ctx context.Context, req *pb.PublishRequest) (*pb.PublishTopicResponse, error) {
+	delivered, failed := s.mgr.Publish(req.GetTopic(), req.GetPayload())
+	return &pb.PublishTopicResponse{Delivered: delivered, Failed: failed}, nil
+}
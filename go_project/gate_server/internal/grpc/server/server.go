@@ -0,0 +1,76 @@
+package grpc_server
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
+	"github.com/mxxmstar/learning/pkg/internalauth"
+	"github.com/mxxmstar/learning/pkg/logger"
+	pb "github.com/mxxmstar/learning/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// GRPCServer gate 的内部 gRPC 服务器，供其他 gate 和后端服务调用，必须绑定内网地址
+type GRPCServer struct {
+	gateId string
+	config *gate_config.Config
+	mgr    conn.ConnectionManager
+	router *delivery.Router
+	server *grpc.Server
+}
+
+func NewGRPCServer(gateId string, config *gate_config.Config, mgr conn.ConnectionManager, router *delivery.Router) *GRPCServer {
+	return &GRPCServer{
+		gateId: gateId,
+		config: config,
+		mgr:    mgr,
+		router: router,
+	}
+}
+
+func (s *GRPCServer) Start() error {
+	gateServer := s.config.GetGateServer(s.gateId)
+	if gateServer == nil {
+		return fmt.Errorf("gate server %s not found in config", s.gateId)
+	}
+
+	// GateRelay 与 Push 只校验共享令牌且不加密，只监听配置的内网地址，不能监听所有网卡
+	addr := fmt.Sprintf("%s:%d", gateServer.GRPCConfig.Host, gateServer.GRPCConfig.Port)
+	listen, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.FormatLog(context.Background(), "error", fmt.Sprintf("Failed to start gRPC server at %s: %v", addr, err))
+		return err
+	}
+
+	// 创建 gRPC 服务器
+	// 所有方法都要求携带共享令牌，未配置令牌时拒绝所有调用
+	s.server = grpc.NewServer(grpc.UnaryInterceptor(internalauth.UnaryServerInterceptor(s.config.ServerConfig.GlobalConfig.InternalToken)))
+
+	// 注册服务
+	pb.RegisterGateRelayServer(s.server, NewRelayService(s.gateId, s.mgr))
+	pb.RegisterPushServer(s.server, NewPushService(s.router))
+
+	// 在开发环境中启用反射服务，以便使用 gRPC 客户端工具进行调试
+	if s.config.ServerConfig.GlobalConfig.Env != "production" {
+		reflection.Register(s.server)
+	}
+
+	// 启动服务器
+	if err := s.server.Serve(listen); err != nil {
+		logger.FormatLog(context.Background(), "error", fmt.Sprintf("Failed to start gRPC server: %v", err))
+		return err
+	}
+	return nil
+}
+
+func (s *GRPCServer) Stop() {
+	if s.server != nil {
+		s.server.GracefulStop()
+		logger.FormatLog(context.Background(), "info", "gRPC server stopped gracefully")
+	}
+}
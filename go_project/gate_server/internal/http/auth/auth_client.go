@@ -10,12 +10,14 @@ import (
 
 	"github.com/mxxmstar/learning/gate_server/gate_config"
 	http_status_client "github.com/mxxmstar/learning/gate_server/internal/http/status"
-	auth_def "github.com/mxxmstar/learning/pkg/def/auth"
+	auth_def "github.com/mxxmstar/learning/pkg/def/verify/auth"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
 )
 
 type AuthClient struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL       string
+	httpClient    *http.Client
+	internalToken string // 调用登出、吊销等内部接口时携带的共享令牌
 }
 
 func NewAuthClient(c gate_config.Config, httpClient *http.Client) (*AuthClient, error) {
@@ -24,10 +26,17 @@ func NewAuthClient(c gate_config.Config, httpClient *http.Client) (*AuthClient,
 	if err != nil {
 		return nil, err
 	}
+	addr := fmt.Sprintf("%s:%d", verifyServer.HTTPAddress.Host, verifyServer.HTTPAddress.Port)
+	return NewAuthClientWithAddress(addr, c.ServerConfig.GlobalConfig.InternalToken, httpClient), nil
+}
+
+// NewAuthClientWithAddress 使用指定的 verify_server http 地址创建客户端，addr 格式为 host:port
+func NewAuthClientWithAddress(addr, internalToken string, httpClient *http.Client) *AuthClient {
 	return &AuthClient{
-		baseURL:    verifyServer.HTTPAddress.Host + ":" + fmt.Sprint(verifyServer.HTTPAddress.Port),
-		httpClient: httpClient,
-	}, nil
+		baseURL:       "http://" + addr,
+		httpClient:    httpClient,
+		internalToken: internalToken,
+	}
 }
 
 func (c *AuthClient) VerifySession(ctx context.Context, sessionId string) (*auth_def.VerifySessionResponse, error) {
@@ -126,7 +135,9 @@ func (c *AuthClient) RefreshSession(ctx context.Context, sessionId string) (*aut
 	return &res, nil
 }
 
-func (c *AuthClient) LoginByEmail(ctx context.Context, email, password, DeviceId string) (*auth_def.LoginByEmailResponse, error) {
+// LoginByEmail ipAddress 与 userAgent 为客户端的地址与 UA，通过 X-Forwarded-For 与 User-Agent 透传，用于登录限流
+// verify_server 只信任携带共享令牌的请求透传的 IP
+func (c *AuthClient) LoginByEmail(ctx context.Context, email, password, DeviceId, ipAddress, userAgent string) (*auth_def.LoginByEmailResponse, error) {
 	req := &auth_def.LoginByEmailRequest{
 		Email:    email,
 		Password: password,
@@ -138,11 +149,20 @@ func (c *AuthClient) LoginByEmail(ctx context.Context, email, password, DeviceId
 		return nil, err
 	}
 
-	response, err := c.httpClient.Post(
-		fmt.Sprintf("%s/gate/user-auth/loginByEmail", c.baseURL),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/gate/user-auth/loginByEmail", c.baseURL), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(auth_def.InternalTokenHeader, c.internalToken)
+	if ipAddress != "" {
+		httpReq.Header.Set("X-Forwarded-For", ipAddress)
+	}
+	if userAgent != "" {
+		httpReq.Header.Set("User-Agent", userAgent)
+	}
+
+	response, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -194,3 +214,234 @@ func (c *AuthClient) SignUp(ctx context.Context, username, email, password, conf
 	}
 	return &res, nil
 }
+
+func (c *AuthClient) RefreshJWT(ctx context.Context, refreshToken string) (*auth_def.RefreshJWTResponse, error) {
+	req := &auth_def.RefreshJWTRequest{
+		RefreshToken: refreshToken,
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.httpClient.Post(
+		fmt.Sprintf("%s/gate/user-auth/refresh-jwt", c.baseURL),
+		"application/json",
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res auth_def.RefreshJWTResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *AuthClient) Logout(ctx context.Context, sessionId, jwtToken string) (*auth_def.LogoutResponse, error) {
+	req := &auth_def.LogoutRequest{
+		SessionId: sessionId,
+		JWTToken:  jwtToken,
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.postInternal(ctx, "/gate/user-auth/logout", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res auth_def.LogoutResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *AuthClient) RevokeUserTokens(ctx context.Context, userId uint64) (*auth_def.RevokeUserTokensResponse, error) {
+	req := &auth_def.RevokeUserTokensRequest{
+		UserId: userId,
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.postInternal(ctx, "/gate/user-auth/revoke-user-tokens", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res auth_def.RevokeUserTokensResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListSessions sessionId 与 jwtToken 为调用方自己的凭证，二选一
+func (c *AuthClient) ListSessions(ctx context.Context, sessionId, jwtToken string) (*auth_def.ListSessionsResponse, error) {
+	req := &auth_def.ListSessionsRequest{
+		SessionId: sessionId,
+		JWTToken:  jwtToken,
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.httpClient.Post(
+		fmt.Sprintf("%s/gate/user-auth/sessions", c.baseURL),
+		"application/json",
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res auth_def.ListSessionsResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// RevokeSession handle 取自 ListSessions
+func (c *AuthClient) RevokeSession(ctx context.Context, sessionId, jwtToken, handle string) (*auth_def.RevokeSessionResponse, error) {
+	req := &auth_def.RevokeSessionRequest{
+		SessionId: sessionId,
+		JWTToken:  jwtToken,
+		Handle:    handle,
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.httpClient.Post(
+		fmt.Sprintf("%s/gate/user-auth/revoke-session", c.baseURL),
+		"application/json",
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res auth_def.RevokeSessionResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *AuthClient) RevokeOtherSessions(ctx context.Context, sessionId, jwtToken string) (*auth_def.RevokeOtherSessionsResponse, error) {
+	req := &auth_def.RevokeOtherSessionsRequest{
+		SessionId: sessionId,
+		JWTToken:  jwtToken,
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.httpClient.Post(
+		fmt.Sprintf("%s/gate/user-auth/revoke-other-sessions", c.baseURL),
+		"application/json",
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res auth_def.RevokeOtherSessionsResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// postInternal 携带共享令牌调用 verify_server 的内部接口
+func (c *AuthClient) postInternal(ctx context.Context, path string, jsonData []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(auth_def.InternalTokenHeader, c.internalToken)
+	return c.httpClient.Do(httpReq)
+}
+
+// FetchJWKS 获取 verify_server 的 jwt 验签公钥
+func (c *AuthClient) FetchJWKS(ctx context.Context) (*jwt_manager.JWKS, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/.well-known/jwks.json", c.baseURL), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res jwt_manager.JWKS
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
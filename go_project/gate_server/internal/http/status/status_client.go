@@ -2,10 +2,13 @@ package http_status_client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/mxxmstar/learning/gate_server/gate_config"
 	status_def "github.com/mxxmstar/learning/pkg/def/status"
@@ -29,6 +32,17 @@ func NewStatusClient(c gate_config.Config, httpClient *http.Client) *StatusClien
 const (
 	// BaseURL =
 	ServiceDiscoveryURL = "/gate/discovery"
+	ServiceRegisterURL  = "/gate/service/register"
+	ServiceHeartbeatURL = "/gate/service/heartbeat"
+)
+
+// GateServiceType gate 在 status_server 中注册的服务类型
+const GateServiceType = "gate"
+
+// 通过心跳上报的 gate 状态
+const (
+	StatusActive   = "active"   // 正常接入新连接
+	StatusDraining = "draining" // 排空中，不再接入新连接，不应再分配客户端
 )
 
 // 状态码映射
@@ -105,6 +119,97 @@ func GetVerifyServer(c *StatusClient) (*status_def.ServiceInfo, error) {
 	return HandleServiceDiscoveryByTagsResponse(&res)
 }
 
+// 获取负载率最低的 gate 实例，负载由各 gate 的心跳上报，exclude 中的 gate 不参与选择
+func GetGateServer(ctx context.Context, c *StatusClient, exclude ...string) (*status_def.ServiceInfo, error) {
+	req := &status_def.ServiceDiscoveryByTagsRequest{
+		ServiceName: GateServiceType,
+		Strategy:    "Load",
+		Exclude:     exclude,
+	}
+	var res status_def.ServiceDiscoveryByTagsResponse
+	if err := post(ctx, c, ServiceDiscoveryURL+"/by-tags", req, &res); err != nil {
+		return nil, err
+	}
+	return HandleServiceDiscoveryByTagsResponse(&res)
+}
+
+// 获取所有可用的 gate 实例，排空中的 gate 由 status_server 过滤
+func ListGateServers(ctx context.Context, c *StatusClient) ([]*status_def.ServiceInfo, error) {
+	req := &status_def.ServiceDiscoveryRequest{ServiceName: GateServiceType}
+	var res status_def.ServiceDiscoveryResponse
+	if err := post(ctx, c, ServiceDiscoveryURL+"/list", req, &res); err != nil {
+		return nil, err
+	}
+	if res.Code != CodeSuccess {
+		return nil, fmt.Errorf("unexpected response code %d: %s", res.Code, res.Message)
+	}
+	return res.Services, nil
+}
+
+// GateLocator 缓存 status_server 返回的负载较低的 gate，拒绝连接时引导客户端
+type GateLocator struct {
+	client *StatusClient
+	self   string        // 当前 gate 名称，查询到自身时不返回
+	ttl    time.Duration // 缓存时间
+
+	mu         sync.Mutex
+	addr       string
+	expiresAt  time.Time
+	refreshing bool // 正在查询 status_server，其他调用方返回旧的结果
+}
+
+func NewGateLocator(client *StatusClient, self string, ttl time.Duration) *GateLocator {
+	return &GateLocator{client: client, self: self, ttl: ttl}
+}
+
+// LessLoadedGate 返回负载较低的其他 gate 的 http 地址，没有可用的 gate 时返回空字符串
+// 查询 status_server 时不持有锁，同一时间只有一个调用方查询，其他调用方返回缓存的结果
+func (l *GateLocator) LessLoadedGate(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	if l.refreshing || time.Now().Before(l.expiresAt) {
+		addr := l.addr
+		l.mu.Unlock()
+		return addr, nil
+	}
+	l.refreshing = true
+	l.mu.Unlock()
+
+	addr, err := l.locate(ctx)
+
+	// 查询失败时同样缓存，避免拒绝连接时反复请求 status_server
+	l.mu.Lock()
+	l.addr, l.expiresAt, l.refreshing = addr, time.Now().Add(l.ttl), false
+	l.mu.Unlock()
+	return addr, err
+}
+
+func (l *GateLocator) locate(ctx context.Context) (string, error) {
+	gate, err := GetGateServer(ctx, l.client, l.self)
+	if err != nil {
+		return "", err
+	}
+	if gate == nil || gate.ServiceId == l.self || gate.ServiceName == l.self || gate.HTTPAddress == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("%s:%d", gate.HTTPAddress.Host, gate.HTTPAddress.Port), nil
+}
+
+// OtherGates 返回除当前 gate 以外所有可用 gate 的 http 地址，用于排空时引导客户端重连
+func (l *GateLocator) OtherGates(ctx context.Context) ([]string, error) {
+	gates, err := ListGateServers(ctx, l.client)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, gate := range gates {
+		if gate.ServiceId == l.self || gate.ServiceName == l.self || gate.HTTPAddress == nil {
+			continue
+		}
+		addrs = append(addrs, fmt.Sprintf("%s:%d", gate.HTTPAddress.Host, gate.HTTPAddress.Port))
+	}
+	return addrs, nil
+}
+
 // 处理服务发现响应
 func HandleServiceDiscoveryByTagsResponse(res *status_def.ServiceDiscoveryByTagsResponse) (*status_def.ServiceInfo, error) {
 	switch res.Code {
@@ -119,3 +224,66 @@ func HandleServiceDiscoveryByTagsResponse(res *status_def.ServiceDiscoveryByTags
 		return nil, fmt.Errorf("unexpected response code %d: %s", res.Code, res.Message)
 	}
 }
+
+// 注册 gate 服务，返回 status_server 分配的服务 Id
+func RegisterService(ctx context.Context, c *StatusClient, req *status_def.ServiceRegisterRequest) (string, error) {
+	var res status_def.ServiceRegisterResponse
+	if err := post(ctx, c, ServiceRegisterURL, req, &res); err != nil {
+		return "", err
+	}
+	if res.Code != CodeSuccess {
+		if message, exists := ServiceRegisterMessages[res.Code]; exists {
+			return "", fmt.Errorf("%s: %s", message, res.Message)
+		}
+		return "", fmt.Errorf("unexpected response code %d: %s", res.Code, res.Message)
+	}
+	if res.ServiceId == "" {
+		return req.ServiceId, nil
+	}
+	return res.ServiceId, nil
+}
+
+// 上报心跳与当前状态、负载，load 为当前负载，maxLoad 为最大负载，0 表示不限制
+func SendHeartbeat(ctx context.Context, c *StatusClient, serviceType, serviceId, status string, load, maxLoad int) error {
+	req := &status_def.ServiceHeartbeatRequest{
+		ServiceType: serviceType,
+		ServiceId:   serviceId,
+		Status:      status,
+		Timestamp:   time.Now().Unix(),
+		Load:        load,
+		MaxLoad:     maxLoad,
+	}
+	var res status_def.ServiceHeartbeatResponse
+	if err := post(ctx, c, ServiceHeartbeatURL, req, &res); err != nil {
+		return err
+	}
+	if res.Code != CodeSuccess {
+		return fmt.Errorf("unexpected response code %d: %s", res.Code, res.Message)
+	}
+	return nil
+}
+
+func post(ctx context.Context, c *StatusClient, path string, req, res interface{}) error {
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	response, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, res)
+}
@@ -0,0 +1,136 @@
+package msglimit
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+)
+
+// 空闲的令牌桶清理间隔，已回满的桶与不存在的桶等价
+const sweepInterval = time.Minute
+
+// bucket 令牌桶，按 rate 每秒补充令牌，最多保存 burst 个
+type bucket struct {
+	limit  gate_config.RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit gate_config.RateLimit, now time.Time) *bucket {
+	return &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	}
+	b.last = now
+}
+
+// take 取一个令牌，令牌不足时返回下一个令牌的等待时间
+func (b *bucket) take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+func (b *bucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Burst)
+}
+
+// 分片数，不同分片的键互不竞争锁
+const limiterShards = 32
+
+// Limiter 按键限流，每个键按消息类型各有一个令牌桶
+// 规则中单独配置的消息类型使用独立的额度，其他类型共用默认额度
+// 令牌桶按键分片，每个分片各自加锁、各自清理，清理只扫描当前分片
+type Limiter struct {
+	rules  gate_config.RateLimitRules
+	now    func() time.Time
+	shards [limiterShards]limiterShard
+}
+
+type limiterShard struct {
+	mu        sync.Mutex
+	buckets   map[string]map[string]*bucket // key -> 消息类型 -> 令牌桶
+	lastSweep time.Time
+}
+
+func NewLimiter(rules gate_config.RateLimitRules) *Limiter {
+	l := &Limiter{
+		rules: rules,
+		now:   time.Now,
+	}
+	for i := range l.shards {
+		l.shards[i].buckets = make(map[string]map[string]*bucket)
+	}
+	return l
+}
+
+func (l *Limiter) shard(key string) *limiterShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &l.shards[h.Sum32()%limiterShards]
+}
+
+// Allow 消耗 key 在 msgType 上的一个令牌，超限时返回需要等待的时间
+func (l *Limiter) Allow(key, msgType string) (bool, time.Duration) {
+	limit, ok := l.rules.Types[msgType]
+	if !ok {
+		limit, msgType = l.rules.Default, ""
+	}
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return true, 0
+	}
+
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := l.now()
+	s.sweep(now)
+
+	byType, ok := s.buckets[key]
+	if !ok {
+		byType = make(map[string]*bucket)
+		s.buckets[key] = byType
+	}
+	b, ok := byType[msgType]
+	if !ok {
+		b = newBucket(limit, now)
+		byType[msgType] = b
+	}
+	return b.take(now)
+}
+
+// Forget 删除 key 的所有令牌桶，连接关闭时调用
+func (l *Limiter) Forget(key string) {
+	s := l.shard(key)
+	s.mu.Lock()
+	delete(s.buckets, key)
+	s.mu.Unlock()
+}
+
+// sweep 定期清理分片中已回满的令牌桶，调用方需持有 mu
+func (s *limiterShard) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, byType := range s.buckets {
+		for typ, b := range byType {
+			if b.full(now) {
+				delete(byType, typ)
+			}
+		}
+		if len(byType) == 0 {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package msglimit
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewLimiter(gate_config.RateLimitRules{
+		Default: gate_config.RateLimit{Rate: 1, Burst: 2},
+		Types:   map[string]gate_config.RateLimit{"signup": {Rate: 0.5, Burst: 1}},
+	})
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("c1", "chat")
+	assert.True(t, ok)
+	ok, _ = l.Allow("c1", "typing")
+	assert.True(t, ok, "未单独配置的类型共用默认额度")
+	ok, wait := l.Allow("c1", "chat")
+	assert.False(t, ok, "默认额度用完后应该限流")
+	assert.Equal(t, time.Second, wait)
+
+	ok, _ = l.Allow("c1", "signup")
+	assert.True(t, ok, "单独配置的类型使用独立额度")
+	ok, wait = l.Allow("c1", "signup")
+	assert.False(t, ok)
+	assert.Equal(t, 2*time.Second, wait)
+
+	ok, _ = l.Allow("c2", "chat")
+	assert.True(t, ok, "不同的键互不影响")
+
+	now = now.Add(time.Second)
+	ok, _ = l.Allow("c1", "chat")
+	assert.True(t, ok, "令牌应该按速率补充")
+
+	l.Forget("c1")
+	ok, _ = l.Allow("c1", "signup")
+	assert.True(t, ok, "Forget 后额度重新计算")
+
+	// 清理按分片进行，所有分片都被访问后只剩新的令牌桶
+	now = now.Add(time.Hour)
+	for i := 0; i < 1000; i++ {
+		l.Allow(fmt.Sprintf("k%d", i), "")
+	}
+	l.Allow("c3", "chat")
+	assert.Equal(t, 0, l.keys("c1")+l.keys("c2"), "已回满的令牌桶应该被清理")
+
+	unlimited := NewLimiter(gate_config.RateLimitRules{})
+	for i := 0; i < 100; i++ {
+		ok, _ = unlimited.Allow("c1", "chat")
+		assert.True(t, ok, "没有配置额度时不限流")
+	}
+}
+
+// keys 返回 key 现有的令牌桶数
+func (l *Limiter) keys(key string) int {
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets[key])
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	l := NewLimiter(gate_config.RateLimitRules{Default: gate_config.RateLimit{Rate: 1, Burst: 10}})
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if ok, _ := l.Allow("c1", "chat"); ok {
+					allowed.Add(1)
+				}
+				l.Allow(fmt.Sprintf("c%d", j), "chat")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, allowed.Load(), int32(11), "并发访问同一个键时不能超过额度")
+}
+
+func TestViolations(t *testing.T) {
+	now := time.Unix(1000, 0)
+	v := NewViolations(3, 10*time.Second)
+	assert.False(t, v.Add(now))
+	assert.False(t, v.Add(now.Add(time.Second)))
+	assert.True(t, v.Add(now.Add(2*time.Second)), "窗口内超限次数达到上限")
+
+	v = NewViolations(3, 10*time.Second)
+	v.Add(now)
+	v.Add(now.Add(time.Second))
+	assert.False(t, v.Add(now.Add(20*time.Second)), "超过窗口后重新计数")
+}
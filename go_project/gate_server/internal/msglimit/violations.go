@@ -0,0 +1,24 @@
+package msglimit
+
+import "time"
+
+// Violations 统计窗口内的超限次数，达到上限时认为客户端在滥用，非并发安全
+type Violations struct {
+	max    int
+	window time.Duration
+	count  int
+	start  time.Time
+}
+
+func NewViolations(max int, window time.Duration) *Violations {
+	return &Violations{max: max, window: window}
+}
+
+// Add 记录一次超限，返回是否达到上限，max 不大于 0 时从不达到上限
+func (v *Violations) Add(now time.Time) bool {
+	if v.count == 0 || now.Sub(v.start) > v.window {
+		v.count, v.start = 0, now
+	}
+	v.count++
+	return v.max > 0 && v.count >= v.max
+}
@@ -0,0 +1,65 @@
+package online
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/pkg/logger"
+)
+
+// 更新在线状态的超时时间，Redis 不可用时不阻塞连接的注册与注销
+const storeTimeout = 2 * time.Second
+
+// presenceManager 在连接注册与注销时同步更新在线状态
+type presenceManager struct {
+	conn.ConnectionManager
+	store Store
+}
+
+// TrackPresence 包装连接管理器，注册与注销连接时更新在线状态
+// 更新失败只记录日志，连接记录最终会因没有心跳续期而过期
+func TrackPresence(mgr conn.ConnectionManager, store Store) conn.ConnectionManager {
+	return &presenceManager{
+		ConnectionManager: mgr,
+		store:             store,
+	}
+}
+
+func (m *presenceManager) Register(c conn.Connection) error {
+	if err := m.ConnectionManager.Register(c); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := m.store.Add(ctx, InfoOf(c)); err != nil {
+		logger.FormatLog(ctx, "error", fmt.Sprintf("[online] add conn %s failed: %v", c.Id(), err))
+	}
+	return nil
+}
+
+func (m *presenceManager) UnRegister(c conn.Connection) error {
+	if err := m.ConnectionManager.UnRegister(c); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := m.store.Remove(ctx, c.UserId(), c.Id()); err != nil {
+		logger.FormatLog(ctx, "error", fmt.Sprintf("[online] remove conn %s failed: %v", c.Id(), err))
+	}
+	return nil
+}
+
+// InfoOf 获取连接的在线状态信息，连接没有实现 Describer 时只包含基本信息
+func InfoOf(c conn.Connection) ConnInfo {
+	if d, ok := c.(Describer); ok {
+		return d.ConnInfo()
+	}
+	return ConnInfo{
+		ConnId:  c.Id(),
+		UserId:  c.UserId(),
+		GateId:  GateIdOf(c.Id()),
+		LoginAt: time.Now().UnixMilli(),
+	}
+}
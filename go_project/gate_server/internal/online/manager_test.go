@@ -0,0 +1,41 @@
+package online
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStore 只记录在线连接，用于验证管理器的调用
+type fakeStore struct {
+	Store
+	conns map[string]ConnInfo
+}
+
+func (s *fakeStore) Add(ctx context.Context, info ConnInfo) error {
+	s.conns[info.ConnId] = info
+	return nil
+}
+
+func (s *fakeStore) Remove(ctx context.Context, userId uint64, connId string) error {
+	delete(s.conns, connId)
+	return nil
+}
+
+func TestTrackPresence(t *testing.T) {
+	store := &fakeStore{conns: make(map[string]ConnInfo)}
+	mgr := TrackPresence(conn.NewManager(), store)
+
+	c := conntest.New("gate1#a", 1)
+	assert.NoError(t, mgr.Register(c))
+	assert.Equal(t, "gate1", store.conns["gate1#a"].GateId, "注册时应该写入在线状态")
+	assert.Len(t, mgr.GetConnectionsByUserId(1), 1, "本地连接表应该同步注册")
+
+	assert.NoError(t, mgr.UnRegister(c))
+	assert.Empty(t, store.conns, "注销时应该删除在线状态")
+
+	assert.ErrorIs(t, mgr.UnRegister(c), conn.ErrConnectionNotFound, "重复注销应该返回错误")
+}
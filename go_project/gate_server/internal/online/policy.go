@@ -0,0 +1,51 @@
+package online
+
+import "github.com/mxxmstar/learning/gate_server/gate_config"
+
+// LoginPolicy 多端登录策略
+type LoginPolicy string
+
+const (
+	LoginPolicyMulti         LoginPolicy = "multi"           // 允许多个连接同时在线
+	LoginPolicyPerDeviceType LoginPolicy = "per_device_type" // 每种设备类型只保留一个连接
+	LoginPolicySingle        LoginPolicy = "single"          // 全局只保留一个连接
+)
+
+// PolicyFor 根据设备类型选择登录策略，未配置的设备类型使用默认策略
+func PolicyFor(cfg gate_config.LoginPolicyConfig, deviceType string) LoginPolicy {
+	policy := LoginPolicyMulti
+	if p, ok := cfg.DeviceTypes[deviceType]; ok {
+		policy = LoginPolicy(p)
+	} else if cfg.Default != "" {
+		policy = LoginPolicy(cfg.Default)
+	}
+	return policy
+}
+
+// Conflicts 返回新连接按策略需要踢掉的旧连接
+// 只踢掉比新连接更早登录的连接，多个 gate 并发登录时最后登录的连接保留
+// per_device_type 下没有上报设备类型的连接视为同一种设备，不能借此绕过限制
+func Conflicts(policy LoginPolicy, newConn ConnInfo, conns []ConnInfo) []ConnInfo {
+	var conflicts []ConnInfo
+	for _, c := range conns {
+		if c.ConnId == newConn.ConnId || !olderThan(c, newConn) {
+			continue
+		}
+		switch policy {
+		case LoginPolicySingle:
+			conflicts = append(conflicts, c)
+		case LoginPolicyPerDeviceType:
+			if c.DeviceType == newConn.DeviceType {
+				conflicts = append(conflicts, c)
+			}
+		}
+	}
+	return conflicts
+}
+
+func olderThan(a, b ConnInfo) bool {
+	if a.LoginAt != b.LoginAt {
+		return a.LoginAt < b.LoginAt
+	}
+	return a.ConnId < b.ConnId
+}
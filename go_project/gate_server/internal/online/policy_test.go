@@ -0,0 +1,57 @@
+package online
+
+import (
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/stretchr/testify/assert"
+)
+
+func connIds(conns []ConnInfo) []string {
+	ids := make([]string, 0, len(conns))
+	for _, c := range conns {
+		ids = append(ids, c.ConnId)
+	}
+	return ids
+}
+
+func TestConflicts(t *testing.T) {
+	conns := []ConnInfo{
+		{ConnId: "gate1#a", DeviceType: "mobile", LoginAt: 1},
+		{ConnId: "gate2#b", DeviceType: "web", LoginAt: 2},
+		{ConnId: "gate1#c", DeviceType: "mobile", LoginAt: 3},
+	}
+	newConn := conns[2]
+
+	assert.Empty(t, Conflicts(LoginPolicyMulti, newConn, conns), "multi 策略不应该踢人")
+	assert.Equal(t, []string{"gate1#a"}, connIds(Conflicts(LoginPolicyPerDeviceType, newConn, conns)), "应该只踢掉同类型设备")
+	assert.Equal(t, []string{"gate1#a", "gate2#b"}, connIds(Conflicts(LoginPolicySingle, newConn, conns)), "应该踢掉其他所有连接")
+
+	// 并发登录时较早的连接不会踢掉较晚的连接
+	assert.Empty(t, Conflicts(LoginPolicySingle, conns[0], conns), "较早的连接不应该踢掉较晚的连接")
+}
+
+func TestPolicyFor(t *testing.T) {
+	cfg := gate_config.LoginPolicyConfig{
+		Default:     string(LoginPolicyPerDeviceType),
+		DeviceTypes: map[string]string{"web": string(LoginPolicyMulti)},
+	}
+	assert.Equal(t, LoginPolicyMulti, PolicyFor(cfg, "web"))
+	assert.Equal(t, LoginPolicyPerDeviceType, PolicyFor(cfg, "mobile"))
+	assert.Equal(t, LoginPolicyMulti, PolicyFor(gate_config.LoginPolicyConfig{}, "mobile"), "未配置时默认允许多端登录")
+	assert.Equal(t, LoginPolicyPerDeviceType, PolicyFor(cfg, ""), "没有设备类型时仍然使用默认策略")
+	assert.Equal(t, LoginPolicySingle, PolicyFor(gate_config.LoginPolicyConfig{Default: string(LoginPolicySingle)}, ""), "single 策略不受设备类型影响")
+}
+
+func TestConflictsEmptyDeviceType(t *testing.T) {
+	conns := []ConnInfo{
+		{ConnId: "gate1#a", LoginAt: 1},
+		{ConnId: "gate1#b", LoginAt: 2},
+	}
+	assert.Equal(t, []string{"gate1#a"}, connIds(Conflicts(LoginPolicyPerDeviceType, conns[1], conns)), "没有设备类型的连接视为同一种设备")
+}
+
+func TestGateIdOf(t *testing.T) {
+	assert.Equal(t, "gate_server_1", GateIdOf("gate_server_1#abcdef"))
+	assert.Equal(t, "", GateIdOf("abcdef"))
+}
@@ -0,0 +1,348 @@
+package online
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/pkg/logger"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// PresenceChannel 在线状态变化事件的发布订阅频道
+	PresenceChannel = "presence:events"
+
+	onlinePrefix      = "online:"           // 用户的在线连接，hash field 为连接 Id
+	expirePrefix      = "online_expire:"    // 用户在线连接的过期时间，zset score 为过期时间(ms)
+	lastSeenPrefix    = "online_last_seen:" // 用户最后在线时间(ms)
+	kickChannelPrefix = "gate:kick:"        // 每个 gate 的踢人通知频道
+	topicPrefix       = "topic:"            // 主题在各 gate 上的订阅连接数，hash field 为 gate Id，value 为 "连接数:过期时间(ms)"
+)
+
+// 清理已过期的连接，返回清理的数量和最晚的过期时间
+// KEYS[1] 在线连接 KEYS[2] 过期时间 ARGV[1] 当前时间(ms)
+const pruneFunc = `
+local function prune(now)
+	local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", now, "WITHSCORES")
+	local latest = 0
+	for i = 1, #expired, 2 do
+		redis.call("HDEL", KEYS[1], expired[i])
+		latest = math.max(latest, tonumber(expired[i + 1]))
+	end
+	if #expired > 0 then
+		redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", now)
+	end
+	return #expired / 2, latest
+end
+`
+
+// touchScript 写入或续期连接，用户从离线变为在线时返回 1
+// ARGV[2] 连接 Id ARGV[3] 连接信息 ARGV[4] 连接过期时间(ms)
+const touchScript = pruneFunc + `
+prune(ARGV[1])
+local wasOnline = redis.call("ZCARD", KEYS[2]) > 0
+redis.call("HSET", KEYS[1], ARGV[2], ARGV[3])
+redis.call("ZADD", KEYS[2], string.format("%d", ARGV[1] + ARGV[4]), ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+redis.call("PEXPIRE", KEYS[2], ARGV[4])
+if wasOnline then
+	return 0
+end
+return 1
+`
+
+// removeScript 删除连接，用户从在线变为离线时记录最后在线时间并返回 1
+// KEYS[3] 最后在线时间 ARGV[2] 连接 Id ARGV[3] 最后在线时间的过期时间(ms)
+const removeScript = pruneFunc + `
+local removed = redis.call("ZREM", KEYS[2], ARGV[2])
+redis.call("HDEL", KEYS[1], ARGV[2])
+local pruned = prune(ARGV[1])
+if redis.call("ZCARD", KEYS[2]) > 0 or (removed == 0 and pruned == 0) then
+	return 0
+end
+redis.call("SET", KEYS[3], ARGV[1], "PX", ARGV[3])
+return 1
+`
+
+// setTopicScript 记录本 gate 上主题的订阅连接数，连接数为 0 时删除记录
+// 每条记录带过期时间，由 TopicSyncer 定期续期，gate 异常退出后其记录过期失效；key 本身随最近一次写入续期
+// KEYS[1] 主题 ARGV[1] gate Id ARGV[2] 订阅连接数 ARGV[3] 当前时间(ms) ARGV[4] 过期时间(ms)
+const setTopicScript = `
+if tonumber(ARGV[2]) <= 0 then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2] .. ":" .. string.format("%d", ARGV[3] + ARGV[4]))
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return 1
+`
+
+// queryScript 查询用户的在线连接，返回 {是否因过期而离线, 最后在线时间, 连接信息...}
+// 连接全部过期（gate 异常退出）时以最晚的过期时间减去连接过期时间作为最后在线时间
+// KEYS[3] 最后在线时间 ARGV[2] 连接过期时间(ms) ARGV[3] 最后在线时间的过期时间(ms)
+const queryScript = pruneFunc + `
+local pruned, latest = prune(ARGV[1])
+local conns = redis.call("HVALS", KEYS[1])
+local expired = 0
+if #conns == 0 and pruned > 0 then
+	expired = 1
+	redis.call("SET", KEYS[3], string.format("%d", latest - ARGV[2]), "PX", ARGV[3])
+end
+local result = {expired, redis.call("GET", KEYS[3]) or ""}
+for _, c in ipairs(conns) do
+	table.insert(result, c)
+end
+return result
+`
+
+type kickMessage struct {
+	ConnId string `json:"conn_id"`
+	Reason string `json:"reason"`
+}
+
+// RedisStore 基于 Redis 的在线状态存储
+// 每个连接单独记录过期时间并由心跳续期，gate 异常退出时连接记录会在 ConnTTL 后过期
+type RedisStore struct {
+	client      *redis.RedisClient
+	connTTL     time.Duration
+	lastSeenTTL time.Duration
+}
+
+func NewRedisStore(client *redis.RedisClient, cfg gate_config.PresenceConfig) *RedisStore {
+	if cfg.ConnTTL <= 0 {
+		cfg.ConnTTL = 90 * time.Second
+	}
+	if cfg.LastSeenTTL <= 0 {
+		cfg.LastSeenTTL = 30 * 24 * time.Hour
+	}
+	return &RedisStore{
+		client:      client,
+		connTTL:     cfg.ConnTTL,
+		lastSeenTTL: cfg.LastSeenTTL,
+	}
+}
+
+func (s *RedisStore) Add(ctx context.Context, info ConnInfo) error {
+	return s.Touch(ctx, info)
+}
+
+func (s *RedisStore) Touch(ctx context.Context, info ConnInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixMilli()
+	becameOnline, err := s.client.Eval(ctx, touchScript, userKeys(info.UserId),
+		now, info.ConnId, data, s.connTTL.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if becameOnline == 1 {
+		s.publish(ctx, PresenceEvent{Type: PresenceOnline, UserId: info.UserId, GateId: info.GateId, ConnId: info.ConnId, At: now})
+	}
+	return nil
+}
+
+func (s *RedisStore) Remove(ctx context.Context, userId uint64, connId string) error {
+	now := time.Now().UnixMilli()
+	becameOffline, err := s.client.Eval(ctx, removeScript, userKeys(userId),
+		now, connId, s.lastSeenTTL.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if becameOffline == 1 {
+		s.publish(ctx, PresenceEvent{Type: PresenceOffline, UserId: userId, GateId: GateIdOf(connId), ConnId: connId, At: now})
+	}
+	return nil
+}
+
+func (s *RedisStore) Query(ctx context.Context, userId uint64) (*Presence, error) {
+	values, err := s.queryCmd(ctx, s.client.GetClient(), userId).Slice()
+	if err != nil {
+		return nil, err
+	}
+	return s.parsePresence(ctx, userId, values), nil
+}
+
+func (s *RedisStore) QueryMany(ctx context.Context, userIds []uint64) (map[uint64]*Presence, error) {
+	pipe := s.client.GetClient().Pipeline()
+	cmds := make([]*goredis.Cmd, len(userIds))
+	for i, userId := range userIds {
+		cmds[i] = s.queryCmd(ctx, pipe, userId)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint64]*Presence, len(userIds))
+	for i, userId := range userIds {
+		values, err := cmds[i].Slice()
+		if err != nil {
+			return nil, err
+		}
+		result[userId] = s.parsePresence(ctx, userId, values)
+	}
+	return result, nil
+}
+
+func (s *RedisStore) queryCmd(ctx context.Context, c goredis.Scripter, userId uint64) *goredis.Cmd {
+	return c.Eval(ctx, queryScript, userKeys(userId),
+		time.Now().UnixMilli(), s.connTTL.Milliseconds(), s.lastSeenTTL.Milliseconds())
+}
+
+// parsePresence 解析 queryScript 的返回值，连接全部过期时发布离线事件
+func (s *RedisStore) parsePresence(ctx context.Context, userId uint64, values []interface{}) *Presence {
+	p := &Presence{UserId: userId}
+	if len(values) < 2 {
+		return p
+	}
+	for _, v := range values[2:] {
+		data, _ := v.(string)
+		var c ConnInfo
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			continue
+		}
+		p.Conns = append(p.Conns, c)
+	}
+	p.Online = len(p.Conns) > 0
+	if !p.Online {
+		lastSeen, _ := values[1].(string)
+		p.LastSeen, _ = strconv.ParseInt(lastSeen, 10, 64)
+	}
+	if expired, _ := values[0].(int64); expired == 1 {
+		s.publish(ctx, PresenceEvent{Type: PresenceOffline, UserId: userId, At: p.LastSeen})
+	}
+	return p
+}
+
+func (s *RedisStore) SubscribePresence(ctx context.Context, handler func(PresenceEvent)) error {
+	return s.subscribe(ctx, PresenceChannel, func(payload string) {
+		var event PresenceEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			logger.FormatLog(ctx, "error", fmt.Sprintf("[online] invalid presence event: %v", err))
+			return
+		}
+		handler(event)
+	})
+}
+
+func (s *RedisStore) Kick(ctx context.Context, connId, reason string) error {
+	gateId := GateIdOf(connId)
+	if gateId == "" {
+		return fmt.Errorf("kick: invalid conn id %s", connId)
+	}
+	data, err := json.Marshal(kickMessage{ConnId: connId, Reason: reason})
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, kickChannelPrefix+gateId, data)
+}
+
+func (s *RedisStore) SubscribeKick(ctx context.Context, gateId string, handler func(connId, reason string)) error {
+	return s.subscribe(ctx, kickChannelPrefix+gateId, func(payload string) {
+		var kick kickMessage
+		if err := json.Unmarshal([]byte(payload), &kick); err != nil {
+			logger.FormatLog(ctx, "error", fmt.Sprintf("[online] invalid kick message: %v", err))
+			return
+		}
+		handler(kick.ConnId, kick.Reason)
+	})
+}
+
+// TopicTTL 主题订阅记录的过期时间，TopicSyncer 需要在此之前续期
+func (s *RedisStore) TopicTTL() time.Duration {
+	return s.connTTL
+}
+
+func (s *RedisStore) SetTopicMembers(ctx context.Context, topic, gateId string, members int) error {
+	return s.client.Eval(ctx, setTopicScript, []string{topicPrefix + topic},
+		gateId, members, time.Now().UnixMilli(), s.connTTL.Milliseconds()).Err()
+}
+
+// TopicGates 查询有订阅者的 gate，过期的记录被忽略并顺带删除
+func (s *RedisStore) TopicGates(ctx context.Context, topic string) (map[string]int, error) {
+	values, err := s.client.GetClient().HGetAll(ctx, topicPrefix+topic).Result()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	gates := make(map[string]int, len(values))
+	var expired []string
+	for gateId, v := range values {
+		n, expiresAt, ok := parseTopicMembers(v)
+		if !ok || expiresAt <= now {
+			expired = append(expired, gateId)
+			continue
+		}
+		if n > 0 {
+			gates[gateId] = n
+		}
+	}
+	if len(expired) > 0 {
+		if err := s.client.GetClient().HDel(ctx, topicPrefix+topic, expired...).Err(); err != nil {
+			logger.FormatLog(ctx, "warn", fmt.Sprintf("[online] delete expired members of topic %s failed: %v", topic, err))
+		}
+	}
+	return gates, nil
+}
+
+// parseTopicMembers 解析 "连接数:过期时间(ms)"
+func parseTopicMembers(v string) (members int, expiresAt int64, ok bool) {
+	count, expires, found := strings.Cut(v, ":")
+	if !found {
+		return 0, 0, false
+	}
+	members, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0, false
+	}
+	expiresAt, err = strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return members, expiresAt, true
+}
+
+func (s *RedisStore) subscribe(ctx context.Context, channel string, handler func(payload string)) error {
+	pubsub := s.client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			handler(msg.Payload)
+		}
+	}
+}
+
+// publish 发布在线状态变化事件，失败只记录日志，订阅方可以通过 Query 兜底
+func (s *RedisStore) publish(ctx context.Context, event PresenceEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if err := s.client.Publish(ctx, PresenceChannel, data); err != nil {
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("[online] publish presence event failed: %v", err))
+	}
+}
+
+func userKeys(userId uint64) []string {
+	uid := strconv.FormatUint(userId, 10)
+	return []string{onlinePrefix + uid, expirePrefix + uid, lastSeenPrefix + uid}
+}
@@ -0,0 +1,67 @@
+package online
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicMembersExpire(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewRedisClient(mr.Addr(), "", 0), gate_config.PresenceConfig{ConnTTL: 200 * time.Millisecond})
+	ctx := context.Background()
+
+	require.NoError(t, store.SetTopicMembers(ctx, "news", "gate_1", 2))
+	require.NoError(t, store.SetTopicMembers(ctx, "news", "gate_2", 1))
+	gates, err := store.TopicGates(ctx, "news")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"gate_1": 2, "gate_2": 1}, gates)
+
+	// gate_1 异常退出不再续期，gate_2 持续续期
+	time.Sleep(120 * time.Millisecond)
+	require.NoError(t, store.SetTopicMembers(ctx, "news", "gate_2", 1))
+	time.Sleep(120 * time.Millisecond)
+	gates, err = store.TopicGates(ctx, "news")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"gate_2": 1}, gates, "未续期的 gate 记录应该过期")
+
+	require.NoError(t, store.SetTopicMembers(ctx, "news", "gate_2", 0))
+	gates, err = store.TopicGates(ctx, "news")
+	require.NoError(t, err)
+	assert.Empty(t, gates)
+}
+
+// topicStore 记录 SetTopicMembers 的调用次数
+type topicStore struct {
+	Store
+	writes chan int
+}
+
+func (s *topicStore) SetTopicMembers(ctx context.Context, topic, gateId string, members int) error {
+	s.writes <- members
+	return nil
+}
+
+func TestTopicSyncerRenew(t *testing.T) {
+	store := &topicStore{writes: make(chan int, 16)}
+	syncer := NewTopicSyncer(store, "gate_1", 20*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go syncer.Run(ctx)
+
+	syncer.Observe("news", 3)
+	for i := 0; i < 3; i++ {
+		select {
+		case members := <-store.writes:
+			assert.Equal(t, 3, members)
+		case <-time.After(time.Second):
+			t.Fatal("有订阅者的主题应该定期续期")
+		}
+	}
+}
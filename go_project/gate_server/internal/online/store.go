@@ -0,0 +1,78 @@
+package online
+
+import (
+	"context"
+	"strings"
+)
+
+// ConnInfo 跨 gate 共享的在线连接信息
+type ConnInfo struct {
+	ConnId     string `json:"conn_id"`
+	UserId     uint64 `json:"user_id"`
+	GateId     string `json:"gate_id"`
+	DeviceId   string `json:"device_id,omitempty"`
+	DeviceType string `json:"device_type,omitempty"`
+	LoginAt    int64  `json:"login_at"` // 认证成功的时间，unix 毫秒
+}
+
+// Presence 用户在线状态
+type Presence struct {
+	UserId   uint64     `json:"user_id"`
+	Online   bool       `json:"online"`
+	LastSeen int64      `json:"last_seen,omitempty"` // 最后一个连接下线的时间，unix 毫秒，在线时为 0
+	Conns    []ConnInfo `json:"conns,omitempty"`     // 在线连接及其所在的 gate
+}
+
+// PresenceEventType 在线状态变化事件类型
+type PresenceEventType string
+
+const (
+	PresenceOnline  PresenceEventType = "online"  // 用户的第一个连接上线
+	PresenceOffline PresenceEventType = "offline" // 用户的最后一个连接下线或过期
+)
+
+// PresenceEvent 在线状态变化事件，只在用户整体上线/下线时发布，单个连接的变化不发布
+type PresenceEvent struct {
+	Type   PresenceEventType `json:"type"`
+	UserId uint64            `json:"user_id"`
+	GateId string            `json:"gate_id,omitempty"` // 触发事件的连接所在的 gate，连接过期时为空
+	ConnId string            `json:"conn_id,omitempty"`
+	At     int64             `json:"at"` // 事件时间，unix 毫秒
+}
+
+// Store 跨 gate 共享的在线状态存储，用于多端登录策略与在线状态查询
+type Store interface {
+	// Add 记录新连接
+	Add(ctx context.Context, info ConnInfo) error
+	// Touch 心跳续期连接记录，记录已过期时重新写入
+	Touch(ctx context.Context, info ConnInfo) error
+	// Remove 删除连接记录
+	Remove(ctx context.Context, userId uint64, connId string) error
+	// Query 查询用户的在线状态
+	Query(ctx context.Context, userId uint64) (*Presence, error)
+	// QueryMany 批量查询用户的在线状态
+	QueryMany(ctx context.Context, userIds []uint64) (map[uint64]*Presence, error)
+	// SubscribePresence 订阅在线状态变化事件，阻塞直到 ctx 结束
+	SubscribePresence(ctx context.Context, handler func(PresenceEvent)) error
+	// Kick 通知持有该连接的 gate 踢掉连接
+	Kick(ctx context.Context, connId, reason string) error
+	// SubscribeKick 订阅发给本 gate 的踢人通知，阻塞直到 ctx 结束
+	SubscribeKick(ctx context.Context, gateId string, handler func(connId, reason string)) error
+	// SetTopicMembers 记录本 gate 上主题的订阅连接数，为 0 时删除记录
+	SetTopicMembers(ctx context.Context, topic, gateId string, members int) error
+	// TopicGates 查询有订阅者的 gate 及其订阅连接数
+	TopicGates(ctx context.Context, topic string) (map[string]int, error)
+}
+
+// Describer 可以提供在线状态信息的连接
+type Describer interface {
+	ConnInfo() ConnInfo
+}
+
+// GateIdOf 从连接 Id 中解析 gate Id，连接 Id 格式为 gateId#uuid
+func GateIdOf(connId string) string {
+	if i := strings.LastIndex(connId, "#"); i > 0 {
+		return connId[:i]
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package online
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mxxmstar/learning/pkg/logger"
+)
+
+// 同步失败后的重试间隔
+const topicRetryInterval = time.Second
+
+// TopicSyncer 将本 gate 上主题的订阅连接数同步到 Store，其他 gate 据此只向有订阅者的 gate 转发主题消息
+// 连接管理器在锁内通知变化，这里只记录每个主题最新的连接数，由 Run 在后台按主题合并写入
+// Store 中的记录会过期，Run 每隔 refresh 重新写入当前所有有订阅者的主题，gate 异常退出后其记录随之失效
+type TopicSyncer struct {
+	store   Store
+	gateId  string
+	refresh time.Duration
+
+	mu      sync.Mutex
+	pending map[string]int
+	current map[string]int // 有订阅者的主题及其连接数，用于续期
+	notify  chan struct{}
+}
+
+// NewTopicSyncer refresh 为续期间隔，应小于 Store 中记录的过期时间，<=0 时不续期
+func NewTopicSyncer(store Store, gateId string, refresh time.Duration) *TopicSyncer {
+	return &TopicSyncer{
+		store:   store,
+		gateId:  gateId,
+		refresh: refresh,
+		pending: make(map[string]int),
+		current: make(map[string]int),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Observe 记录主题最新的订阅连接数，作为 conn.TopicObserver 使用，不会阻塞
+func (s *TopicSyncer) Observe(topic string, members int) {
+	s.mu.Lock()
+	s.pending[topic] = members
+	if members > 0 {
+		s.current[topic] = members
+	} else {
+		delete(s.current, topic)
+	}
+	s.mu.Unlock()
+	s.wake()
+}
+
+// Run 将变化写入 Store，阻塞直到 ctx 结束
+// 写入失败的主题保留到下一轮重试，期间有新的变化时以最新的连接数为准
+func (s *TopicSyncer) Run(ctx context.Context) {
+	var refresh <-chan time.Time
+	if s.refresh > 0 {
+		ticker := time.NewTicker(s.refresh)
+		defer ticker.Stop()
+		refresh = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.notify:
+		case <-refresh:
+			s.renew()
+		}
+
+		if failed := s.flush(ctx); failed > 0 {
+			logger.FormatLog(ctx, "warn", fmt.Sprintf("[online] sync %d topics failed, retrying in %s", failed, topicRetryInterval))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(topicRetryInterval):
+			}
+			s.wake()
+		}
+	}
+}
+
+func (s *TopicSyncer) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// renew 将所有有订阅者的主题加入待写入，刷新其过期时间；已有待写入变化的主题以变化为准
+func (s *TopicSyncer) renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for topic, members := range s.current {
+		if _, changed := s.pending[topic]; !changed {
+			s.pending[topic] = members
+		}
+	}
+}
+
+// flush 写入当前所有变化，返回失败的主题数
+func (s *TopicSyncer) flush(ctx context.Context) int {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[string]int)
+	s.mu.Unlock()
+
+	failed := 0
+	for topic, members := range batch {
+		wctx, cancel := context.WithTimeout(ctx, storeTimeout)
+		err := s.store.SetTopicMembers(wctx, topic, s.gateId, members)
+		cancel()
+		if err == nil {
+			continue
+		}
+		failed++
+		s.mu.Lock()
+		if _, changed := s.pending[topic]; !changed {
+			s.pending[topic] = members
+		}
+		s.mu.Unlock()
+	}
+	return failed
+}
@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// Message 等待客户端确认的消息
+type Message struct {
+	Seq     uint64
+	Payload []byte // 已写入 seq 的消息
+}
+
+// Store 按流保存服务端推送的消息，直到客户端确认
+// 流由用户与设备确定，客户端重连到任意 gate 后都可以按最后确认的 seq 补发
+type Store interface {
+	// Append 为消息分配递增的 seq 并保存，返回写入 seq 后的消息
+	Append(ctx context.Context, stream string, payload []byte) (Message, error)
+	// Ack 确认 seq 及之前的所有消息
+	Ack(ctx context.Context, stream string, seq uint64) error
+	// Since 返回 seq 之后的所有消息，消息已被淘汰而无法补全时 complete 为 false
+	Since(ctx context.Context, stream string, seq uint64) (msgs []Message, complete bool, err error)
+}
+
+// StreamId 用户在某个设备上的消息流，设备 Id 为空时不支持可靠投递
+func StreamId(userId uint64, deviceId string) string {
+	if deviceId == "" {
+		return ""
+	}
+	return strconv.FormatUint(userId, 10) + ":" + deviceId
+}
+
+// withoutSeq 去掉 JSON 对象消息中已有的 seq 字段，seq 由 Store 在分配时写入，非 JSON 对象返回 false
+func withoutSeq(payload []byte) ([]byte, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return payload, false
+	}
+	delete(fields, "seq")
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return payload, false
+	}
+	return data, true
+}
+
+// WithSeq 在 JSON 对象消息中写入 seq 字段，非 JSON 对象返回 false
+func WithSeq(payload []byte, seq uint64) ([]byte, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return payload, false
+	}
+	fields["seq"] = json.RawMessage(strconv.FormatUint(seq, 10))
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return payload, false
+	}
+	return data, true
+}
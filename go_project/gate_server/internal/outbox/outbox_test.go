@@ -0,0 +1,29 @@
+package outbox
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSeq(t *testing.T) {
+	data, ok := WithSeq([]byte(`{"type":"chat","body":{"text":"hi"}}`), 42)
+	assert.True(t, ok, "JSON 对象应该能写入 seq")
+
+	var msg struct {
+		Type string `json:"type"`
+		Seq  uint64 `json:"seq"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, uint64(42), msg.Seq, "seq 应该写入消息")
+	assert.Equal(t, "chat", msg.Type, "其他字段应该保留")
+
+	_, ok = WithSeq([]byte("plain text"), 1)
+	assert.False(t, ok, "非 JSON 消息不能写入 seq")
+	_, ok = WithSeq([]byte(`[1,2]`), 1)
+	assert.False(t, ok, "JSON 数组不能写入 seq")
+
+	assert.Equal(t, "", StreamId(1, ""), "没有设备 Id 时不支持可靠投递")
+	assert.Equal(t, "1:phone", StreamId(1, "phone"))
+}
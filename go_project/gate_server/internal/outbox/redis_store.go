@@ -0,0 +1,107 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	seqPrefix    = "outbox_seq:" // 流的当前 seq
+	bufferPrefix = "outbox:"     // 等待确认的消息，zset score 为 seq
+)
+
+// appendScript 分配 seq、写入消息并淘汰超出容量的最早消息，在一个脚本中完成，seq 与消息不会不一致
+// KEYS[1] 当前 seq KEYS[2] 消息 ARGV[1] 去掉开头 "{" 的 JSON 对象 ARGV[2] 容量 ARGV[3] 过期时间(ms)
+// 返回 {seq, 写入 seq 后的消息}
+const appendScript = `
+local seq = redis.call("INCR", KEYS[1])
+local msg
+if ARGV[1] == "}" then
+	msg = '{"seq":' .. seq .. '}'
+else
+	msg = '{"seq":' .. seq .. ',' .. ARGV[1]
+end
+redis.call("ZADD", KEYS[2], seq, msg)
+redis.call("ZREMRANGEBYRANK", KEYS[2], 0, -tonumber(ARGV[2]) - 1)
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+redis.call("PEXPIRE", KEYS[2], ARGV[3])
+return {seq, msg}
+`
+
+// RedisStore 基于 Redis 的消息补发缓冲
+type RedisStore struct {
+	client *redis.RedisClient
+	size   int           // 每个流最多保存的消息数
+	ttl    time.Duration // 流没有新消息后的保存时间
+}
+
+func NewRedisStore(client *redis.RedisClient, cfg gate_config.ReliableConfig) *RedisStore {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.BufferTTL <= 0 {
+		cfg.BufferTTL = 24 * time.Hour
+	}
+	return &RedisStore{
+		client: client,
+		size:   cfg.BufferSize,
+		ttl:    cfg.BufferTTL,
+	}
+}
+
+func (s *RedisStore) Append(ctx context.Context, stream string, payload []byte) (Message, error) {
+	data, ok := withoutSeq(payload)
+	if !ok {
+		return Message{}, fmt.Errorf("outbox: payload is not a json object")
+	}
+	res, err := s.client.Eval(ctx, appendScript, []string{seqPrefix + stream, bufferPrefix + stream},
+		data[1:], s.size, s.ttl.Milliseconds()).Slice()
+	if err != nil {
+		return Message{}, err
+	}
+	if len(res) != 2 {
+		return Message{}, fmt.Errorf("outbox: unexpected append result %v", res)
+	}
+	seq, _ := res[0].(int64)
+	msg, _ := res[1].(string)
+	return Message{Seq: uint64(seq), Payload: []byte(msg)}, nil
+}
+
+func (s *RedisStore) Ack(ctx context.Context, stream string, seq uint64) error {
+	return s.client.GetClient().ZRemRangeByScore(ctx, bufferPrefix+stream, "-inf", fmt.Sprint(seq)).Err()
+}
+
+func (s *RedisStore) Since(ctx context.Context, stream string, seq uint64) ([]Message, bool, error) {
+	pipe := s.client.GetClient().Pipeline()
+	current := pipe.Get(ctx, seqPrefix+stream)
+	buffered := pipe.ZRangeByScoreWithScores(ctx, bufferPrefix+stream, &goredis.ZRangeBy{
+		Min: fmt.Sprintf("(%d", seq),
+		Max: "+inf",
+	})
+	if _, err := pipe.Exec(ctx); err != nil && err != goredis.Nil {
+		return nil, false, err
+	}
+
+	var latest uint64
+	if v, err := current.Uint64(); err == nil {
+		latest = v
+	}
+	if seq >= latest {
+		// 没有新消息，或客户端确认的 seq 比服务端新（流已过期重建）
+		return nil, seq == latest, nil
+	}
+
+	msgs := make([]Message, 0, len(buffered.Val()))
+	for _, z := range buffered.Val() {
+		payload, _ := z.Member.(string)
+		msgs = append(msgs, Message{Seq: uint64(z.Score), Payload: []byte(payload)})
+	}
+	// 缺少紧接着的消息说明已被淘汰
+	complete := len(msgs) > 0 && msgs[0].Seq == seq+1
+	return msgs, complete, nil
+}
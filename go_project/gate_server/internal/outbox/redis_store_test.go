@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, size int) (*miniredis.Miniredis, *RedisStore) {
+	mr := miniredis.RunT(t)
+	return mr, NewRedisStore(redis.NewRedisClient(mr.Addr(), "", 0), gate_config.ReliableConfig{BufferSize: size, BufferTTL: time.Hour})
+}
+
+func appendN(t *testing.T, s *RedisStore, stream string, n int) {
+	for i := 0; i < n; i++ {
+		_, err := s.Append(context.Background(), stream, []byte(fmt.Sprintf(`{"type":"chat","seq":99,"body":{"n":%d}}`, i)))
+		require.NoError(t, err)
+	}
+}
+
+func seqs(msgs []Message) []uint64 {
+	out := make([]uint64, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, m.Seq)
+	}
+	return out
+}
+
+func TestRedisStoreAppend(t *testing.T) {
+	_, s := newTestStore(t, 10)
+	ctx := context.Background()
+
+	m, err := s.Append(ctx, "1:phone", []byte(`{"type":"chat","seq":99}`))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), m.Seq)
+	var msg struct {
+		Type string `json:"type"`
+		Seq  uint64 `json:"seq"`
+	}
+	require.NoError(t, json.Unmarshal(m.Payload, &msg))
+	assert.Equal(t, uint64(1), msg.Seq, "消息中的 seq 应该是分配的 seq")
+	assert.Equal(t, "chat", msg.Type)
+
+	m, err = s.Append(ctx, "1:phone", []byte(`{}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"seq":2}`, string(m.Payload))
+
+	_, err = s.Append(ctx, "1:phone", []byte("plain"))
+	assert.Error(t, err, "非 JSON 对象不能可靠投递")
+}
+
+func TestRedisStoreSinceAndAck(t *testing.T) {
+	_, s := newTestStore(t, 10)
+	ctx := context.Background()
+	appendN(t, s, "1:phone", 5)
+
+	msgs, complete, err := s.Since(ctx, "1:phone", 2)
+	require.NoError(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, []uint64{3, 4, 5}, seqs(msgs))
+
+	msgs, complete, err = s.Since(ctx, "1:phone", 5)
+	require.NoError(t, err)
+	assert.True(t, complete, "已确认到最新 seq 时没有需要补发的消息")
+	assert.Empty(t, msgs)
+
+	// 确认之后的消息才保留，之前的被删除
+	require.NoError(t, s.Ack(ctx, "1:phone", 3))
+	msgs, complete, err = s.Since(ctx, "1:phone", 3)
+	require.NoError(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, []uint64{4, 5}, seqs(msgs))
+	_, complete, err = s.Since(ctx, "1:phone", 1)
+	require.NoError(t, err)
+	assert.False(t, complete, "已确认删除的消息无法补全")
+}
+
+func TestRedisStoreSinceEvicted(t *testing.T) {
+	mr, s := newTestStore(t, 3)
+	ctx := context.Background()
+	appendN(t, s, "1:phone", 5)
+
+	msgs, complete, err := s.Since(ctx, "1:phone", 1)
+	require.NoError(t, err)
+	assert.False(t, complete, "超出容量被淘汰的消息无法补全")
+	assert.Equal(t, []uint64{3, 4, 5}, seqs(msgs))
+
+	msgs, complete, err = s.Since(ctx, "1:phone", 2)
+	require.NoError(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, []uint64{3, 4, 5}, seqs(msgs))
+
+	// 流过期后重建，客户端确认的 seq 比服务端新
+	mr.FastForward(2 * time.Hour)
+	msgs, complete, err = s.Since(ctx, "1:phone", 5)
+	require.NoError(t, err)
+	assert.False(t, complete, "流过期后应该要求客户端重新同步")
+	assert.Empty(t, msgs)
+	m, err := s.Append(ctx, "1:phone", []byte(`{"type":"chat"}`))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), m.Seq, "过期后 seq 重新开始")
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
+	"github.com/mxxmstar/learning/gate_server/internal/online"
+	"github.com/mxxmstar/learning/gate_server/internal/server/websocket"
+)
+
+// 连接列表的默认与最大返回数量
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// 未指定原因时的踢人原因
+const defaultAdminKickReason = "kicked by admin"
+
+// 测试消息的默认类型
+const defaultTestType = "admin_test"
+
+// AdminHandler 运维查看与控制本 gate 上的连接
+// 查询只包含本 gate 的连接，踢人与测试消息通过 Router 作用于所有 gate
+type AdminHandler struct {
+	gateId string
+	mgr    conn.ConnectionManager
+	router *delivery.Router
+}
+
+func NewAdminHandler(gateId string, mgr conn.ConnectionManager, router *delivery.Router) *AdminHandler {
+	return &AdminHandler{
+		gateId: gateId,
+		mgr:    mgr,
+		router: router,
+	}
+}
+
+// RegisterRoutes 注册管理接口，group 需要先经过 AdminAuth
+func (h *AdminHandler) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/stats", h.StatsHandler)
+	group.GET("/connections", h.ListConnectionsHandler)
+	group.GET("/connections/:id", h.ConnectionHandler)
+	group.POST("/connections/:id/kick", h.KickConnectionHandler)
+	group.POST("/users/:id/kick", h.KickUserHandler)
+	group.POST("/users/:id/send", h.SendTestHandler)
+}
+
+// AdminAuth 校验 Authorization: Bearer <token>
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		got, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			respondError(ctx, http.StatusUnauthorized, "unauthorized")
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ConnectionView 连接详情
+type ConnectionView struct {
+	online.ConnInfo
+	Topics []string             `json:"topics,omitempty"`
+	Stats  *websocket.ConnStats `json:"stats,omitempty"`
+}
+
+// StatsHandler 本 gate 的连接、用户与主题总数
+func (h *AdminHandler) StatsHandler(ctx *gin.Context) {
+	respond(ctx, gin.H{
+		"gate_id": h.gateId,
+		"totals":  h.mgr.Stats(),
+	})
+}
+
+// ListConnectionsHandler 列出本 gate 上的连接，可按 user_id 过滤，最多返回 limit 条
+func (h *AdminHandler) ListConnectionsHandler(ctx *gin.Context) {
+	limit := defaultListLimit
+	if v := ctx.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			respondError(ctx, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, maxListLimit)
+	}
+
+	var conns []conn.Connection
+	if v := ctx.Query("user_id"); v != "" {
+		userId, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		conns = h.mgr.GetConnectionsByUserId(userId)
+	} else {
+		h.mgr.Range(func(c conn.Connection) bool {
+			conns = append(conns, c)
+			return len(conns) < limit
+		})
+	}
+
+	infos := make([]online.ConnInfo, 0, min(len(conns), limit))
+	for _, c := range conns[:min(len(conns), limit)] {
+		infos = append(infos, online.InfoOf(c))
+	}
+	respond(ctx, gin.H{
+		"total": h.mgr.Stats().Connections,
+		"conns": infos,
+	})
+}
+
+// ConnectionHandler 本 gate 上单个连接的详情与运行统计
+func (h *AdminHandler) ConnectionHandler(ctx *gin.Context) {
+	c, err := h.mgr.GetConnection(ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, http.StatusNotFound, "connection not found on gate "+h.gateId)
+		return
+	}
+	view := ConnectionView{
+		ConnInfo: online.InfoOf(c),
+		Topics:   h.mgr.TopicsOf(c.Id()),
+	}
+	if s, isStats := c.(interface{ Stats() websocket.ConnStats }); isStats {
+		stats := s.Stats()
+		view.Stats = &stats
+	}
+	respond(ctx, view)
+}
+
+type kickRequest struct {
+	Reason string `json:"reason"`
+}
+
+// KickConnectionHandler 关闭连接，其他 gate 上的连接由所在 gate 关闭
+func (h *AdminHandler) KickConnectionHandler(ctx *gin.Context) {
+	var req kickRequest
+	if !bindOptional(ctx, &req) {
+		return
+	}
+	results := h.router.CloseConnections(ctx.Request.Context(), []string{ctx.Param("id")}, reasonOr(req.Reason))
+	respond(ctx, results)
+}
+
+// KickUserHandler 踢掉用户在所有 gate 上的连接，客户端会先收到 kicked 消息
+func (h *AdminHandler) KickUserHandler(ctx *gin.Context) {
+	userId, valid := userIdParam(ctx)
+	if !valid {
+		return
+	}
+	var req kickRequest
+	if !bindOptional(ctx, &req) {
+		return
+	}
+	results, err := h.router.KickUser(ctx.Request.Context(), userId, reasonOr(req.Reason))
+	if err != nil {
+		respondError(ctx, http.StatusServiceUnavailable, "query presence: "+err.Error())
+		return
+	}
+	respond(ctx, results)
+}
+
+type sendTestRequest struct {
+	Type string                 `json:"type"`
+	Body map[string]interface{} `json:"body"`
+}
+
+// SendTestHandler 向用户在所有 gate 上的连接发送测试消息，用于确认推送链路
+func (h *AdminHandler) SendTestHandler(ctx *gin.Context) {
+	userId, valid := userIdParam(ctx)
+	if !valid {
+		return
+	}
+	var req sendTestRequest
+	if !bindOptional(ctx, &req) {
+		return
+	}
+	if req.Type == "" {
+		req.Type = defaultTestType
+	}
+	payload, err := json.Marshal(websocket.Envelope{Type: req.Type, Body: req.Body})
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	results, err := h.router.DeliverToUsers(ctx.Request.Context(), []uint64{userId}, payload)
+	if err != nil {
+		respondError(ctx, http.StatusServiceUnavailable, "query presence: "+err.Error())
+		return
+	}
+	respond(ctx, results)
+}
+
+func userIdParam(ctx *gin.Context) (uint64, bool) {
+	userId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, "invalid user id")
+		return 0, false
+	}
+	return userId, true
+}
+
+// bindOptional 请求体可以为空
+func bindOptional(ctx *gin.Context, v interface{}) bool {
+	if err := json.NewDecoder(ctx.Request.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		respondError(ctx, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+	return true
+}
+
+func reasonOr(reason string) string {
+	if reason == "" {
+		return defaultAdminKickReason
+	}
+	return reason
+}
+
+func respond(ctx *gin.Context, data interface{}) {
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "message": "ok", "data": data})
+}
+
+func respondError(ctx *gin.Context, status int, message string) {
+	ctx.JSON(status, gin.H{"success": false, "message": message})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
+	"github.com/stretchr/testify/assert"
+)
+
+type adminResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func newAdminServer(t *testing.T) (*gin.Engine, conn.ConnectionManager) {
+	gin.SetMode(gin.TestMode)
+	mgr := conn.NewManager()
+	for _, c := range []*conntest.Conn{conntest.New("gate1#a", 1), conntest.New("gate1#b", 1), conntest.New("gate1#c", 2)} {
+		assert.NoError(t, mgr.Register(c))
+	}
+	engine := gin.New()
+	NewAdminHandler("gate1", mgr, delivery.NewRouter("gate1", mgr, nil, nil)).
+		RegisterRoutes(engine.Group("/admin", AdminAuth("secret")))
+	return engine, mgr
+}
+
+func doAdmin(engine *gin.Engine, method, path, body string) (int, adminResponse) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	var rsp adminResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &rsp)
+	return w.Code, rsp
+}
+
+func TestAdminAuth(t *testing.T) {
+	engine, _ := newAdminServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "token 错误时应该拒绝访问")
+}
+
+func TestAdminConnections(t *testing.T) {
+	engine, mgr := newAdminServer(t)
+
+	code, rsp := doAdmin(engine, http.MethodGet, "/admin/stats", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"gate_id":"gate1","totals":{"connections":3,"users":2,"topics":0}}`, string(rsp.Data))
+
+	code, rsp = doAdmin(engine, http.MethodGet, "/admin/connections?user_id=1", "")
+	assert.Equal(t, http.StatusOK, code)
+	var list struct {
+		Total int `json:"total"`
+		Conns []struct {
+			ConnId string `json:"conn_id"`
+		} `json:"conns"`
+	}
+	assert.NoError(t, json.Unmarshal(rsp.Data, &list))
+	assert.Equal(t, 3, list.Total)
+	assert.Len(t, list.Conns, 2, "应该按用户过滤连接")
+
+	code, _ = doAdmin(engine, http.MethodGet, "/admin/connections?limit=1", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = doAdmin(engine, http.MethodGet, "/admin/connections/gate1%23x", "")
+	assert.Equal(t, http.StatusNotFound, code, "不存在的连接应该返回 404")
+
+	code, _ = doAdmin(engine, http.MethodPost, "/admin/connections/gate1%23c/kick", `{"reason":"debug"}`)
+	assert.Equal(t, http.StatusOK, code)
+	c, _ := mgr.GetConnection("gate1#c")
+	assert.Equal(t, "debug", c.(*conntest.Conn).Closed(), "应该使用指定的原因关闭连接")
+
+	code, _ = doAdmin(engine, http.MethodPost, "/admin/users/abc/kick", "")
+	assert.Equal(t, http.StatusBadRequest, code, "非法的用户 Id 应该返回 400")
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"github.com/mxxmstar/learning/gate_server/gate_config"
 	auth_user "github.com/mxxmstar/learning/gate_server/internal/user_auth"
 )
 
@@ -9,7 +8,6 @@ type AuthHandler struct {
 	authService auth_user.AuthService
 }
 
-func NewAuthHandler(cfg *gate_config.Config) *AuthHandler {
-	var authService auth_user.AuthService
-
+func NewAuthHandler(authService auth_user.AuthService) *AuthHandler {
+	return &AuthHandler{authService: authService}
 }
@@ -0,0 +1,151 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	auth_user "github.com/mxxmstar/learning/gate_server/internal/user_auth"
+	"github.com/mxxmstar/learning/pkg/logger"
+)
+
+// 拒绝连接时指向负载较低 gate 的响应头
+const HeaderGateRedirect = "X-Gate-Redirect"
+
+// GateLocator 通过服务发现查找其他 gate，拒绝连接或排空时引导客户端
+type GateLocator interface {
+	LessLoadedGate(ctx context.Context) (string, error)
+	OtherGates(ctx context.Context) ([]string, error)
+}
+
+// admission 本 gate 的连接数上限，连接在升级前占用名额，关闭时释放
+type admission struct {
+	cfg     gate_config.AdmissionConfig
+	mu      sync.Mutex
+	total   int
+	perIP   map[string]int
+	perUser map[uint64]int
+}
+
+func newAdmission(cfg gate_config.AdmissionConfig) *admission {
+	return &admission{cfg: cfg, perIP: make(map[string]int), perUser: make(map[uint64]int)}
+}
+
+// acquire 为来源 IP 占用一个连接名额，超过上限时返回对应的 HTTP 状态码
+func (a *admission) acquire(ip string) (release func(), status int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cfg.MaxConns > 0 && a.total >= a.cfg.MaxConns {
+		return nil, http.StatusServiceUnavailable
+	}
+	if a.cfg.MaxConnsPerIP > 0 && a.perIP[ip] >= a.cfg.MaxConnsPerIP {
+		return nil, http.StatusTooManyRequests
+	}
+	a.total++
+	a.perIP[ip]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.total--
+			if a.perIP[ip]--; a.perIP[ip] <= 0 {
+				delete(a.perIP, ip)
+			}
+		})
+	}, http.StatusOK
+}
+
+// acquireUser 为用户占用一个连接名额，检查与占用在同一把锁内完成，并发认证的连接不会超过上限
+func (a *admission) acquireUser(userId uint64) (release func(), ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cfg.MaxConnsPerUser > 0 && a.perUser[userId] >= a.cfg.MaxConnsPerUser {
+		return nil, false
+	}
+	a.perUser[userId]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			if a.perUser[userId]--; a.perUser[userId] <= 0 {
+				delete(a.perUser, userId)
+			}
+		})
+	}, true
+}
+
+// admit 检查连接数上限，未配置上限时返回空的 release
+func (s *WebsocketServer) admit(remoteAddr string) (release func(), status int) {
+	if s.admission == nil {
+		return func() {}, http.StatusOK
+	}
+	return s.admission.acquire(remoteIP(remoteAddr))
+}
+
+// admitUser 认证后占用用户在本 gate 上的连接名额，连接关闭时释放
+func (s *WebsocketServer) admitUser(userId uint64) (release func(), ok bool) {
+	if s.admission == nil || s.admission.cfg.MaxConnsPerUser <= 0 {
+		return func() {}, true
+	}
+	return s.admission.acquireUser(userId)
+}
+
+// preAuth 升级前完成的认证与用户连接名额，握手时不再验证认证消息中的凭证
+type preAuth struct {
+	result  *auth_user.AuthResult
+	release func()
+}
+
+// preAuthenticate 升级请求通过 Authorization: Bearer <jwt> 携带凭证时在升级前认证，超过用户连接数上限时返回 429
+// 没有携带凭证时返回 nil，由握手时的认证消息认证
+func (s *WebsocketServer) preAuthenticate(r *http.Request) (*preAuth, int) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, http.StatusOK
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), authTimeout)
+	result, err := s.auth.ValidateTokenOrSession(ctx, token, "", r.URL.Query().Get("device_id"))
+	cancel()
+	if err != nil || result == nil || !result.Valid {
+		return nil, http.StatusUnauthorized
+	}
+	release, ok := s.admitUser(result.UserId)
+	if !ok {
+		return nil, http.StatusTooManyRequests
+	}
+	return &preAuth{result: result, release: release}, http.StatusOK
+}
+
+// reject 拒绝升级，响应头中携带负载较低的 gate
+func (s *WebsocketServer) reject(w http.ResponseWriter, r *http.Request, status int, reason string) {
+	if s.locator != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
+		addr, err := s.locator.LessLoadedGate(ctx)
+		cancel()
+		if err != nil {
+			logger.FormatLog(r.Context(), "warn", fmt.Sprintf("[ws] locate less loaded gate failed: %v", err))
+		}
+		if addr != "" {
+			w.Header().Set(HeaderGateRedirect, addr)
+		}
+	}
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, reason, status)
+}
+
+func remoteIP(remoteAddr string) string {
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	http_status_client "github.com/mxxmstar/learning/gate_server/internal/http/status"
+	"github.com/mxxmstar/learning/pkg/config"
+	status_def "github.com/mxxmstar/learning/pkg/def/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticLocator string
+
+func (l staticLocator) LessLoadedGate(ctx context.Context) (string, error) {
+	return string(l), nil
+}
+
+func (l staticLocator) OtherGates(ctx context.Context) ([]string, error) {
+	return []string{string(l)}, nil
+}
+
+func TestAdmission(t *testing.T) {
+	a := newAdmission(gate_config.AdmissionConfig{MaxConns: 3, MaxConnsPerIP: 2})
+
+	r1, status := a.acquire("1.1.1.1")
+	assert.Equal(t, http.StatusOK, status)
+	_, status = a.acquire("1.1.1.1")
+	assert.Equal(t, http.StatusOK, status)
+	_, status = a.acquire("1.1.1.1")
+	assert.Equal(t, http.StatusTooManyRequests, status, "超过单 IP 上限应该返回 429")
+
+	_, status = a.acquire("2.2.2.2")
+	assert.Equal(t, http.StatusOK, status)
+	_, status = a.acquire("3.3.3.3")
+	assert.Equal(t, http.StatusServiceUnavailable, status, "超过 gate 上限应该返回 503")
+
+	r1()
+	r1()
+	assert.Equal(t, 2, a.total, "重复释放只生效一次")
+	_, status = a.acquire("3.3.3.3")
+	assert.Equal(t, http.StatusOK, status, "释放后可以接入新连接")
+}
+
+func TestRejectRedirect(t *testing.T) {
+	s := NewWebsocketServer("gate1", conn.NewManager(), nil, nil, gate_config.LoginPolicyConfig{}, nil)
+	s.admission = newAdmission(gate_config.AdmissionConfig{MaxConns: 1})
+	s.locator = staticLocator("gate2:8080")
+
+	_, status := s.admit("1.1.1.1:5000")
+	assert.Equal(t, http.StatusOK, status)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "升级前应该拒绝超过上限的连接")
+	assert.Equal(t, "gate2:8080", rec.Header().Get(HeaderGateRedirect), "响应头应该指向负载较低的 gate")
+}
+
+func TestAdmissionUserConcurrent(t *testing.T) {
+	a := newAdmission(gate_config.AdmissionConfig{MaxConnsPerUser: 3})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var releases []func()
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if release, ok := a.acquireUser(1); ok {
+				mu.Lock()
+				releases = append(releases, release)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, releases, 3, "并发认证的连接不能超过用户上限")
+
+	releases[0]()
+	_, ok := a.acquireUser(1)
+	assert.True(t, ok, "释放后可以接入新连接")
+	_, ok = a.acquireUser(2)
+	assert.True(t, ok, "其他用户不受影响")
+}
+
+// newStatusServer 模拟 status_server 的服务发现接口，校验 gate 的请求参数
+func newStatusServer(t *testing.T, gate *status_def.ServiceInfo) gate_config.Config {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req status_def.ServiceDiscoveryByTagsRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, http_status_client.GateServiceType, req.ServiceName, "应该按 gate 的服务类型查询")
+		assert.Contains(t, req.Exclude, "gate1", "应该排除当前 gate")
+		_ = json.NewEncoder(w).Encode(&status_def.ServiceDiscoveryByTagsResponse{Code: 200, Services: gate})
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	cfg := gate_config.Config{ServerConfig: &config.ServerConfig{}}
+	cfg.ServerConfig.StatusServer.HttpConfig = config.StatusHttpServerConfig{Host: host, Port: p}
+	return cfg
+}
+
+func TestRejectRedirectFromStatusServer(t *testing.T) {
+	cfg := newStatusServer(t, &status_def.ServiceInfo{
+		ServiceType: http_status_client.GateServiceType,
+		ServiceId:   "gate2",
+		HTTPAddress: &status_def.HTTPAddress{Host: "10.0.0.2", Port: 8080},
+	})
+	client := http_status_client.NewStatusClient(cfg, &http.Client{Timeout: time.Second})
+
+	s := NewWebsocketServer("gate1", conn.NewManager(), nil, nil, gate_config.LoginPolicyConfig{}, nil)
+	s.admission = newAdmission(gate_config.AdmissionConfig{MaxConns: 1})
+	s.locator = http_status_client.NewGateLocator(client, "gate1", time.Minute)
+	_, status := s.admit("1.1.1.1:5000")
+	require.Equal(t, http.StatusOK, status)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "10.0.0.2:8080", rec.Header().Get(HeaderGateRedirect), "应该重定向到 status_server 返回的 gate")
+}
+
+func TestPreAuthUserLimit(t *testing.T) {
+	s := NewWebsocketServer("gate1", conn.NewManager(), tokenAuth{}, nil, gate_config.LoginPolicyConfig{}, nil)
+	s.admission = newAdmission(gate_config.AdmissionConfig{MaxConnsPerUser: 1})
+	_, ok := s.admitUser(7)
+	require.True(t, ok)
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "升级前认证的用户超过上限应该返回 429")
+
+	req.Header.Set("Authorization", "Bearer bad")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
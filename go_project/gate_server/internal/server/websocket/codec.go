@@ -0,0 +1,266 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	pb "github.com/mxxmstar/learning/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// 通过 Sec-WebSocket-Protocol 协商的子协议，客户端没有指定时使用 JSON
+const (
+	SubprotocolProto = "gate.proto.v1" // protobuf 二进制帧
+	SubprotocolJSON  = "gate.json.v1"  // JSON 文本帧
+)
+
+var (
+	protoJSONMarshal   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	protoJSONUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// Codec 连接使用的消息编解码
+type Codec interface {
+	Subprotocol() string
+	FrameType() int // websocket 帧类型
+	Decode(data []byte) (*Envelope, error)
+	Encode(e *Envelope) ([]byte, error)
+}
+
+// codecFor 根据协商的子协议选择编解码
+func codecFor(subprotocol string) Codec {
+	if subprotocol == SubprotocolProto {
+		return protoCodec{}
+	}
+	return jsonCodec{}
+}
+
+// sniffCodec 没有子协议协商的连接按第一条消息选择编码
+// JSON 消息以 '{' 开头，protobuf 消息第一个字节为 type 字段的 tag (0x0a)
+func sniffCodec(frame []byte) Codec {
+	if len(frame) > 0 && frame[0] == '{' {
+		return jsonCodec{}
+	}
+	return protoCodec{}
+}
+
+// DecodeBody 将消息体解码为 v，JSON 与 protobuf 客户端的消息体解码为相同的类型
+func (e *Envelope) DecodeBody(v proto.Message) error {
+	if len(e.rawBody) == 0 {
+		return nil
+	}
+	if e.binaryBody {
+		return proto.Unmarshal(e.rawBody, v)
+	}
+	return protoJSONUnmarshal.Unmarshal(e.rawBody, v)
+}
+
+// HandlerFunc 函数形式的消息处理器
+type HandlerFunc func(ctx context.Context, conn conn.Connection, envelope *Envelope) error
+
+func (f HandlerFunc) HandleMessage(ctx context.Context, conn conn.Connection, envelope *Envelope) error {
+	return f(ctx, conn, envelope)
+}
+
+// Typed 将消息体解码为 T 后调用 fn，消息体无法解码时回复 bad_request
+func Typed[T any, PT interface {
+	*T
+	proto.Message
+}](fn func(ctx context.Context, conn conn.Connection, envelope *Envelope, body PT) error) MessageHandler {
+	return HandlerFunc(func(ctx context.Context, conn conn.Connection, envelope *Envelope) error {
+		body := PT(new(T))
+		if err := envelope.DecodeBody(body); err != nil {
+			return NewError(ErrCodeBadRequest, "invalid %s body: %v", envelope.Type, err)
+		}
+		return fn(ctx, conn, envelope, body)
+	})
+}
+
+type envelopeAlias Envelope
+
+type jsonCodec struct{}
+
+func (jsonCodec) Subprotocol() string { return SubprotocolJSON }
+func (jsonCodec) FrameType() int      { return websocket.TextMessage }
+
+func (jsonCodec) Decode(data []byte) (*Envelope, error) {
+	e := &Envelope{}
+	aux := struct {
+		*envelopeAlias
+		Body json.RawMessage `json:"body,omitempty"`
+	}{envelopeAlias: (*envelopeAlias)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return nil, err
+	}
+	if len(aux.Body) > 0 && string(aux.Body) != "null" {
+		e.rawBody = aux.Body
+		if err := json.Unmarshal(aux.Body, &e.Body); err != nil {
+			return nil, fmt.Errorf("body must be a json object: %w", err)
+		}
+	}
+	return e, nil
+}
+
+func (jsonCodec) Encode(e *Envelope) ([]byte, error) {
+	if e.Message == nil {
+		return json.Marshal(e)
+	}
+	body, err := protoJSONMarshal.Marshal(e.Message)
+	if err != nil {
+		return nil, err
+	}
+	if e.flatJSON {
+		return flatten(e, body)
+	}
+	return json.Marshal(struct {
+		*envelopeAlias
+		Body json.RawMessage `json:"body,omitempty"`
+	}{(*envelopeAlias)(e), body})
+}
+
+// flatten 将消息体的字段合并到消息的顶层，与 Envelope 同名的字段以 Envelope 为准
+func flatten(e *Envelope, body []byte) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	head, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(head, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Subprotocol() string { return SubprotocolProto }
+func (protoCodec) FrameType() int      { return websocket.BinaryMessage }
+
+func (protoCodec) Decode(data []byte) (*Envelope, error) {
+	var pe pb.ClientEnvelope
+	if err := proto.Unmarshal(data, &pe); err != nil {
+		return nil, err
+	}
+	e := &Envelope{
+		Type:       pe.GetType(),
+		Id:         pe.GetId(),
+		Seq:        pe.GetSeq(),
+		Token:      pe.GetToken(),
+		SessionId:  pe.GetSessionId(),
+		DeviceId:   pe.GetDeviceId(),
+		DeviceType: pe.GetDeviceType(),
+		Resume:     pe.Resume,
+		Key:        pe.GetKey(),
+		rawBody:    pe.GetBody(),
+		binaryBody: true,
+	}
+	if pe.GetError() != nil {
+		e.Error = &ErrorBody{Code: pe.GetError().GetCode(), Message: pe.GetError().GetMessage()}
+	}
+	return e, nil
+}
+
+func (protoCodec) Encode(e *Envelope) ([]byte, error) {
+	pe := &pb.ClientEnvelope{
+		Type:       e.Type,
+		Id:         e.Id,
+		Seq:        e.Seq,
+		Token:      e.Token,
+		SessionId:  e.SessionId,
+		DeviceId:   e.DeviceId,
+		DeviceType: e.DeviceType,
+		Resume:     e.Resume,
+		Key:        e.Key,
+	}
+	if e.Error != nil {
+		pe.Error = &pb.ClientError{Code: e.Error.Code, Message: e.Error.Message}
+	}
+
+	var err error
+	switch {
+	case e.Message != nil:
+		pe.Body, err = proto.Marshal(e.Message)
+	case e.binaryBody:
+		pe.Body = e.rawBody
+	case e.Body != nil:
+		// 没有约定类型的消息体编码为 google.protobuf.Struct
+		var s *structpb.Struct
+		if s, err = structBody(e); err == nil {
+			pe.Body, err = proto.Marshal(s)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(pe)
+}
+
+// Struct 的数字是 float64，超过该值的整数会丢失精度
+const maxSafeInteger = 1 << 53
+
+// structBody 将没有约定类型的消息体转换为 Struct，超过 2^53 的整数编码为字符串，避免 id 等字段丢失精度
+func structBody(e *Envelope) (*structpb.Struct, error) {
+	if len(e.rawBody) == 0 {
+		return structpb.NewStruct(e.Body)
+	}
+	d := json.NewDecoder(bytes.NewReader(e.rawBody))
+	d.UseNumber()
+	var body map[string]interface{}
+	if err := d.Decode(&body); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(safeNumbers(body).(map[string]interface{}))
+}
+
+// safeNumbers 将 json.Number 转换为 float64，无法精确表示的整数转换为字符串
+func safeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = safeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = safeNumbers(item)
+		}
+	case json.Number:
+		if n, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			if n > maxSafeInteger || n < -maxSafeInteger {
+				return v.String()
+			}
+			return float64(n)
+		}
+		if _, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return v.String()
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// transcode 将集群内部使用的 JSON 消息转换为连接协商的编码，无法解析的消息原样返回
+func transcode(codec Codec, msg []byte) []byte {
+	if _, ok := codec.(protoCodec); !ok {
+		return msg
+	}
+	e, err := jsonCodec{}.Decode(msg)
+	if err != nil {
+		return msg
+	}
+	data, err := codec.Encode(e)
+	if err != nil {
+		return msg
+	}
+	return data
+}
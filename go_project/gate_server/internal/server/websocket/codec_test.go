@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+
+	pb "github.com/mxxmstar/learning/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDecodeBody(t *testing.T) {
+	want := &pb.SignupBody{Email: "a@b.c", Username: "alice", Password: "p", ConfirmPassword: "p"}
+
+	jsonEnv, err := jsonCodec{}.Decode([]byte(`{"type":"signup","id":"1","body":{"email":"a@b.c","username":"alice","password":"p","confirm_password":"p"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", jsonEnv.Body["username"], "JSON 消息仍然保留 Body")
+
+	body, err := proto.Marshal(want)
+	assert.NoError(t, err)
+	frame, err := proto.Marshal(&pb.ClientEnvelope{Type: "signup", Id: "1", Body: body})
+	assert.NoError(t, err)
+	protoEnv, err := protoCodec{}.Decode(frame)
+	assert.NoError(t, err)
+
+	for _, env := range []*Envelope{jsonEnv, protoEnv} {
+		var got pb.SignupBody
+		assert.NoError(t, env.DecodeBody(&got))
+		assert.True(t, proto.Equal(want, &got), "两种编码应该解码出相同的消息体")
+		assert.Equal(t, "1", env.Id)
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	msg := []byte(`{"type":"chat","seq":3,"body":{"text":"hi"}}`)
+	assert.Equal(t, msg, transcode(jsonCodec{}, msg), "JSON 连接不转换编码")
+
+	var pe pb.ClientEnvelope
+	assert.NoError(t, proto.Unmarshal(transcode(protoCodec{}, msg), &pe))
+	assert.Equal(t, "chat", pe.GetType())
+	assert.Equal(t, uint64(3), pe.GetSeq(), "seq 应该保留")
+
+	var body structpb.Struct
+	assert.NoError(t, proto.Unmarshal(pe.GetBody(), &body))
+	assert.Equal(t, "hi", body.AsMap()["text"], "没有约定类型的消息体应该编码为 Struct")
+}
+
+func TestTranscodeLargeInteger(t *testing.T) {
+	msg := []byte(`{"type":"chat","body":{"msg_id":9007199254740993,"user_id":18446744073709551615,"count":3,"ratio":0.5,"items":[{"id":9007199254740993}]}}`)
+
+	var pe pb.ClientEnvelope
+	assert.NoError(t, proto.Unmarshal(transcode(protoCodec{}, msg), &pe))
+	var body structpb.Struct
+	assert.NoError(t, proto.Unmarshal(pe.GetBody(), &body))
+	m := body.AsMap()
+	assert.Equal(t, "9007199254740993", m["msg_id"], "超过 2^53 的整数应该编码为字符串")
+	assert.Equal(t, "18446744073709551615", m["user_id"], "超过 int64 的整数应该编码为字符串")
+	assert.Equal(t, float64(3), m["count"])
+	assert.Equal(t, 0.5, m["ratio"])
+	assert.Equal(t, "9007199254740993", m["items"].([]interface{})[0].(map[string]interface{})["id"], "嵌套的整数也应该保留精度")
+}
+
+func TestFlatJSONMessage(t *testing.T) {
+	e := &Envelope{Type: "signup_response", Id: "1", Message: &pb.SignupResultBody{Success: true}, flatJSON: true}
+
+	data, err := jsonCodec{}.Encode(e)
+	assert.NoError(t, err)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, map[string]interface{}{"type": "signup_response", "id": "1", "success": true, "error": ""}, got, "JSON 客户端应该保持字段在顶层的旧格式")
+
+	data, err = protoCodec{}.Encode(e)
+	assert.NoError(t, err)
+	var pe pb.ClientEnvelope
+	assert.NoError(t, proto.Unmarshal(data, &pe))
+	var body pb.SignupResultBody
+	assert.NoError(t, proto.Unmarshal(pe.GetBody(), &body))
+	assert.True(t, body.GetSuccess(), "protobuf 客户端仍然使用有类型的消息体")
+}
@@ -0,0 +1,85 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mxxmstar/learning/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// 排空时关闭连接的原因
+const closeReasonDraining = "gate draining"
+
+// DrainOptions 排空参数
+type DrainOptions struct {
+	Window  time.Duration // 在该时间内均匀关闭所有连接
+	Targets []string      // 建议客户端重连的 gate 地址，按连接轮流分配，为空时通过服务发现查询
+}
+
+// ServeHTTP 接入 WebSocket 连接
+func (s *WebsocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handleNewConnectioon(w, r)
+}
+
+// Draining 是否处于排空模式
+func (s *WebsocketServer) Draining() bool {
+	return s.draining.Load()
+}
+
+// Drain 进入排空模式：拒绝新连接，通知所有连接重连到其他 gate，并在 Window 内逐个关闭
+// 每个连接收到的 reconnect 消息携带计划关闭的时间，客户端可以在此之前自行重连
+// ctx 取消时立即关闭剩余的连接
+func (s *WebsocketServer) Drain(ctx context.Context, opts DrainOptions) {
+	s.draining.Store(true)
+
+	conns := s.mgr.GetAllConnections()
+	logger.FormatLog(ctx, "info", "[ws] draining",
+		zap.Int("conns", len(conns)),
+		zap.Duration("window", opts.Window))
+	if len(conns) == 0 {
+		return
+	}
+
+	if len(opts.Targets) == 0 && s.locator != nil {
+		targets, err := s.locator.OtherGates(ctx)
+		if err != nil {
+			logger.FormatLog(ctx, "warn", fmt.Sprintf("[ws] discover drain targets: %v", err))
+		}
+		opts.Targets = targets
+	}
+
+	interval := opts.Window / time.Duration(len(conns))
+	for i, c := range conns {
+		body := map[string]interface{}{
+			"reason":      "draining",
+			"close_in_ms": (interval * time.Duration(i+1)).Milliseconds(),
+		}
+		if len(opts.Targets) > 0 {
+			body["gate"] = opts.Targets[i%len(opts.Targets)]
+		}
+		_ = Reply(c, nil, "reconnect", body)
+	}
+
+	start := time.Now()
+	for i, c := range conns {
+		if wait := time.Until(start.Add(interval * time.Duration(i+1))); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+		// 客户端已经自行重连的连接关闭时直接返回
+		if wc, ok := c.(*wsConnection); ok {
+			_ = wc.closeWith(websocket.CloseServiceRestart, closeReasonDraining)
+		} else {
+			_ = c.Close(closeReasonDraining)
+		}
+	}
+	logger.FormatLog(ctx, "info", fmt.Sprintf("[ws] drained %d connections in %s", len(conns), time.Since(start)))
+}
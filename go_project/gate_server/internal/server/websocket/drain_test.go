@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain(t *testing.T) {
+	mgr := conn.NewManager()
+	s := NewWebsocketServer("gate1", mgr, nil, nil, gate_config.LoginPolicyConfig{}, nil)
+
+	conns := []*conntest.Conn{conntest.New("gate1#a", 1), conntest.New("gate1#b", 1), conntest.New("gate1#c", 1)}
+	for _, c := range conns {
+		assert.NoError(t, mgr.Register(c))
+	}
+
+	start := time.Now()
+	s.Drain(context.Background(), DrainOptions{Window: 60 * time.Millisecond, Targets: []string{"gate2:8080", "gate3:8080"}})
+	assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond, "连接应该在排空窗口内逐步关闭")
+	assert.True(t, s.Draining())
+
+	gates := map[string]int{}
+	for _, c := range conns {
+		assert.Equal(t, closeReasonDraining, c.Closed(), "排空结束后所有连接都应该关闭")
+		var e Envelope
+		assert.NoError(t, json.Unmarshal(c.Sent()[0], &e))
+		assert.Equal(t, "reconnect", e.Type)
+		gates[e.Body["gate"].(string)]++
+	}
+	assert.Equal(t, map[string]int{"gate2:8080": 2, "gate3:8080": 1}, gates, "建议的 gate 应该轮流分配")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "排空中应该拒绝新连接")
+}
+
+func TestDrainTargetsFromLocator(t *testing.T) {
+	mgr := conn.NewManager()
+	s := NewWebsocketServer("gate1", mgr, nil, nil, gate_config.LoginPolicyConfig{}, nil)
+	s.locator = staticLocator("gate2:8080")
+
+	c := conntest.New("gate1#a", 1)
+	assert.NoError(t, mgr.Register(c))
+	s.Drain(context.Background(), DrainOptions{Window: 10 * time.Millisecond})
+
+	var e Envelope
+	assert.NoError(t, json.Unmarshal(c.Sent()[0], &e))
+	assert.Equal(t, "gate2:8080", e.Body["gate"], "未指定目标时应该通过服务发现查询其他 gate")
+}
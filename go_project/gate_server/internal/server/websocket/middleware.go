@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Middleware 包装 MessageHandler，在消息处理前后执行日志、鉴权、计时等通用逻辑
+type Middleware func(next MessageHandler) MessageHandler
+
+// Use 添加中间件，先添加的在外层，对所有消息类型生效，包括未注册的类型
+func (s *WebsocketServer) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+	var h MessageHandler = HandlerFunc(s.route)
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		h = s.middlewares[i](h)
+	}
+	s.dispatch = h
+}
+
+// route 按消息类型查找处理器
+func (s *WebsocketServer) route(ctx context.Context, c conn.Connection, envelope *Envelope) error {
+	handler, ok := s.handlers[envelope.Type]
+	if !ok {
+		return NewError(ErrCodeUnknownType, "unknown message type: %s", envelope.Type)
+	}
+	return handler.HandleMessage(ctx, c, envelope)
+}
+
+// Recover 捕获处理器的 panic，只影响当前消息，客户端收到 internal_error
+func Recover() Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return HandlerFunc(func(ctx context.Context, c conn.Connection, envelope *Envelope) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.FormatLog(ctx, "error", "[ws] handler panic",
+						zap.String("connId", c.Id()),
+						zap.String("type", envelope.Type),
+						zap.Any("panic", r),
+						zap.Stack("stack"))
+					err = fmt.Errorf("handler panic: %v", r)
+				}
+			}()
+			return next.HandleMessage(ctx, c, envelope)
+		})
+	}
+}
+
+// Trace 为每条消息生成 trace Id，处理器中的日志通过 ctx 关联到连接和消息
+func Trace() Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return HandlerFunc(func(ctx context.Context, c conn.Connection, envelope *Envelope) error {
+			return next.HandleMessage(logger.WithConnectionContext(ctx, c.Id()), c, envelope)
+		})
+	}
+}
+
+// Timeout 限制每条消息的处理时间，超时后处理器的 ctx 被取消，客户端收到 timeout 错误
+func Timeout(d time.Duration) Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return HandlerFunc(func(ctx context.Context, c conn.Connection, envelope *Envelope) error {
+			if d <= 0 {
+				return next.HandleMessage(ctx, c, envelope)
+			}
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			err := next.HandleMessage(ctx, c, envelope)
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return NewError(ErrCodeTimeout, "%s timed out after %s", envelope.Type, d)
+			}
+			return err
+		})
+	}
+}
+
+// Latency 记录每条消息的处理耗时
+func Latency() Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return HandlerFunc(func(ctx context.Context, c conn.Connection, envelope *Envelope) error {
+			start := time.Now()
+			err := next.HandleMessage(ctx, c, envelope)
+			logger.LogRouter(ctx, envelope.Type, time.Since(start))
+			return err
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/mxxmstar/learning/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	s := NewWebsocketServer("gate1", conn.NewManager(), nil, nil, gate_config.LoginPolicyConfig{}, nil)
+
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next MessageHandler) MessageHandler {
+			return HandlerFunc(func(ctx context.Context, c conn.Connection, e *Envelope) error {
+				order = append(order, name)
+				return next.HandleMessage(ctx, c, e)
+			})
+		}
+	}
+	s.Use(Recover(), mark("a"), Trace(), mark("b"), Timeout(20*time.Millisecond))
+	s.RegisterHandler("echo", HandlerFunc(func(ctx context.Context, c conn.Connection, e *Envelope) error {
+		assert.NotNil(t, logger.GetConnectionContext(ctx), "Trace 应该注入连接上下文")
+		return nil
+	}))
+	s.RegisterHandler("panic", HandlerFunc(func(ctx context.Context, c conn.Connection, e *Envelope) error {
+		panic("boom")
+	}))
+	s.RegisterHandler("slow", HandlerFunc(func(ctx context.Context, c conn.Connection, e *Envelope) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	c := conntest.New("gate1#a", 1)
+	assert.NoError(t, s.dispatch.HandleMessage(context.Background(), c, &Envelope{Type: "echo"}))
+	assert.Equal(t, []string{"a", "b"}, order, "中间件应该按添加顺序执行")
+
+	err := s.dispatch.HandleMessage(context.Background(), c, &Envelope{Type: "panic"})
+	assert.Error(t, err, "panic 应该转换为错误")
+
+	err = s.dispatch.HandleMessage(context.Background(), c, &Envelope{Type: "slow"})
+	assert.Equal(t, ErrCodeTimeout, err.(*Error).Code, "处理超时应该返回 timeout 错误")
+
+	err = s.dispatch.HandleMessage(context.Background(), c, &Envelope{Type: "missing"})
+	assert.Equal(t, ErrCodeUnknownType, err.(*Error).Code, "未注册的类型同样经过中间件")
+	assert.Len(t, order, 8)
+}
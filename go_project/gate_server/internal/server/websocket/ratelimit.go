@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/msglimit"
+	"github.com/mxxmstar/learning/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// 超过额度被断开时的原因
+const closeReasonRateLimited = "rate limited"
+
+// rateLimits 上行消息与建立连接的限流
+type rateLimits struct {
+	conn *msglimit.Limiter // 按连接 Id
+	user *msglimit.Limiter // 按用户 Id，只统计本 gate 上的连接
+	ip   *msglimit.Limiter // 按来源 IP，在升级前检查
+	cfg  gate_config.RateLimitConfig
+}
+
+func newRateLimits(cfg gate_config.RateLimitConfig) *rateLimits {
+	return &rateLimits{
+		conn: msglimit.NewLimiter(cfg.Conn),
+		user: msglimit.NewLimiter(cfg.User),
+		ip:   msglimit.NewLimiter(gate_config.RateLimitRules{Default: cfg.IP}),
+		cfg:  cfg,
+	}
+}
+
+// allowConnect 按来源 IP 限制建立连接的频率
+func (s *WebsocketServer) allowConnect(remoteAddr string) bool {
+	if s.limits == nil {
+		return true
+	}
+	ok, _ := s.limits.ip.Allow(remoteIP(remoteAddr), "")
+	return ok
+}
+
+// allowMessage 检查连接与用户的上行额度，超限时回复 rate_limited
+// ack 与 pong 是对服务端消息的响应，只计入连接的额度，不占用用户的额度
+// 窗口内多次超限的连接以 policy violation 断开，返回 false 时消息不再处理
+func (s *WebsocketServer) allowMessage(wsConn *wsConnection, envelope *Envelope) bool {
+	if s.limits == nil {
+		return true
+	}
+	scope := "connection"
+	ok, retryAfter := s.limits.conn.Allow(wsConn.connId, envelope.Type)
+	if ok && envelope.Type != "ack" && envelope.Type != "pong" {
+		scope = "user"
+		ok, retryAfter = s.limits.user.Allow(strconv.FormatUint(wsConn.userId, 10), envelope.Type)
+	}
+	if ok {
+		return true
+	}
+
+	if wsConn.violations == nil {
+		wsConn.violations = msglimit.NewViolations(s.limits.cfg.MaxViolations, s.limits.cfg.ViolationWindow)
+	}
+	if wsConn.violations.Add(time.Now()) {
+		logger.FormatLog(context.Background(), "warn", "[ws] disconnect abusive connection",
+			zap.String("connId", wsConn.connId),
+			zap.Uint64("userId", wsConn.userId),
+			zap.String("type", envelope.Type))
+		_ = wsConn.closeWith(websocket.ClosePolicyViolation, closeReasonRateLimited)
+		return false
+	}
+	_ = Reply(wsConn, envelope, "rate_limited", map[string]interface{}{
+		"type":           envelope.Type,
+		"scope":          scope,
+		"retry_after_ms": retryAfter.Milliseconds(),
+	})
+	return false
+}
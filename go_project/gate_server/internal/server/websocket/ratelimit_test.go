@@ -0,0 +1,26 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowMessageLimitsAck(t *testing.T) {
+	s := NewWebsocketServer("gate1", conn.NewManager(), nil, nil, gate_config.LoginPolicyConfig{}, nil)
+	s.limits = newRateLimits(gate_config.RateLimitConfig{
+		Conn: gate_config.RateLimitRules{Types: map[string]gate_config.RateLimit{"ack": {Rate: 1, Burst: 2}}},
+		User: gate_config.RateLimitRules{Default: gate_config.RateLimit{Rate: 1, Burst: 1}},
+	})
+	c := newQueueConn(conn.OverflowDropNewest)
+	c.codec = codecFor("")
+	c.userId = 1
+
+	ack := &Envelope{Type: "ack", Seq: 1}
+	assert.True(t, s.allowMessage(c, ack))
+	assert.True(t, s.allowMessage(c, ack), "ack 不占用用户的额度")
+	assert.False(t, s.allowMessage(c, ack), "ack 超过连接额度时应该限流")
+	assert.True(t, s.allowMessage(c, &Envelope{Type: "chat"}), "ack 与其他消息的额度互不影响")
+}
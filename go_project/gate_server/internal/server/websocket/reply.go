@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"google.golang.org/protobuf/proto"
+)
+
+// error 消息的错误码
+const (
+	ErrCodeInvalidMessage = "invalid_message" // 消息格式错误
+	ErrCodeUnknownType    = "unknown_type"    // 未知消息类型
+	ErrCodeBadRequest     = "bad_request"     // 请求参数错误
+	ErrCodeInternal       = "internal_error"  // 服务内部错误
+	ErrCodeTimeout        = "timeout"         // 处理超时
+	ErrCodeForbidden      = "forbidden"       // 没有权限
+	ErrCodeBusy           = "busy"            // 连接待处理的消息过多，稍后重试
+)
+
+// ErrorBody error 消息携带的错误信息
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error 处理器返回该错误时，错误码和错误信息会回复给客户端，其他错误只回复 internal_error
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func NewError(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Reply 回复请求，响应消息携带请求的 id，req 为 nil 时作为普通推送发送
+func Reply(c conn.Connection, req *Envelope, msgType string, body map[string]interface{}) error {
+	rsp := Envelope{Type: msgType, Body: body}
+	if req != nil {
+		rsp.Id = req.Id
+	}
+	return send(c, &rsp)
+}
+
+// ReplyError 回复 error 消息
+func ReplyError(c conn.Connection, req *Envelope, code, message string) error {
+	rsp := Envelope{
+		Type:  "error",
+		Error: &ErrorBody{Code: code, Message: message},
+	}
+	if req != nil {
+		rsp.Id = req.Id
+	}
+	return send(c, &rsp)
+}
+
+// replyHandlerError 将处理器返回的错误回复给客户端，非 *Error 的错误不暴露内部细节
+func replyHandlerError(c conn.Connection, req *Envelope, err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return ReplyError(c, req, e.Code, e.Message)
+	}
+	return ReplyError(c, req, ErrCodeInternal, "internal error")
+}
+
+// ReplyMessage 使用有类型的消息体回复请求，protobuf 客户端收到对应类型的消息体
+func ReplyMessage(c conn.Connection, req *Envelope, msgType string, body proto.Message) error {
+	rsp := Envelope{Type: msgType, Message: body}
+	if req != nil {
+		rsp.Id = req.Id
+	}
+	return send(c, &rsp)
+}
+
+// ReplyFlatMessage 与 ReplyMessage 相同，但 JSON 客户端收到的消息体字段在顶层而不是 body 中
+// 用于引入 body 之前就存在的消息，如 signup_response，保持已有 JSON 客户端的消息格式不变
+func ReplyFlatMessage(c conn.Connection, req *Envelope, msgType string, body proto.Message) error {
+	rsp := Envelope{Type: msgType, Message: body, flatJSON: true}
+	if req != nil {
+		rsp.Id = req.Id
+	}
+	return send(c, &rsp)
+}
+
+// send 优先按连接协商的编码发送，其他连接使用 JSON
+func send(c conn.Connection, envelope *Envelope) error {
+	if s, ok := c.(EnvelopeSender); ok {
+		return s.SendEnvelope(envelope)
+	}
+	data, err := jsonCodec{}.Encode(envelope)
+	if err != nil {
+		return err
+	}
+	return c.Send(data)
+}
@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/stretchr/testify/assert"
+)
+
+// lastEnvelope 连接最后收到的消息
+func lastEnvelope(t *testing.T, c *conntest.Conn) Envelope {
+	sent := c.Sent()
+	var e Envelope
+	assert.NoError(t, json.Unmarshal(sent[len(sent)-1], &e))
+	return e
+}
+
+func TestReply(t *testing.T) {
+	c := conntest.New("gate1#a", 1)
+	req := &Envelope{Type: "signup", Id: "req-1"}
+
+	assert.NoError(t, Reply(c, req, "signup_response", map[string]interface{}{"success": true}))
+	rsp := lastEnvelope(t, c)
+	assert.Equal(t, "req-1", rsp.Id, "响应应该携带请求 id")
+	assert.Equal(t, "signup_response", rsp.Type)
+
+	assert.NoError(t, replyHandlerError(c, req, NewError(ErrCodeBadRequest, "email is missing")))
+	rsp = lastEnvelope(t, c)
+	assert.Equal(t, "error", rsp.Type)
+	assert.Equal(t, "req-1", rsp.Id, "错误响应应该携带请求 id")
+	assert.Equal(t, &ErrorBody{Code: ErrCodeBadRequest, Message: "email is missing"}, rsp.Error, "业务错误应该原样回复")
+
+	assert.NoError(t, replyHandlerError(c, req, errors.New("dial tcp: connection refused")))
+	assert.Equal(t, &ErrorBody{Code: ErrCodeInternal, Message: "internal error"}, lastEnvelope(t, c).Error, "内部错误不应该暴露细节")
+}
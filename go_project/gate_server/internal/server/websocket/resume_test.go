@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/outbox"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// frameTransport 记录写出的帧
+type frameTransport struct {
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+func (t *frameTransport) ReadFrame() ([]byte, error) { return nil, net.ErrClosed }
+func (t *frameTransport) WriteFrame(data []byte, deadline time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.frames = append(t.frames, data)
+	return nil
+}
+func (t *frameTransport) Ping(deadline time.Time) error       { return nil }
+func (t *frameTransport) SetReadDeadline(d time.Time) error   { return nil }
+func (t *frameTransport) Close(code int, reason string) error { return nil }
+func (t *frameTransport) RemoteAddr() net.Addr                { return &net.TCPAddr{} }
+
+func (t *frameTransport) seqs() []uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []uint64
+	for _, f := range t.frames {
+		var e Envelope
+		if json.Unmarshal(f, &e) == nil {
+			out = append(out, e.Seq)
+		}
+	}
+	return out
+}
+
+func TestResumeReplaysBeforeNewMessages(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := outbox.NewRedisStore(redis.NewRedisClient(mr.Addr(), "", 0), gate_config.ReliableConfig{})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := store.Append(ctx, "1:phone", []byte(`{"type":"chat"}`))
+		require.NoError(t, err)
+	}
+
+	s := NewWebsocketServer("gate1", conn.NewManager(), nil, nil, gate_config.LoginPolicyConfig{}, nil)
+	tr := &frameTransport{}
+	c := newQueueConn(conn.OverflowDropNewest)
+	c.SendChan = make(chan []byte, 8)
+	c.t, c.mgr, c.codec = tr, s.mgr, codecFor("")
+	c.kickChan = make(chan string, 1)
+	c.outbox, c.stream = store, "1:phone"
+
+	c.reliableMu.Lock()
+	s.resume(c, 1)
+	c.reliableMu.Unlock()
+
+	// 补发期间的新推送不被阻塞，在补发的消息之后写出
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, c.SendReliable([]byte(`{"type":"chat"}`)))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("新的可靠推送不应该被补发阻塞")
+	}
+
+	go s.writePump(c)
+	defer c.Close("test")
+	assert.Eventually(t, func() bool { return len(tr.seqs()) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []uint64{2, 3, 4}, tr.seqs(), "补发的消息应该在新消息之前按 seq 顺序写出")
+}
@@ -2,7 +2,6 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/mxxmstar/learning/gate_server/internal/conn"
@@ -29,13 +28,5 @@ func (r *Router) Route(ctx context.Context, conn conn.Connection, envelope *Enve
 	}
 
 	// 发送未知消息类型响应到客户端
-	rsp := map[string]interface{}{
-		"type":    "unknown_message_type",
-		"message": fmt.Sprintf("unknown message type: %s", envelope.Type),
-	}
-	rspBytes, err := json.Marshal(rsp)
-	if err != nil {
-		return err
-	}
-	return conn.Send(rspBytes)
+	return ReplyError(conn, envelope, ErrCodeUnknownType, fmt.Sprintf("unknown message type: %s", envelope.Type))
 }
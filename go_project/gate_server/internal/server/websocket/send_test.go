@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/stretchr/testify/assert"
+)
+
+func newQueueConn(policy conn.OverflowPolicy) *wsConnection {
+	return &wsConnection{
+		connId:    "gate1#a",
+		SendChan:  make(chan []byte, 2),
+		closeChan: make(chan struct{}),
+		overflow:  policy,
+		sendWait:  10 * time.Millisecond,
+	}
+}
+
+func TestSendOverflow(t *testing.T) {
+	for _, policy := range []conn.OverflowPolicy{conn.OverflowDropNewest, conn.OverflowBlock} {
+		c := newQueueConn(policy)
+		assert.NoError(t, c.Send([]byte("1")))
+		assert.NoError(t, c.Send([]byte("2")))
+		assert.ErrorIs(t, c.Send([]byte("3")), conn.ErrSendQueueFull, "队列满时应该返回 ErrSendQueueFull: %s", policy)
+		assert.Equal(t, conn.QueueStats{Depth: 2, Capacity: 2, Dropped: 1}, c.QueueStats())
+	}
+
+	c := newQueueConn(conn.OverflowDropOldest)
+	assert.NoError(t, c.Send([]byte("1")))
+	assert.NoError(t, c.Send([]byte("2")))
+	assert.NoError(t, c.Send([]byte("3")), "drop_oldest 策略应该接收新消息")
+	assert.Equal(t, []byte("2"), <-c.SendChan, "最早的消息应该被丢弃")
+	assert.Equal(t, []byte("3"), <-c.SendChan)
+	assert.Equal(t, uint64(1), c.QueueStats().Dropped)
+
+	c.closed.Store(true)
+	assert.ErrorIs(t, c.Send([]byte("4")), conn.ErrConnectionClosed, "已关闭的连接应该返回 ErrConnectionClosed")
+}
+
+// closeTransport 只记录关闭，用于验证断开慢消费者
+type closeTransport struct {
+	mu     sync.Mutex
+	reason string
+	closed chan struct{}
+}
+
+func (t *closeTransport) ReadFrame() ([]byte, error)                       { return nil, net.ErrClosed }
+func (t *closeTransport) WriteFrame(data []byte, deadline time.Time) error { return nil }
+func (t *closeTransport) Ping(deadline time.Time) error                    { return nil }
+func (t *closeTransport) SetReadDeadline(d time.Time) error                { return nil }
+func (t *closeTransport) RemoteAddr() net.Addr                             { return &net.TCPAddr{} }
+func (t *closeTransport) Close(code int, reason string) error {
+	t.mu.Lock()
+	t.reason = reason
+	t.mu.Unlock()
+	close(t.closed)
+	return nil
+}
+
+func TestSendOverflowDisconnect(t *testing.T) {
+	mgr := conn.NewManager()
+	tr := &closeTransport{closed: make(chan struct{})}
+	c := newQueueConn(conn.OverflowDisconnect)
+	c.t, c.mgr = tr, mgr
+	assert.NoError(t, mgr.Register(c))
+
+	assert.NoError(t, c.Send([]byte("1")))
+	assert.NoError(t, c.Send([]byte("2")))
+	assert.ErrorIs(t, c.Send([]byte("3")), conn.ErrSendQueueFull, "队列满时应该返回 ErrSendQueueFull")
+
+	select {
+	case <-tr.closed:
+	case <-time.After(time.Second):
+		t.Fatal("disconnect 策略应该断开慢消费者")
+	}
+	tr.mu.Lock()
+	assert.Equal(t, "slow consumer", tr.reason)
+	tr.mu.Unlock()
+	assert.Equal(t, uint64(1), c.QueueStats().Dropped)
+	assert.Eventually(t, func() bool {
+		_, err := mgr.GetConnection(c.Id())
+		return err != nil
+	}, time.Second, 10*time.Millisecond, "断开的连接应该被注销")
+	assert.ErrorIs(t, c.Send([]byte("4")), conn.ErrConnectionClosed)
+}
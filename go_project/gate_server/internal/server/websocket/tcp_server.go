@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mxxmstar/learning/pkg/logger"
+)
+
+// ListenAndServeTCP 监听 TCP 端口接入原生客户端，addr 为空时使用配置的监听地址
+func (s *WebsocketServer) ListenAndServeTCP(addr string) error {
+	if addr == "" {
+		addr = s.tcpConfig.Addr
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen tcp %s: %w", addr, err)
+	}
+	return s.ServeTCP(ln)
+}
+
+// ServeTCP 在 ln 上接入 TCP 客户端，直到 ln 被关闭
+// 消息使用 4 字节大端长度前缀分帧，认证、心跳与消息路由与 WebSocket 连接相同
+// 没有子协议协商，按认证消息的第一个字节选择 JSON 或 protobuf 编码
+func (s *WebsocketServer) ServeTCP(ln net.Listener) error {
+	logger.FormatLog(context.Background(), "info", fmt.Sprintf("[tcp] listening on %s", ln.Addr()))
+
+	var backoff time.Duration
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			// 文件描述符耗尽等错误，退避后重试
+			if backoff == 0 {
+				backoff = 5 * time.Millisecond
+			} else if backoff *= 2; backoff > time.Second {
+				backoff = time.Second
+			}
+			logger.FormatLog(context.Background(), "warn", fmt.Sprintf("[tcp] accept error: %v, retrying in %s", err, backoff))
+			time.Sleep(backoff)
+			continue
+		}
+		backoff = 0
+		go s.handleTCPConnection(c)
+	}
+}
+
+func (s *WebsocketServer) handleTCPConnection(c net.Conn) {
+	// 排空中不再接入新连接，按来源 IP 限制建立连接的频率
+	if s.draining.Load() || !s.allowConnect(c.RemoteAddr().String()) {
+		_ = c.Close()
+		return
+	}
+	// 本 gate 与来源 IP 的连接数上限
+	release, status := s.admit(c.RemoteAddr().String())
+	if status != http.StatusOK {
+		_ = c.Close()
+		return
+	}
+	t := newTCPTransport(c, s.tcpConfig.MaxFrameSize)
+	s.handshake(context.Background(), t, func(first []byte) Codec {
+		codec := sniffCodec(first)
+		t.ping, _ = codec.Encode(&Envelope{Type: "ping"})
+		return codec
+	}, release, nil)
+}
@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	auth_user "github.com/mxxmstar/learning/gate_server/internal/user_auth"
+	"github.com/mxxmstar/learning/pkg/session"
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenAuth struct{}
+
+func (tokenAuth) ValidateTokenOrSession(ctx context.Context, token, sessionId, deviceId string) (*auth_user.AuthResult, error) {
+	if token != "good" {
+		return &auth_user.AuthResult{Error: "invalid token"}, nil
+	}
+	return &auth_user.AuthResult{UserId: 7, DeviceId: deviceId, Valid: true}, nil
+}
+
+func (tokenAuth) RefreshSession(ctx context.Context, sessionId string) (*auth_user.AuthResult, error) {
+	return nil, errors.New("not supported")
+}
+
+func dialTCP(t *testing.T, addr string, auth string) (*session.TCPConnection, Envelope) {
+	c, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	tc := session.NewTCPConnection(c)
+	_ = tc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	assert.NoError(t, tc.WriteMessage([]byte(auth)))
+	return tc, readTCP(t, tc)
+}
+
+func readTCP(t *testing.T, tc *session.TCPConnection) Envelope {
+	var e Envelope
+	msg, err := tc.ReadMessage()
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(msg, &e))
+	return e
+}
+
+func TestServeTCP(t *testing.T) {
+	mgr := conn.NewManager()
+	s := NewWebsocketServer("gate1", mgr, tokenAuth{}, nil, gate_config.LoginPolicyConfig{}, nil)
+	s.RegisterHandler("echo", HandlerFunc(func(ctx context.Context, c conn.Connection, e *Envelope) error {
+		return Reply(c, e, "echo_response", e.Body)
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	go s.ServeTCP(ln)
+
+	bad, nack := dialTCP(t, ln.Addr().String(), `{"type":"auth","token":"bad"}`)
+	assert.Equal(t, "auth_nack", nack.Type, "认证失败应该返回 auth_nack")
+	_, err = bad.ReadMessage()
+	assert.Error(t, err, "认证失败后应该关闭连接")
+
+	tc, ack := dialTCP(t, ln.Addr().String(), `{"type":"auth","token":"good","device_id":"d1"}`)
+	defer tc.Close()
+	assert.Equal(t, "auth_ack", ack.Type)
+	assert.Equal(t, SubprotocolJSON, ack.Body["protocol"], "JSON 认证消息应该选择 JSON 编码")
+	assert.Len(t, mgr.GetConnectionsByUserId(7), 1, "TCP 连接应该注册到连接管理器")
+
+	assert.NoError(t, tc.WriteMessage([]byte(`{"type":"echo","id":"e1","body":{"text":"hi"}}`)))
+	rsp := readTCP(t, tc)
+	assert.Equal(t, "echo_response", rsp.Type, "消息应该路由到注册的处理器")
+	assert.Equal(t, "e1", rsp.Id)
+	assert.Equal(t, "hi", rsp.Body["text"])
+
+	_ = tc.Close()
+	assert.Eventually(t, func() bool { return len(mgr.GetConnectionsByUserId(7)) == 0 },
+		time.Second, 10*time.Millisecond, "客户端断开后应该注销连接")
+}
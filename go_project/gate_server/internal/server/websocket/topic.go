@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
+	"github.com/mxxmstar/learning/pkg/logger"
+	pb "github.com/mxxmstar/learning/proto"
+)
+
+// TopicAuthorizer 判断连接能否订阅主题
+type TopicAuthorizer func(ctx context.Context, c conn.Connection, topic string) bool
+
+// UserTopicPrefix 用户私有主题的前缀，user:<用户 Id> 只有该用户的连接可以订阅
+const UserTopicPrefix = "user:"
+
+// NewTopicAuthorizer 已认证的连接可以订阅自己的用户主题以及 cfg.PublicPrefixes 下的主题，其他主题一律拒绝
+func NewTopicAuthorizer(cfg gate_config.TopicConfig) TopicAuthorizer {
+	prefixes := append([]string(nil), cfg.PublicPrefixes...)
+	return func(ctx context.Context, c conn.Connection, topic string) bool {
+		if c.UserId() == 0 {
+			return false
+		}
+		if topic == UserTopicPrefix+strconv.FormatUint(c.UserId(), 10) {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if prefix != "" && strings.HasPrefix(topic, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// TopicHandler 处理客户端的 subscribe 与 unsubscribe 消息
+// 主题消息由后端通过 Push.Publish 发布，客户端不能直接发布
+type TopicHandler struct {
+	mgr       conn.ConnectionManager
+	router    *delivery.Router // 查询所有 gate 上的订阅数，为 nil 时只统计本 gate
+	authorize TopicAuthorizer  // 为 nil 时拒绝所有订阅
+}
+
+func NewTopicHandler(mgr conn.ConnectionManager, router *delivery.Router, authorize TopicAuthorizer) *TopicHandler {
+	return &TopicHandler{
+		mgr:       mgr,
+		router:    router,
+		authorize: authorize,
+	}
+}
+
+func (h *TopicHandler) HandleMessage(ctx context.Context, c conn.Connection, envelope *Envelope) error {
+	switch envelope.Type {
+	case "subscribe":
+		return Typed(h.handleSubscribe).HandleMessage(ctx, c, envelope)
+	case "unsubscribe":
+		return Typed(h.handleUnsubscribe).HandleMessage(ctx, c, envelope)
+	default:
+		return NewError(ErrCodeUnknownType, "unknown message type: %s", envelope.Type)
+	}
+}
+
+func (h *TopicHandler) handleSubscribe(ctx context.Context, c conn.Connection, envelope *Envelope, body *pb.TopicBody) error {
+	topic := body.GetTopic()
+	if h.authorize == nil || !h.authorize(ctx, c, topic) {
+		return NewError(ErrCodeForbidden, "subscribe: topic %s is not allowed", topic)
+	}
+	if err := h.mgr.Subscribe(c.Id(), topic); err != nil {
+		return topicError("subscribe", err)
+	}
+	return ReplyMessage(c, envelope, "subscribed", h.result(ctx, topic))
+}
+
+func (h *TopicHandler) handleUnsubscribe(ctx context.Context, c conn.Connection, envelope *Envelope, body *pb.TopicBody) error {
+	topic := body.GetTopic()
+	if err := h.mgr.Unsubscribe(c.Id(), topic); err != nil {
+		return topicError("unsubscribe", err)
+	}
+	return ReplyMessage(c, envelope, "unsubscribed", h.result(ctx, topic))
+}
+
+// result 查询失败时退化为本 gate 的订阅数
+func (h *TopicHandler) result(ctx context.Context, topic string) *pb.TopicResultBody {
+	members := h.mgr.TopicMembers(topic)
+	if h.router != nil {
+		n, err := h.router.TopicMembers(ctx, topic)
+		if err == nil {
+			members = n
+		} else {
+			logger.FormatLog(ctx, "warn", fmt.Sprintf("[ws] query members of topic %s failed: %v", topic, err))
+		}
+	}
+	return &pb.TopicResultBody{Topic: topic, Members: int64(members)}
+}
+
+func topicError(op string, err error) error {
+	if errors.Is(err, conn.ErrInvalidTopic) || errors.Is(err, conn.ErrTooManyTopics) {
+		return NewError(ErrCodeBadRequest, "%s: %v", op, err)
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/conn/conntest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicAuthorizer(t *testing.T) {
+	authorize := NewTopicAuthorizer(gate_config.TopicConfig{PublicPrefixes: []string{"public:"}})
+	ctx := context.Background()
+	c := conntest.New("gate1#a", 1)
+
+	assert.True(t, authorize(ctx, c, "user:1"), "可以订阅自己的用户主题")
+	assert.False(t, authorize(ctx, c, "user:2"), "不能订阅其他用户的主题")
+	assert.True(t, authorize(ctx, c, "public:news"))
+	assert.False(t, authorize(ctx, c, "room:1"), "未配置的主题应该被拒绝")
+}
+
+func TestTopicHandlerDefaultDeny(t *testing.T) {
+	mgr := conn.NewManager()
+	c := conntest.New("gate1#a", 1)
+	require.NoError(t, mgr.Register(c))
+
+	h := NewTopicHandler(mgr, nil, nil)
+	err := h.HandleMessage(context.Background(), c, &Envelope{Type: "subscribe", Body: map[string]interface{}{"topic": "public:news"}})
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, ErrCodeForbidden, e.Code, "没有配置授权时应该拒绝订阅")
+	assert.Equal(t, 0, mgr.TopicMembers("public:news"))
+}
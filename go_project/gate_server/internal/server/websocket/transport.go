@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mxxmstar/learning/pkg/session"
+)
+
+// transport 连接底层的帧收发，WebSocket 与 TCP 连接共用认证、心跳、发送队列与消息路由
+type transport interface {
+	ReadFrame() ([]byte, error) // 读取一条完整消息，对端正常关闭时返回 io.EOF
+	WriteFrame(data []byte, deadline time.Time) error
+	Ping(deadline time.Time) error // 发送心跳
+	SetReadDeadline(t time.Time) error
+	Close(code int, reason string) error // code 为 WebSocket 关闭码，TCP 连接直接断开
+	RemoteAddr() net.Addr
+}
+
+// wsTransport 使用 WebSocket 帧，心跳为 ping/pong 控制帧，收到 pong 时延长读超时
+type wsTransport struct {
+	ws        *websocket.Conn
+	frameType int
+}
+
+func newWSTransport(ws *websocket.Conn, codec Codec) *wsTransport {
+	ws.SetReadLimit(16 * 1024)
+	ws.SetPongHandler(func(string) error {
+		_ = ws.SetReadDeadline(time.Now().Add(pongwait))
+		return nil
+	})
+	return &wsTransport{ws: ws, frameType: codec.FrameType()}
+}
+
+func (t *wsTransport) ReadFrame() ([]byte, error) {
+	_, msg, err := t.ws.ReadMessage()
+	if websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+		return nil, io.EOF
+	}
+	return msg, err
+}
+
+func (t *wsTransport) WriteFrame(data []byte, deadline time.Time) error {
+	_ = t.ws.SetWriteDeadline(deadline)
+	return t.ws.WriteMessage(t.frameType, data)
+}
+
+func (t *wsTransport) Ping(deadline time.Time) error {
+	_ = t.ws.SetWriteDeadline(deadline)
+	return t.ws.WriteMessage(websocket.PingMessage, nil)
+}
+
+func (t *wsTransport) SetReadDeadline(deadline time.Time) error {
+	return t.ws.SetReadDeadline(deadline)
+}
+
+func (t *wsTransport) Close(code int, reason string) error {
+	// 发送控制帧关闭连接
+	_ = t.ws.WriteControl(
+		websocket.CloseMessage,                     // 关闭消息
+		websocket.FormatCloseMessage(code, reason), // 格式化关闭消息体内容
+		time.Now().Add(time.Second),                // 添加超时时间
+	)
+	return t.ws.Close()
+}
+
+func (t *wsTransport) RemoteAddr() net.Addr {
+	return t.ws.RemoteAddr()
+}
+
+// tcpTransport 使用 4 字节长度前缀分帧，没有控制帧，心跳为 ping 消息，收到任意消息都延长读超时
+type tcpTransport struct {
+	conn *session.TCPConnection
+	ping []byte // 按连接编码的 ping 消息
+}
+
+func newTCPTransport(c net.Conn, maxFrameSize int) *tcpTransport {
+	conn := session.NewTCPConnection(c)
+	conn.MaxFrameSize = maxFrameSize
+	return &tcpTransport{conn: conn}
+}
+
+func (t *tcpTransport) ReadFrame() ([]byte, error) {
+	msg, err := t.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	_ = t.conn.SetReadDeadline(time.Now().Add(pongwait))
+	return msg, nil
+}
+
+func (t *tcpTransport) WriteFrame(data []byte, deadline time.Time) error {
+	_ = t.conn.SetWriteDeadline(deadline)
+	return t.conn.WriteMessage(data)
+}
+
+func (t *tcpTransport) Ping(deadline time.Time) error {
+	if t.ping == nil {
+		return nil
+	}
+	return t.WriteFrame(t.ping, deadline)
+}
+
+func (t *tcpTransport) SetReadDeadline(deadline time.Time) error {
+	return t.conn.SetReadDeadline(deadline)
+}
+
+func (t *tcpTransport) Close(code int, reason string) error {
+	return t.conn.Close()
+}
+
+func (t *tcpTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
@@ -0,0 +1,135 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mxxmstar/learning/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	errWorkerQueueFull  = errors.New("worker queue is full")
+	errWorkerPoolClosed = errors.New("worker pool is closed")
+)
+
+// workerTask 待处理的消息
+type workerTask struct {
+	ctx   context.Context
+	owner string // 提交消息的连接
+	run   func(ctx context.Context)
+}
+
+// workerPool 处理消息的有界工作池
+// 每个顺序键有独立的队列，相同键的消息按提交顺序处理，不同键的消息并行处理，同时处理的消息数不超过 workers
+// 每个连接排队与处理中的消息数不超过 queueSize，超过时 Submit 立即返回 errWorkerQueueFull，慢消息只影响所在的连接
+type workerPool struct {
+	sem       chan struct{}
+	queueSize int
+
+	mu      sync.Mutex
+	queues  map[string][]workerTask // 顺序键 -> 待处理的消息，存在即有协程在处理
+	pending map[string]int          // 连接 -> 排队与处理中的消息数
+	closed  bool
+
+	wg   sync.WaitGroup
+	once sync.Once
+	done chan struct{}
+}
+
+func newWorkerPool(workers, queueSize int) *workerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	return &workerPool{
+		sem:       make(chan struct{}, workers),
+		queueSize: queueSize,
+		queues:    make(map[string][]workerTask),
+		pending:   make(map[string]int),
+		done:      make(chan struct{}),
+	}
+}
+
+// Submit 按顺序键提交连接 owner 的消息，不会阻塞
+// 连接的消息数达到上限时返回 errWorkerQueueFull，工作池关闭后返回 errWorkerPoolClosed
+func (p *workerPool) Submit(ctx context.Context, owner, key string, run func(ctx context.Context)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errWorkerPoolClosed
+	}
+	if p.pending[owner] >= p.queueSize {
+		return errWorkerQueueFull
+	}
+	p.pending[owner]++
+
+	queue, running := p.queues[key]
+	p.queues[key] = append(queue, workerTask{ctx: ctx, owner: owner, run: run})
+	if !running {
+		p.wg.Add(1)
+		go p.work(key)
+	}
+	return nil
+}
+
+// work 依次处理顺序键的消息，队列为空时退出
+func (p *workerPool) work(key string) {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		queue := p.queues[key]
+		if len(queue) == 0 {
+			delete(p.queues, key)
+			p.mu.Unlock()
+			return
+		}
+		task := queue[0]
+		queue[0] = workerTask{}
+		p.queues[key] = queue[1:]
+		p.mu.Unlock()
+
+		select {
+		case p.sem <- struct{}{}:
+		case <-p.done:
+			return
+		}
+		p.run(task)
+		<-p.sem
+
+		p.mu.Lock()
+		if p.pending[task.owner]--; p.pending[task.owner] <= 0 {
+			delete(p.pending, task.owner)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// run 处理一条消息，panic 只影响当前消息
+func (p *workerPool) run(task workerTask) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FormatLog(task.ctx, "error", "[ws] worker panic",
+				zap.String("connId", task.owner),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+		}
+	}()
+	// 连接已关闭的消息不再处理
+	if task.ctx.Err() != nil {
+		return
+	}
+	task.run(task.ctx)
+}
+
+// Close 停止接收消息并等待正在处理的消息结束，未处理的消息被丢弃
+func (p *workerPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.once.Do(func() { close(p.done) })
+	p.wg.Wait()
+}
@@ -0,0 +1,102 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPoolOrdering(t *testing.T) {
+	p := newWorkerPool(4, 64)
+	defer p.Close()
+
+	var mu sync.Mutex
+	got := make(map[string][]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, key := range []string{"a", "b", "c"} {
+			key, i := key, i
+			wg.Add(1)
+			assert.NoError(t, p.Submit(context.Background(), key, key, func(ctx context.Context) {
+				defer wg.Done()
+				mu.Lock()
+				got[key] = append(got[key], i)
+				mu.Unlock()
+			}))
+		}
+	}
+	wg.Wait()
+	for key, seq := range got {
+		for i, v := range seq {
+			assert.Equal(t, i, v, "相同顺序键的消息应该按提交顺序处理: %s", key)
+		}
+	}
+}
+
+func TestWorkerPoolParallel(t *testing.T) {
+	p := newWorkerPool(16, 8)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	p.Submit(ctx, "conn1", "slow", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+	})
+	<-started
+
+	done := make(chan struct{})
+	p.Submit(context.Background(), "conn2", "other", func(ctx context.Context) { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("不同顺序键的消息不应该被慢消息阻塞")
+	}
+
+	cancel()
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("取消 ctx 后处理中的消息应该收到通知")
+	}
+}
+
+func TestWorkerPoolBackpressurePerConnection(t *testing.T) {
+	p := newWorkerPool(4, 2)
+	defer p.Close()
+
+	block := make(chan struct{})
+	defer close(block)
+	slow := func(ctx context.Context) { <-block }
+	assert.NoError(t, p.Submit(context.Background(), "conn1", "conn1", slow))
+	assert.NoError(t, p.Submit(context.Background(), "conn1", "conn1", slow))
+	assert.ErrorIs(t, p.Submit(context.Background(), "conn1", "conn1", slow), errWorkerQueueFull, "连接的消息数超过上限时应该立即返回")
+
+	// 其他连接不受影响，与慢连接相同的 worker 数下仍然可以处理
+	done := make(chan struct{})
+	assert.NoError(t, p.Submit(context.Background(), "conn2", "conn2", func(ctx context.Context) { close(done) }))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("慢连接不应该阻塞其他连接的消息")
+	}
+}
+
+func TestWorkerPoolRecover(t *testing.T) {
+	p := newWorkerPool(1, 8)
+	defer p.Close()
+
+	assert.NoError(t, p.Submit(context.Background(), "conn1", "conn1", func(ctx context.Context) { panic("boom") }))
+	done := make(chan struct{})
+	assert.NoError(t, p.Submit(context.Background(), "conn1", "conn1", func(ctx context.Context) { close(done) }))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("panic 之后的消息应该继续处理")
+	}
+}
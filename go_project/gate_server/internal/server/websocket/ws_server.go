@@ -2,16 +2,25 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/mxxmstar/learning/gate_server/gate_config"
 	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
 	grpc_auth_client "github.com/mxxmstar/learning/gate_server/internal/grpc/auth"
+	grpc_relay_client "github.com/mxxmstar/learning/gate_server/internal/grpc/relay"
 	http_auth_client "github.com/mxxmstar/learning/gate_server/internal/http/auth"
+	http_status_client "github.com/mxxmstar/learning/gate_server/internal/http/status"
+	"github.com/mxxmstar/learning/gate_server/internal/online"
+	"github.com/mxxmstar/learning/gate_server/internal/outbox"
 	auth_user "github.com/mxxmstar/learning/gate_server/internal/user_auth"
+	"github.com/mxxmstar/learning/pkg/logger"
+	"github.com/mxxmstar/learning/pkg/revocation"
+	pb "github.com/mxxmstar/learning/proto"
+	"go.uber.org/zap"
 )
 
 type AuthMessageHandler struct {
@@ -25,45 +34,46 @@ func NewAuthMessageHandler(authService auth_user.AuthService) *AuthMessageHandle
 func (h *AuthMessageHandler) HandleMessage(ctx context.Context, conn conn.Connection, envelope *Envelope) error {
 	switch envelope.Type {
 	case "signup":
-		return h.handleSignup(ctx, conn, envelope)
+		return Typed(h.handleSignup).HandleMessage(ctx, conn, envelope)
 	case "login":
 		// return h.handleLogin(ctx, conn, envelope)
 		return fmt.Errorf("login")
 	default:
-		return fmt.Errorf("unknown message type: %s", envelope.Type)
+		return NewError(ErrCodeUnknownType, "unknown message type: %s", envelope.Type)
 	}
 }
 
 // 处理用户注册，调用 grpc SignUp 方法
-func (h *AuthMessageHandler) handleSignup(ctx context.Context, conn conn.Connection, envelope *Envelope) error {
-	// 从 envelope 中获取用户注册信息
-	email, ok := envelope.Body["email"].(string)
-	if !ok {
-		return fmt.Errorf("signup: email is missing or invalid")
+func (h *AuthMessageHandler) handleSignup(ctx context.Context, conn conn.Connection, envelope *Envelope, body *pb.SignupBody) error {
+	email := body.GetEmail()
+	if email == "" {
+		return NewError(ErrCodeBadRequest, "signup: email is missing or invalid")
 	}
 
-	username, ok := envelope.Body["username"].(string)
-	if !ok {
-		return fmt.Errorf("signup: username is missing or invalid")
+	username := body.GetUsername()
+	if username == "" {
+		return NewError(ErrCodeBadRequest, "signup: username is missing or invalid")
 	}
 
-	password, ok := envelope.Body["password"].(string)
-	if !ok {
-		return fmt.Errorf("signup: password is missing or invalid")
+	password := body.GetPassword()
+	if password == "" {
+		return NewError(ErrCodeBadRequest, "signup: password is missing or invalid")
 	}
 
-	confirmPassword, ok := envelope.Body["confirm_password"].(string)
-	if !ok {
-		return fmt.Errorf("signup: confirm_password is missing or invalid")
+	confirmPassword := body.GetConfirmPassword()
+	if confirmPassword == "" {
+		return NewError(ErrCodeBadRequest, "signup: confirm_password is missing or invalid")
 	}
 
 	if password != confirmPassword {
-		return fmt.Errorf("signup: password and confirm_password do not match")
+		return NewError(ErrCodeBadRequest, "signup: password and confirm_password do not match")
 	}
 
-	authGRPC, ok := h.authService.(*auth_user.GRPCAuthService)
+	authGRPC, ok := h.authService.(interface {
+		AuthService() *grpc_auth_client.AuthClient
+	})
 	if !ok {
-		return fmt.Errorf("signup: authService is not a GRPCAuthService")
+		return fmt.Errorf("signup: authService does not support grpc")
 	}
 
 	signupRsp, err := authGRPC.AuthService().SignUp(ctx, username, email, password, confirmPassword)
@@ -71,16 +81,11 @@ func (h *AuthMessageHandler) handleSignup(ctx context.Context, conn conn.Connect
 		return fmt.Errorf("signup: %v", err)
 	}
 
-	response := map[string]interface{}{
-		"type":    "signup_response",
-		"success": signupRsp.Error == "",
-		"error":   signupRsp.Error,
-	}
-	responseBytes, err := json.Marshal(response)
-	if err != nil {
-		return fmt.Errorf("signup: %v", err)
-	}
-	return conn.Send(responseBytes)
+	// JSON 客户端保持 {"type":"signup_response","success":...,"error":...} 的格式
+	return ReplyFlatMessage(conn, envelope, "signup_response", &pb.SignupResultBody{
+		Success: signupRsp.Error == "",
+		Error:   signupRsp.Error,
+	})
 }
 
 func InitWebSocketServer(cfg *gate_config.Config) *WebsocketServer {
@@ -91,27 +96,127 @@ func InitWebSocketServer(cfg *gate_config.Config) *WebsocketServer {
 	}
 
 	// 初始化 HTTP 客户端
-	httpClient := http_auth_client.NewAuthClient(cfg.VerifyServer.Host+fmt.Sprintf(":%d", cfg.VerifyServer.Port), &http.Client{})
+	verifyServer, err := cfg.VerifyServer()
+	if err != nil {
+		log.Fatalf("Failed to select verify server: %v", err)
+	}
+	httpClient := http_auth_client.NewAuthClientWithAddress(fmt.Sprintf("%s:%d", verifyServer.HttpConfig.Host, verifyServer.HttpConfig.Port), cfg.ServerConfig.GlobalConfig.InternalToken, &http.Client{})
+
+	// 初始化 redis 与凭证吊销列表
+	redisClient, err := gate_config.InitRedis(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create redis client: %v", err)
+	}
+	revocations := revocation.NewStore(redisClient, 0)
 
 	// 创建认证服务
-	authService := auth_user.NewAuthService(auth_user.AuthUserGRPC, grpcClient, httpClient)
+	authService := auth_user.NewAuthService(auth_user.AuthUserType(cfg.AuthConfig.Mode), grpcClient, httpClient, revocations, cfg.AuthConfig)
+
+	// 跨 gate 共享的在线状态
+	onlineStore := online.NewRedisStore(redisClient, cfg.Presence)
 
-	// 创建连接管理器
-	connManager := conn.NewManager()
+	// 创建连接管理器，注册与注销连接时同步更新在线状态，主题的订阅数同步到 onlineStore 供其他 gate 转发
+	topicSyncer := online.NewTopicSyncer(onlineStore, cfg.GateServer.Name, onlineStore.TopicTTL()/3)
+	go topicSyncer.Run(context.Background())
+	connManager := online.TrackPresence(newConnManager(cfg.WebSocketConfig.ConnShards, conn.WithTopicObserver(topicSyncer.Observe)), onlineStore)
+
+	// 凭证被吊销时关闭受影响的连接
+	go watchRevocations(context.Background(), revocations, connManager, authService)
 
 	// 创建 WebSocket 服务器
 	wsServer := NewWebsocketServer(
 		cfg.GateServer.Name, // gate Id
 		connManager,
 		authService,
-		nil, // store (可以是 Redis 或其他存储)
-		nil, // notifyOld function
+		onlineStore,
+		cfg.LoginPolicy,
+		nil, // notifyOld 默认通过 onlineStore 通知旧连接所在的 gate
 	)
 
+	wsServer.wsConfig = cfg.WebSocketConfig
+	wsServer.tcpConfig = cfg.TCP
+
+	// 消息在工作池中处理，慢处理器不会阻塞连接的读取和心跳
+	if cfg.WebSocketConfig.Workers > 0 {
+		wsServer.workers = newWorkerPool(cfg.WebSocketConfig.Workers, cfg.WebSocketConfig.WorkerQueueSize)
+	}
+
+	// 通过在线状态找到用户所在的 gate，其他 gate 上的连接通过 gRPC 转发
+	wsServer.router = delivery.NewRouter(wsServer.gateId, connManager, onlineStore, grpc_relay_client.NewRelayClient(cfg))
+
+	// 推送消息分配 seq，客户端重连到任意 gate 后可以补发
+	if cfg.Reliable.Enabled {
+		wsServer.outbox = outbox.NewRedisStore(redisClient, cfg.Reliable)
+	}
+
+	// 其他 gate 的新登录要求踢掉本 gate 上的旧连接
+	go watchKicks(context.Background(), onlineStore, wsServer)
+
+	// 按连接、用户和来源 IP 限制上行消息与建立连接的频率
+	if cfg.RateLimit.Enabled {
+		wsServer.limits = newRateLimits(cfg.RateLimit)
+	}
+
+	// 连接数上限，拒绝连接时通过 status_server 引导客户端到负载较低的 gate
+	wsServer.admission = newAdmission(cfg.Admission)
+	statusClient := http_status_client.NewStatusClient(*cfg, &http.Client{Timeout: 5 * time.Second})
+	wsServer.locator = http_status_client.NewGateLocator(statusClient, wsServer.gateId, cfg.Admission.RedirectTTL)
+
+	// 处理器 panic 只影响当前消息，日志携带 trace Id 并记录耗时
+	wsServer.Use(Recover(), Trace(), Latency(), Timeout(cfg.WebSocketConfig.HandlerTimeout))
+
 	// 注册认证消息处理器
 	authHandler := NewAuthMessageHandler(authService)
 	wsServer.RegisterHandler("login", authHandler)
 	wsServer.RegisterHandler("signup", authHandler)
 
+	// 注册主题订阅消息处理器
+	topicHandler := NewTopicHandler(connManager, wsServer.router, NewTopicAuthorizer(cfg.Topic))
+	wsServer.RegisterHandler("subscribe", topicHandler)
+	wsServer.RegisterHandler("unsubscribe", topicHandler)
+
 	return wsServer
 }
+
+// newConnManager 分片数大于 1 时使用分片加锁的实现，避免大量连接注册与注销时竞争同一把锁
+func newConnManager(shards int, opts ...conn.Option) conn.ConnectionManager {
+	if shards > 1 {
+		return conn.NewShardedManager(shards, opts...)
+	}
+	return conn.NewManager(opts...)
+}
+
+// 订阅发往本 gate 的踢下线通知，订阅断开后自动重连
+func watchKicks(ctx context.Context, store online.Store, s *WebsocketServer) {
+	for {
+		err := store.SubscribeKick(ctx, s.gateId, func(connId, reason string) {
+			s.KickConnection(connId, reason)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("[ws] kick subscription lost: %v", err))
+		time.Sleep(time.Second)
+	}
+}
+
+// 订阅吊销事件，清空验证结果缓存并关闭受影响的连接，订阅断开后自动重连
+func watchRevocations(ctx context.Context, store *revocation.Store, mgr conn.ConnectionManager, authService auth_user.AuthService) {
+	for {
+		err := store.Subscribe(ctx, func(event revocation.Event) {
+			if cache, ok := authService.(interface{ Purge() }); ok {
+				cache.Purge()
+			}
+			closed := conn.CloseRevoked(mgr, event)
+			logger.FormatLog(ctx, "info", "[ws] credential revoked",
+				zap.String("type", string(event.Type)),
+				zap.Uint64("userId", event.UserId),
+				zap.Int("closed", closed))
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("[ws] revocation subscription lost: %v", err))
+		time.Sleep(time.Second)
+	}
+}
@@ -3,17 +3,25 @@ package websocket
 import (
 	"context"
 	"crypto/rand"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/mxxmstar/learning/gate_server/gate_config"
 	"github.com/mxxmstar/learning/gate_server/internal/conn"
+	"github.com/mxxmstar/learning/gate_server/internal/delivery"
+	"github.com/mxxmstar/learning/gate_server/internal/msglimit"
+	"github.com/mxxmstar/learning/gate_server/internal/online"
+	"github.com/mxxmstar/learning/gate_server/internal/outbox"
 	auth_user "github.com/mxxmstar/learning/gate_server/internal/user_auth"
 	"github.com/mxxmstar/learning/pkg/logger"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 // MessageHandler 定义消息处理器接口
@@ -21,25 +29,61 @@ type MessageHandler interface {
 	HandleMessage(ctx context.Context, conn conn.Connection, envelope *Envelope) error
 }
 
-// client 与 server 通信的消息格式
+// client 与 server 通信的消息格式，集群内部推送的消息统一使用 JSON 编码
 type Envelope struct {
-	Type      string                 `json:"type"`
-	Token     string                 `json:"token,omitempty"`
-	SessionId string                 `json:"session_id,omitempty"`
-	DeviceId  string                 `json:"device_id,omitempty"`
-	Body      map[string]interface{} `json:"body,omitempty"` // 消息体 方便扩展
+	Type       string                 `json:"type"`
+	Id         string                 `json:"id,omitempty"` // 请求 Id，由客户端生成，响应中原样返回
+	Token      string                 `json:"token,omitempty"`
+	SessionId  string                 `json:"session_id,omitempty"`
+	DeviceId   string                 `json:"device_id,omitempty"`
+	DeviceType string                 `json:"device_type,omitempty"` // 设备类型，如 mobile/desktop/web，用于多端登录策略
+	Seq        uint64                 `json:"seq,omitempty"`         // 服务端推送的消息序号，ack 消息中为客户端确认的序号
+	Resume     *uint64                `json:"resume,omitempty"`      // auth 消息中客户端最后确认的 seq，携带时补发之后的消息
+	Key        string                 `json:"key,omitempty"`         // 顺序键，相同键的消息按到达顺序处理，为空时按连接顺序处理
+	Body       map[string]interface{} `json:"body,omitempty"`        // 消息体 方便扩展
+	Error      *ErrorBody             `json:"error,omitempty"`       // type 为 error 时的错误信息
+	Message    proto.Message          `json:"-"`                     // 有类型的消息体，发送时优先于 Body
+
+	rawBody    []byte // 收到的原始消息体，用于 DecodeBody
+	binaryBody bool   // rawBody 是否为 protobuf 编码
+	flatJSON   bool   // JSON 编码时 Message 的字段放在顶层，兼容引入 body 之前的消息格式
+}
+
+// EnvelopeSender 可以按连接协商的编码直接发送 Envelope 的连接
+type EnvelopeSender interface {
+	SendEnvelope(e *Envelope) error
 }
 
-// wsConnection 实现 conn.Connection 接口
+// wsConnection 实现 conn.Connection 接口，WebSocket 与 TCP 连接共用
 type wsConnection struct {
 	connId    string
 	userId    uint64
 	deviceId  string
-	ws        *websocket.Conn
-	SendChan  chan []byte            // 发送消息的 channel
+	cred      conn.Credential        // 认证凭证，凭证被吊销时关闭连接
+	info      online.ConnInfo        // 在线状态信息
+	t         transport              // 底层的帧收发
+	SendChan  chan []byte            // 发送消息的 channel，消息已按 codec 编码
+	codec     Codec                  // 协商的消息编码
+	overflow  conn.OverflowPolicy    // 发送队列满时的策略
+	sendWait  time.Duration          // block 策略的最长等待时间
+	sent      atomic.Uint64          // 已写入连接的消息数
+	dropped   atomic.Uint64          // 因队列满被丢弃的消息数
+	received  atomic.Uint64          // 已读取的消息数
+	lastRead  atomic.Int64           // 最后读取消息的时间，unix 毫秒
 	closed    atomic.Bool            // 是否关闭
 	closeChan chan struct{}          // 关闭 channel
+	kickChan  chan string            // 踢下线通知，由 writePump 发送 kicked 消息后关闭连接
 	mgr       conn.ConnectionManager // 连接管理器
+	ctx       context.Context        // 消息处理器的 ctx，连接关闭时取消
+	cancel    context.CancelFunc
+	release   func() // 释放连接名额
+
+	stream     string       // 可靠投递的消息流，为空时不分配 seq
+	outbox     outbox.Store // 未确认消息的补发缓冲
+	reliableMu sync.Mutex   // 保证可靠消息按 seq 顺序进入发送队列
+	replay     [][]byte     // 重连时补发的消息，由 writePump 在发送队列之前写出
+
+	violations *msglimit.Violations // 超限次数，只在读协程中访问
 }
 
 func (c *wsConnection) Id() string {
@@ -48,7 +92,33 @@ func (c *wsConnection) Id() string {
 func (c *wsConnection) UserId() uint64 {
 	return c.userId
 }
+func (c *wsConnection) Credential() conn.Credential {
+	return c.cred
+}
+func (c *wsConnection) ConnInfo() online.ConnInfo {
+	return c.info
+}
+
+// Send 发送 JSON 编码的消息，protobuf 客户端会先转换编码
 func (c *wsConnection) Send(msg []byte) error {
+	return c.push(transcode(c.codec, msg))
+}
+
+// SendEnvelope 按协商的编码发送消息，有类型的消息体不会丢失类型
+func (c *wsConnection) SendEnvelope(e *Envelope) error {
+	codec := c.codec
+	if codec == nil {
+		codec = jsonCodec{}
+	}
+	data, err := codec.Encode(e)
+	if err != nil {
+		return err
+	}
+	return c.push(data)
+}
+
+// push 将编码后的消息写入发送队列
+func (c *wsConnection) push(msg []byte) error {
 	if c.closed.Load() {
 		return conn.ErrConnectionClosed
 	}
@@ -59,28 +129,159 @@ func (c *wsConnection) Send(msg []byte) error {
 	case <-c.closeChan:
 		return conn.ErrConnectionClosed
 	default:
-		return conn.ErrConnectionClosed
+		return c.sendOverflow(msg)
 	}
 }
 
+// sendOverflow 发送队列满时按溢出策略处理
+func (c *wsConnection) sendOverflow(msg []byte) error {
+	switch c.overflow {
+	case conn.OverflowBlock:
+		timer := time.NewTimer(c.sendWait)
+		defer timer.Stop()
+		select {
+		case c.SendChan <- msg:
+			return nil
+		case <-c.closeChan:
+			return conn.ErrConnectionClosed
+		case <-timer.C:
+			c.dropped.Add(1)
+			return fmt.Errorf("%w: blocked for %s", conn.ErrSendQueueFull, c.sendWait)
+		}
+	case conn.OverflowDropOldest:
+		// 与 writePump 竞争时可能需要多次腾出位置
+		for i := 0; i < 3; i++ {
+			select {
+			case <-c.SendChan:
+				c.dropped.Add(1)
+			default:
+			}
+			select {
+			case c.SendChan <- msg:
+				return nil
+			case <-c.closeChan:
+				return conn.ErrConnectionClosed
+			default:
+			}
+		}
+		c.dropped.Add(1)
+		return fmt.Errorf("%w: drop oldest failed", conn.ErrSendQueueFull)
+	case conn.OverflowDisconnect:
+		c.dropped.Add(1)
+		// 关闭连接会注销在线状态，不阻塞发送方
+		go c.Close("slow consumer")
+		return fmt.Errorf("%w: slow consumer disconnected", conn.ErrSendQueueFull)
+	default:
+		c.dropped.Add(1)
+		return fmt.Errorf("%w: message dropped", conn.ErrSendQueueFull)
+	}
+}
+
+// QueueStats 发送队列统计
+func (c *wsConnection) QueueStats() conn.QueueStats {
+	return conn.QueueStats{
+		Depth:    len(c.SendChan),
+		Capacity: cap(c.SendChan),
+		Sent:     c.sent.Load(),
+		Dropped:  c.dropped.Load(),
+	}
+}
+
+// ConnStats 连接的运行统计，供管理接口排查问题
+type ConnStats struct {
+	RemoteAddr  string          `json:"remote_addr"`
+	Subprotocol string          `json:"subprotocol"`  // 协商的消息编码
+	Reliable    bool            `json:"reliable"`     // 推送是否分配 seq
+	Received    uint64          `json:"received"`     // 已读取的消息数
+	LastReadAt  int64           `json:"last_read_at"` // 最后读取消息的时间，unix 毫秒
+	Queue       conn.QueueStats `json:"queue"`
+}
+
+// Stats 连接的运行统计
+func (c *wsConnection) Stats() ConnStats {
+	stats := ConnStats{
+		Reliable:   c.stream != "",
+		Received:   c.received.Load(),
+		LastReadAt: c.lastRead.Load(),
+		Queue:      c.QueueStats(),
+	}
+	if c.t != nil {
+		stats.RemoteAddr = c.t.RemoteAddr().String()
+	}
+	if c.codec != nil {
+		stats.Subprotocol = c.codec.Subprotocol()
+	}
+	return stats
+}
+
+// Close 正常关闭连接
 func (c *wsConnection) Close(reason string) error {
+	return c.closeWith(websocket.CloseNormalClosure, reason)
+}
+
+// closeWith 使用指定的 WebSocket 关闭码关闭连接
+func (c *wsConnection) closeWith(code int, reason string) error {
 	if !c.closed.CompareAndSwap(false, true) {
 		return nil
 	}
 	close(c.closeChan)
+	if c.cancel != nil {
+		c.cancel()
+	}
 	logger.FormatLog(context.Background(), "info", "wsConnection Close",
 		zap.String("connId", c.Id()),
 		zap.Uint64("userId", c.UserId()),
 		zap.String("reason", reason))
 
-	// 发送控制帧关闭连接
-	_ = c.ws.WriteControl(
-		websocket.CloseMessage, // 关闭消息
-		websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason), // 格式化关闭消息体内容(正常关闭1000)
-		time.Now().Add(time.Second),                                        // 添加超时时间
-	)
-	_ = c.ws.Close()
+	_ = c.t.Close(code, reason)
 	c.mgr.UnRegister(c)
+	if c.release != nil {
+		c.release()
+	}
+	return nil
+}
+
+// SendReliable 为消息分配 seq 并保存到客户端确认，连接断开后客户端可以通过 resume 补发
+func (c *wsConnection) SendReliable(msg []byte) error {
+	if c.outbox == nil || c.stream == "" {
+		return c.Send(msg)
+	}
+	c.reliableMu.Lock()
+	defer c.reliableMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	m, err := c.outbox.Append(ctx, c.stream, msg)
+	if err != nil {
+		// 非 JSON 对象或 Redis 不可用时退化为普通发送
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("[conn %s] append outbox failed: %v", c.connId, err))
+		return c.Send(msg)
+	}
+	return c.Send(m.Payload)
+}
+
+// ack 确认 seq 及之前的消息
+func (c *wsConnection) ack(seq uint64) {
+	if c.outbox == nil || c.stream == "" || seq == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.outbox.Ack(ctx, c.stream, seq); err != nil {
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("[conn %s] ack outbox failed: %v", c.connId, err))
+	}
+}
+
+// Kick 通知客户端被踢下线，由 writePump 发送 kicked 消息后关闭连接
+func (c *wsConnection) Kick(reason string) error {
+	if c.closed.Load() {
+		return conn.ErrConnectionClosed
+	}
+	select {
+	case c.kickChan <- reason:
+	default:
+		// 已经在踢下线流程中
+	}
 	return nil
 }
 
@@ -93,44 +294,102 @@ const (
 	writeBufferSize = 1024
 )
 
-// NotifyOldFunc 通知旧连接关闭
+// NotifyOldFunc 通知其他 gate 上的旧连接关闭
 type NotifyOldFunc func(ctx context.Context, oldConnId string)
 
+// 被新登录踢下线时的原因
+const kickReasonLoginElsewhere = "logged in elsewhere"
+
 type WebsocketServer struct {
-	gateId    string
-	mgr       conn.ConnectionManager
-	auth      auth_user.AuthService     // 验证服务
-	handlers  map[string]MessageHandler // 消息处理器映射
-	store     interface{}               // TODO:会话管理，连接状态存储，踢掉旧连接，用户在线状态管理，分布式存储
-	notifyOld NotifyOldFunc
-	upgrader  websocket.Upgrader
+	gateId      string
+	mgr         conn.ConnectionManager
+	auth        auth_user.AuthService         // 验证服务
+	handlers    map[string]MessageHandler     // 消息处理器映射
+	middlewares []Middleware                  // 按添加顺序包装消息处理器
+	dispatch    MessageHandler                // 经过中间件包装的消息分发
+	store       online.Store                  // 跨 gate 共享的在线状态，为 nil 时不执行多端登录策略
+	loginPolicy gate_config.LoginPolicyConfig // 多端登录策略
+	notifyOld   NotifyOldFunc
+	router      *delivery.Router // 集群范围的消息投递
+	outbox      outbox.Store     // 可靠投递的补发缓冲，为 nil 时不分配 seq
+	workers     *workerPool      // 消息处理工作池，为 nil 时在读协程中同步处理
+	limits      *rateLimits      // 上行限流，为 nil 时不限流
+	draining    atomic.Bool      // 排空中，不再接入新连接
+	admission   *admission       // 连接数上限，为 nil 时不限制
+	locator     GateLocator      // 拒绝连接时查找负载较低的 gate
+	wsConfig    gate_config.WebSocketConfig
+	tcpConfig   gate_config.TCPConfig
+	upgrader    websocket.Upgrader
 }
 
 func NewWebsocketServer(
 	gateId string,
 	mgr conn.ConnectionManager,
 	auth auth_user.AuthService,
-	store interface{},
+	store online.Store,
+	loginPolicy gate_config.LoginPolicyConfig,
 	notifyOld NotifyOldFunc,
 ) *WebsocketServer {
+	// 默认通过在线状态存储通知旧连接所在的 gate
+	if notifyOld == nil && store != nil {
+		notifyOld = func(ctx context.Context, oldConnId string) {
+			if err := store.Kick(ctx, oldConnId, kickReasonLoginElsewhere); err != nil {
+				logger.FormatLog(ctx, "error", fmt.Sprintf("[ws] notify old conn %s failed: %v", oldConnId, err))
+			}
+		}
+	}
 	s := &WebsocketServer{
-		gateId:    gateId,
-		mgr:       mgr,
-		auth:      auth,
-		handlers:  make(map[string]MessageHandler),
-		store:     store,
-		notifyOld: notifyOld,
+		gateId:      gateId,
+		mgr:         mgr,
+		auth:        auth,
+		handlers:    make(map[string]MessageHandler),
+		store:       store,
+		loginPolicy: loginPolicy,
+		notifyOld:   notifyOld,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  readBufferSize,
 			WriteBufferSize: writeBufferSize,
+			Subprotocols:    []string{SubprotocolProto, SubprotocolJSON},
 			CheckOrigin: func(r *http.Request) bool {
 				return true // TODO: 生产环境限制 Origin
 			},
 		},
 	}
+	s.dispatch = HandlerFunc(s.route)
 	return s
 }
 
+// GateId 返回本 gate 的 Id
+func (s *WebsocketServer) GateId() string {
+	return s.gateId
+}
+
+// ConnManager 返回本 gate 的连接管理器
+func (s *WebsocketServer) ConnManager() conn.ConnectionManager {
+	return s.mgr
+}
+
+// Router 返回集群范围的消息投递，未配置时为 nil
+func (s *WebsocketServer) Router() *delivery.Router {
+	return s.router
+}
+
+// 每个连接的发送队列长度
+func (s *WebsocketServer) sendQueueSize() int {
+	if s.wsConfig.SendQueueSize > 0 {
+		return s.wsConfig.SendQueueSize
+	}
+	return 256
+}
+
+// sendTimeout block 策略的最长等待时间，在创建连接时确定，发送时只读
+func (s *WebsocketServer) sendTimeout() time.Duration {
+	if s.wsConfig.SendTimeout > 0 {
+		return s.wsConfig.SendTimeout
+	}
+	return 100 * time.Millisecond
+}
+
 // 注册消息处理器,由消息处理器处理各种业务消息
 func (s *WebsocketServer) RegisterHandler(msgType string, handler MessageHandler) {
 	s.handlers[msgType] = handler
@@ -138,90 +397,298 @@ func (s *WebsocketServer) RegisterHandler(msgType string, handler MessageHandler
 
 // 处理新连接和初始认证
 func (s *WebsocketServer) handleNewConnectioon(w http.ResponseWriter, r *http.Request) {
+	// 排空中的 gate 不再接入新连接，由负载均衡分配到其他 gate
+	if s.draining.Load() {
+		s.reject(w, r, http.StatusServiceUnavailable, closeReasonDraining)
+		return
+	}
+
+	// 按来源 IP 限制建立连接的频率
+	if !s.allowConnect(r.RemoteAddr) {
+		s.reject(w, r, http.StatusTooManyRequests, "too many connections")
+		return
+	}
+
+	// 本 gate 与来源 IP 的连接数上限，升级前占用名额
+	release, status := s.admit(r.RemoteAddr)
+	if status != http.StatusOK {
+		s.reject(w, r, status, "too many connections")
+		return
+	}
+
+	// 携带凭证的升级请求在升级前认证并占用用户连接名额
+	pre, status := s.preAuthenticate(r)
+	if status == http.StatusUnauthorized {
+		release()
+		http.Error(w, "auth failed", status)
+		return
+	}
+	if status != http.StatusOK {
+		release()
+		s.reject(w, r, status, "too many connections")
+		return
+	}
+	if pre != nil {
+		release = chainRelease(pre.release, release)
+	}
+
 	// 升级为 websocket
 	ws, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		release()
 		logger.FormatLog(r.Context(), "error", fmt.Sprintf("[ws] upgrade failed: %v", err))
 		return
 	}
-	defer ws.Close()
+
+	// 按协商的子协议选择编码，没有协商时使用 JSON
+	codec := codecFor(ws.Subprotocol())
+	s.handshake(r.Context(), newWSTransport(ws, codec), func([]byte) Codec { return codec }, release, pre)
+}
+
+// chainRelease 依次调用多个 release
+func chainRelease(releases ...func()) func() {
+	return func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+}
+
+// handshake 读取并验证认证消息，成功后注册连接并启动读写协程，失败时关闭连接
+// negotiate 按第一条消息选择连接的编码，release 在连接关闭时释放连接名额
+// pre 不为 nil 时连接已在升级前认证，认证消息只用于携带设备类型与补发位置
+func (s *WebsocketServer) handshake(ctx context.Context, t transport, negotiate func(first []byte) Codec, release func(), pre *preAuth) {
+	established := false
+	defer func() {
+		if !established {
+			_ = t.Close(websocket.CloseNormalClosure, "handshake failed")
+			release()
+		}
+	}()
 
 	// 读取并处理认证消息
-	_ = ws.SetReadDeadline(time.Now().Add(authTimeout)) // 设置读超时
-	_, msg, err := ws.ReadMessage()
+	_ = t.SetReadDeadline(time.Now().Add(authTimeout)) // 设置读超时
+	msg, err := t.ReadFrame()
 	if err != nil {
-		logger.FormatLog(r.Context(), "error", fmt.Sprintf("[ws] read auth message failed: %v", err))
+		logger.FormatLog(ctx, "error", fmt.Sprintf("[ws] read auth message failed: %v", err))
 		return
 	}
 
-	var envelope Envelope
-	if err := json.Unmarshal(msg, &envelope); err != nil || envelope.Type != "auth" {
-		logger.FormatLog(r.Context(), "error", fmt.Sprintf("[ws] invalid auth message: %v", err))
-		_ = ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"auth_nack","reason":"invalid format"}`))
+	codec := negotiate(msg)
+	envelope, err := codec.Decode(msg)
+	if err != nil || envelope.Type != "auth" {
+		logger.FormatLog(ctx, "error", fmt.Sprintf("[ws] invalid auth message: %v", err))
+		writeAuthNack(t, codec, "invalid format")
 		return
 	}
 
-	// 执行认证
-	ctx, cancel := context.WithTimeout(r.Context(), authTimeout)
-	authResult, err := s.auth.ValidateTokenOrSession(ctx, envelope.Token, envelope.SessionId, envelope.DeviceId)
-	cancel()
-	if err != nil || !authResult.Valid {
-		logger.FormatLog(r.Context(), "error", fmt.Sprintf("[ws] auth failed: %v, error: %s", err, authResult.Error))
-		_ = ws.WriteMessage(websocket.TextMessage, []byte(`"type":"auth_nack","reason":"auth failed"}`))
+	// 执行认证，用户连接数超过上限时拒绝
+	var authResult *auth_user.AuthResult
+	if pre != nil {
+		authResult = pre.result
+	} else {
+		authCtx, cancel := context.WithTimeout(ctx, authTimeout)
+		authResult, err = s.auth.ValidateTokenOrSession(authCtx, envelope.Token, envelope.SessionId, envelope.DeviceId)
+		cancel()
+		if err != nil || !authResult.Valid {
+			reason := ""
+			if authResult != nil {
+				reason = authResult.Error
+			}
+			logger.FormatLog(ctx, "error", fmt.Sprintf("[ws] auth failed: %v, error: %s", err, reason))
+			writeAuthNack(t, codec, "auth failed")
+			return
+		}
+		userRelease, ok := s.admitUser(authResult.UserId)
+		if !ok {
+			writeAuthNack(t, codec, "too many connections")
+			return
+		}
+		release = chainRelease(userRelease, release)
+	}
+
+	// 认证期间进入排空模式的连接不再注册
+	if s.draining.Load() {
+		writeAuthNack(t, codec, closeReasonDraining)
 		return
 	}
 
 	// 创建连接对象并注册
 	// connId := logger.NewTraceId()
 	connId := fmt.Sprintf("%s#%s", s.gateId, s.randUUID())
+	connCtx, connCancel := context.WithCancel(context.Background())
 	wsConn := &wsConnection{
-		connId:    connId,
-		userId:    authResult.UserId,
-		deviceId:  authResult.DeviceId,
-		ws:        ws,
-		SendChan:  make(chan []byte, 256),
+		connId:   connId,
+		userId:   authResult.UserId,
+		deviceId: authResult.DeviceId,
+		cred: conn.Credential{
+			TokenId:   authResult.TokenId,
+			SessionId: authResult.SessionId,
+			IssuedAt:  authResult.IssuedAt,
+		},
+		info: online.ConnInfo{
+			ConnId:     connId,
+			UserId:     authResult.UserId,
+			GateId:     s.gateId,
+			DeviceId:   authResult.DeviceId,
+			DeviceType: envelope.DeviceType,
+			LoginAt:    time.Now().UnixMilli(),
+		},
+		t:         t,
+		SendChan:  make(chan []byte, s.sendQueueSize()),
+		codec:     codec,
+		overflow:  conn.OverflowPolicy(s.wsConfig.OverflowPolicy),
+		sendWait:  s.sendTimeout(),
 		closeChan: make(chan struct{}),
+		kickChan:  make(chan string, 1),
 		mgr:       s.mgr,
+		ctx:       connCtx,
+		cancel:    connCancel,
+		release:   release,
+	}
+	if s.outbox != nil {
+		wsConn.stream = outbox.StreamId(authResult.UserId, authResult.DeviceId)
+		wsConn.outbox = s.outbox
 	}
 
+	// 读取补发的消息前阻塞新的可靠推送，之后的推送在补发的消息之后写出，保证消息按 seq 顺序到达
+	wsConn.reliableMu.Lock()
 	if err := s.mgr.Register(wsConn); err != nil {
-		logger.FormatLog(r.Context(), "error", fmt.Sprintf("[ws] register failed: %v", err))
-		_ = ws.WriteMessage(websocket.TextMessage, []byte(`"type":"auth_nack","reason":"register ws connection failed"}`))
+		wsConn.reliableMu.Unlock()
+		logger.FormatLog(ctx, "error", fmt.Sprintf("[ws] register failed: %v", err))
+		connCancel()
+		writeAuthNack(t, codec, "register ws connection failed")
 		return
 	}
 
-	// 发送认证成功响应 TODO
-	ack := map[string]interface{}{
-		"type":        "auth_ack",
-		"conn_id":     connId,
-		"session_ttl": sessionTTl,
-		"server_time": time.Now().Unix(),
-		// "body": map[string]interface{}{
-		// 	"conn_id": connId,
-		// },
-	}
-	ackBytes, _ := json.Marshal(ack)
-	if err := ws.WriteMessage(websocket.TextMessage, ackBytes); err != nil {
-		logger.FormatLog(r.Context(), "error", fmt.Sprintf("[ws] write auth ack failed: %v", err))
+	// 发送认证成功响应
+	ack, _ := codec.Encode(&Envelope{
+		Type: "auth_ack",
+		Body: map[string]interface{}{
+			"conn_id":     connId,
+			"session_ttl": sessionTTl,
+			"server_time": time.Now().Unix(),
+			"protocol":    codec.Subprotocol(),
+		},
+	})
+	if err := t.WriteFrame(ack, time.Now().Add(5*time.Second)); err != nil {
+		wsConn.reliableMu.Unlock()
+		logger.FormatLog(ctx, "error", fmt.Sprintf("[ws] write auth ack failed: %v", err))
 		wsConn.Close("auth ack failed")
 		return
 	}
+	established = true
+
+	// 读取断线期间未确认的消息，写出时不持有 reliableMu，不阻塞新的推送
+	if envelope.Resume != nil {
+		s.resume(wsConn, *envelope.Resume)
+	}
+	wsConn.reliableMu.Unlock()
 
 	// 启动消息处理协程
 	go s.readPump(wsConn)
 	go s.writePump(wsConn)
+
+	// 按多端登录策略踢掉旧连接
+	s.enforceLoginPolicy(context.Background(), wsConn)
+}
+
+// enforceLoginPolicy 按新连接设备类型对应的策略踢掉冲突的旧连接
+// 新连接在注册时已写入在线状态，并发登录的双方都能查询到对方
+func (s *WebsocketServer) enforceLoginPolicy(ctx context.Context, wsConn *wsConnection) {
+	if s.store == nil {
+		return
+	}
+	presence, err := s.store.Query(ctx, wsConn.userId)
+	if err != nil {
+		logger.FormatLog(ctx, "error", fmt.Sprintf("[ws] query online conns failed: %v", err))
+		return
+	}
+
+	policy := online.PolicyFor(s.loginPolicy, wsConn.info.DeviceType)
+	for _, old := range online.Conflicts(policy, wsConn.info, presence.Conns) {
+		logger.FormatLog(ctx, "info", "[ws] kick old connection",
+			zap.String("connId", old.ConnId),
+			zap.String("newConnId", wsConn.connId),
+			zap.Uint64("userId", old.UserId),
+			zap.String("policy", string(policy)))
+
+		if old.GateId == s.gateId {
+			s.KickConnection(old.ConnId, kickReasonLoginElsewhere)
+			continue
+		}
+		if s.notifyOld != nil {
+			s.notifyOld(ctx, old.ConnId)
+		}
+	}
+}
+
+// resume 读取客户端最后确认的 seq 之后的消息放入 wsConn.replay，调用方需持有 reliableMu 且 writePump 还未启动
+// 部分消息已被淘汰时先发送 resync，客户端需要重新拉取完整状态
+func (s *WebsocketServer) resume(wsConn *wsConnection, lastSeq uint64) {
+	resync, _ := wsConn.codec.Encode(&Envelope{Type: "resync", Body: map[string]interface{}{"last_seq": lastSeq}})
+	if wsConn.outbox == nil || wsConn.stream == "" {
+		wsConn.replay = append(wsConn.replay, resync)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), authTimeout)
+	defer cancel()
+
+	wsConn.ack(lastSeq)
+	msgs, complete, err := wsConn.outbox.Since(ctx, wsConn.stream, lastSeq)
+	if err != nil {
+		logger.FormatLog(ctx, "error", fmt.Sprintf("[conn %s] resume failed: %v", wsConn.connId, err))
+	}
+	if err != nil || !complete {
+		wsConn.replay = append(wsConn.replay, resync)
+	}
+	for _, m := range msgs {
+		wsConn.replay = append(wsConn.replay, transcode(wsConn.codec, m.Payload))
+	}
+	logger.FormatLog(ctx, "info", "[ws] resumed",
+		zap.String("connId", wsConn.connId),
+		zap.Uint64("lastSeq", lastSeq),
+		zap.Int("replayed", len(msgs)))
+}
+
+// Close 关闭本 gate 上的所有连接并停止工作池，正在处理的消息的 ctx 被取消
+func (s *WebsocketServer) Close() {
+	s.mgr.Range(func(c conn.Connection) bool {
+		_ = c.Close("server shutdown")
+		return true
+	})
+	if s.workers != nil {
+		s.workers.Close()
+	}
+}
+
+// KickConnection 踢掉本 gate 上的连接，连接不存在时忽略
+func (s *WebsocketServer) KickConnection(connId, reason string) {
+	c, err := s.mgr.GetConnection(connId)
+	if err != nil {
+		return
+	}
+	_ = conn.Kick(c, reason)
+}
+
+// touchPresence 心跳时续期在线状态
+func (s *WebsocketServer) touchPresence(wsConn *wsConnection) {
+	if s.store == nil || wsConn.closed.Load() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.store.Touch(ctx, wsConn.info); err != nil {
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("[conn %s] touch presence failed: %v", wsConn.connId, err))
+	}
 }
 
 func (s *WebsocketServer) readPump(wsConn *wsConnection) {
 	defer wsConn.Close("read pump exit")
-	ws := wsConn.ws
-	ws.SetReadLimit(16 * 1024)
-	_ = ws.SetReadDeadline(time.Now().Add(pongwait))
-	// 设置心跳处理器
-	ws.SetPongHandler(func(string) error {
-		_ = ws.SetReadDeadline(time.Now().Add(pongwait))
-		return nil
-	})
+	if s.limits != nil {
+		defer s.limits.conn.Forget(wsConn.connId)
+	}
+	_ = wsConn.t.SetReadDeadline(time.Now().Add(pongwait))
 
 	for {
 		select {
@@ -232,38 +699,74 @@ func (s *WebsocketServer) readPump(wsConn *wsConnection) {
 		}
 
 		// 读取消息
-		_, msg, err := ws.ReadMessage()
+		msg, err := wsConn.t.ReadFrame()
 		if err != nil {
-			if !websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if !errors.Is(err, io.EOF) {
 				// 非正常错误，记录日志
 				logger.FormatLog(context.Background(), "error", fmt.Sprintf("[conn %s] read error: %v", wsConn.connId, err))
 			}
 			// 遇到错误，退出主循环关闭连接
 			return
 		}
+		wsConn.received.Add(1)
+		wsConn.lastRead.Store(time.Now().UnixMilli())
 
 		// 解析消息并路由到相应的处理器
-		var envelope Envelope
-		if err := json.Unmarshal(msg, &envelope); err != nil {
+		envelope, err := wsConn.codec.Decode(msg)
+		if err != nil {
 			logger.FormatLog(context.Background(), "error", fmt.Sprintf("[conn %s] unmarshal message error: %v", wsConn.connId, err))
+			_ = ReplyError(wsConn, nil, ErrCodeInvalidMessage, "invalid message format")
+			continue
+		}
+
+		// 超过上行额度的消息不处理
+		if !s.allowMessage(wsConn, envelope) {
+			if wsConn.closed.Load() {
+				return
+			}
 			continue
 		}
 
 		// 特殊处理 ping 消息
 		if envelope.Type == "ping" {
-			_ = wsConn.Send([]byte(`{"type":"pong"}`))
+			_ = Reply(wsConn, envelope, "pong", nil)
+			continue
+		}
+
+		// 客户端对 ping 心跳消息的响应，TCP 连接收到任意消息都会延长读超时
+		if envelope.Type == "pong" {
+			continue
+		}
+
+		// 客户端确认收到的可靠消息
+		if envelope.Type == "ack" {
+			wsConn.ack(envelope.Seq)
 			continue
 		}
 
-		// 查找并执行对应的消息处理器
-		if handler, exists := s.handlers[envelope.Type]; exists {
-			ctx := context.WithValue(context.Background(), "conn", wsConn)
-			if err := handler.HandleMessage(ctx, wsConn, &envelope); err != nil {
-				logger.FormatLog(context.Background(), "error", fmt.Sprintf("[conn %s] handle message error: %v", wsConn.connId, err))
+		// 经过中间件后交给对应的消息处理器，未知消息类型返回 unknown_type
+		ctx := context.WithValue(wsConn.ctx, "conn", wsConn)
+		handle := func(ctx context.Context) {
+			if err := s.dispatch.HandleMessage(ctx, wsConn, envelope); err != nil {
+				logger.FormatLog(context.Background(), "error", fmt.Sprintf("[conn %s] handle %s message error: %v", wsConn.connId, envelope.Type, err))
+				_ = replyHandlerError(wsConn, envelope, err)
+			}
+		}
+		if s.workers == nil {
+			handle(ctx)
+			continue
+		}
+		// 相同顺序键的消息按到达顺序处理，没有顺序键时按连接顺序处理
+		key := wsConn.connId
+		if envelope.Key != "" {
+			key += "/" + envelope.Key
+		}
+		// 队列满时只拒绝当前消息，继续读取，不影响心跳与其他连接
+		if err := s.workers.Submit(ctx, wsConn.connId, key, handle); err != nil {
+			if errors.Is(err, errWorkerPoolClosed) {
+				return
 			}
-		} else {
-			// 未知消息类型，可以选择忽略或记录日志
-			logger.FormatLog(context.Background(), "warn", fmt.Sprintf("[conn %s] unknown message type: %s", wsConn.connId, envelope.Type))
+			_ = ReplyError(wsConn, envelope, ErrCodeBusy, "too many pending messages")
 		}
 	}
 }
@@ -276,34 +779,66 @@ func (s *WebsocketServer) writePump(wsConn *wsConnection) {
 		wsConn.Close("write pump exit")
 	}()
 
-	ws := wsConn.ws
+	t := wsConn.t
+
+	// 先写出重连时补发的消息
+	replay := wsConn.replay
+	wsConn.replay = nil
+	for _, msg := range replay {
+		if err := t.WriteFrame(msg, time.Now().Add(5*time.Second)); err != nil {
+			logger.FormatLog(context.Background(), "error", fmt.Sprintf("[conn %s] write replay error: %v", wsConn.connId, err))
+			return
+		}
+		wsConn.sent.Add(1)
+	}
+
 	for {
 		select {
 		case <-wsConn.closeChan:
 			// 关闭连接
 			return
+		case reason := <-wsConn.kickChan:
+			// 通知客户端被踢下线后关闭连接
+			kicked, _ := wsConn.codec.Encode(&Envelope{
+				Type: "kicked",
+				Body: map[string]interface{}{"reason": reason},
+			})
+			_ = t.WriteFrame(kicked, time.Now().Add(time.Second))
+			wsConn.Close(reason)
+			return
 		case msg, ok := <-wsConn.SendChan:
 			// 发送消息请求
 			if !ok {
-				_ = ws.WriteMessage(websocket.CloseMessage, nil)
 				return
 			}
-			_ = ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
-			if err := ws.WriteMessage(websocket.TextMessage, msg); err != nil {
+			if err := t.WriteFrame(msg, time.Now().Add(5*time.Second)); err != nil {
 				logger.FormatLog(context.Background(), "error", fmt.Sprintf("[conn %s] write error: %v", wsConn.connId, err))
 				return
 			}
+			wsConn.sent.Add(1)
 		case <-ticker.C:
 			// ping 心跳消息
-			_ = ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
-			if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := t.Ping(time.Now().Add(5 * time.Second)); err != nil {
 				logger.FormatLog(context.Background(), "error", fmt.Sprintf("[conn %s] ping error: %v", wsConn.connId, err))
 				return
 			}
+			s.touchPresence(wsConn)
 		}
 	}
 }
 
+// writeAuthNack 认证阶段连接还未创建，直接写入认证失败响应
+func writeAuthNack(t transport, codec Codec, reason string) {
+	nack, err := codec.Encode(&Envelope{
+		Type: "auth_nack",
+		Body: map[string]interface{}{"reason": reason},
+	})
+	if err != nil {
+		return
+	}
+	_ = t.WriteFrame(nack, time.Now().Add(time.Second))
+}
+
 // 工具函数
 func (s *WebsocketServer) randUUID() string {
 	b := make([]byte, 16)
@@ -1,19 +1,29 @@
 package auth_user
 
 import (
+	"github.com/mxxmstar/learning/gate_server/gate_config"
 	auth_client "github.com/mxxmstar/learning/gate_server/internal/grpc/auth"
 	http_auth_client "github.com/mxxmstar/learning/gate_server/internal/http/auth"
+	"github.com/mxxmstar/learning/pkg/revocation"
 )
 
 type AuthUserType string
 
 const (
-	AuthUserGRPC AuthUserType = "grpc"
-	AuthUserHTTP AuthUserType = "http"
+	AuthUserGRPC  AuthUserType = "grpc"
+	AuthUserHTTP  AuthUserType = "http"
+	AuthUserLocal AuthUserType = "local" // 本地验签 jwt，不可用时回退到 grpc
 )
 
-func NewAuthService(authUserType AuthUserType, g *auth_client.AuthClient, h *http_auth_client.AuthClient) AuthService {
+func NewAuthService(authUserType AuthUserType, g *auth_client.AuthClient, h *http_auth_client.AuthClient, revocations *revocation.Store, cfg gate_config.AuthConfig) AuthService {
 	switch authUserType {
+	case AuthUserLocal:
+		// h 为 nil 时直接传入会得到非 nil 的接口值
+		var fetcher JWKSFetcher
+		if h != nil {
+			fetcher = h
+		}
+		return NewLocalAuthService(NewGRPCAuthService(g), fetcher, revocations, cfg)
 	case AuthUserGRPC:
 		return NewGRPCAuthService(g)
 	case AuthUserHTTP:
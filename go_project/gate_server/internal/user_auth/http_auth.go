@@ -1,6 +1,9 @@
 package auth_user
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 func (h *HTTPAuthService) ValidateTokenOrSession(ctx context.Context, token, sessionId, deviceId string) (*AuthResult, error) {
 	var result *AuthResult
@@ -17,6 +20,8 @@ func (h *HTTPAuthService) ValidateTokenOrSession(ctx context.Context, token, ses
 		result = &AuthResult{
 			UserId:   verifyJWTResponse.UserId,
 			DeviceId: verifyJWTResponse.DeviceId,
+			TokenId:  verifyJWTResponse.TokenId,
+			IssuedAt: time.UnixMilli(verifyJWTResponse.IssuedAtMs),
 			Valid:    verifyJWTResponse.Valid,
 			Error:    verifyJWTResponse.Error,
 		}
@@ -31,10 +36,12 @@ func (h *HTTPAuthService) ValidateTokenOrSession(ctx context.Context, token, ses
 		}
 
 		result = &AuthResult{
-			UserId:   verifySessionResponse.UserId,
-			DeviceId: deviceId,
-			Valid:    verifySessionResponse.Valid,
-			Error:    verifySessionResponse.Error,
+			UserId:    verifySessionResponse.UserId,
+			DeviceId:  deviceId,
+			SessionId: sessionId,
+			IssuedAt:  time.Now(),
+			Valid:     verifySessionResponse.Valid,
+			Error:     verifySessionResponse.Error,
 		}
 	} else {
 		return &AuthResult{
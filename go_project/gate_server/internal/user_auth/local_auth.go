@@ -0,0 +1,242 @@
+package auth_user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	grpc_auth_client "github.com/mxxmstar/learning/gate_server/internal/grpc/auth"
+	"github.com/mxxmstar/learning/pkg/cache"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/mxxmstar/learning/pkg/logger"
+	"github.com/mxxmstar/learning/pkg/revocation"
+)
+
+const (
+	// 与 verify_server 的 SessionTTL 保持一致，验证结果缓存时间必须小于该值
+	sessionTTL = 24 * time.Hour
+
+	// 遇到未知 kid 时两次拉取公钥的最小间隔，避免伪造的 kid 打满 verify_server
+	minJWKSRefreshInterval = 10 * time.Second
+)
+
+// JWKSFetcher 获取 jwt 验签公钥
+type JWKSFetcher interface {
+	FetchJWKS(ctx context.Context) (*jwt_manager.JWKS, error)
+}
+
+// LocalAuthService 在本地使用 verify_server 公开的公钥验签 jwt，并缓存验证结果；
+// 没有可用公钥时回退到 gRPC 验证
+type LocalAuthService struct {
+	fallback        *GRPCAuthService
+	fetcher         JWKSFetcher
+	revocations     *revocation.Store // 凭证吊销列表，为 nil 时不检查吊销
+	verdicts        *cache.LRU[string, *AuthResult]
+	refreshInterval time.Duration
+
+	jwtManager  atomic.Pointer[jwt_manager.JWT] // 只能验签的 jwt 管理器，为 nil 表示没有可用公钥
+	refreshMu   sync.Mutex
+	lastRefresh time.Time
+	refreshing  atomic.Bool
+}
+
+func NewLocalAuthService(fallback *GRPCAuthService, fetcher JWKSFetcher, revocations *revocation.Store, cfg gate_config.AuthConfig) *LocalAuthService {
+	ttl := cfg.VerdictCacheTTL
+	if ttl <= 0 || ttl >= sessionTTL {
+		ttl = time.Minute
+	}
+	if cfg.JWKSRefreshInterval <= 0 {
+		cfg.JWKSRefreshInterval = 5 * time.Minute
+	}
+
+	s := &LocalAuthService{
+		fallback:        fallback,
+		fetcher:         fetcher,
+		revocations:     revocations,
+		verdicts:        cache.NewLRU[string, *AuthResult](cfg.VerdictCacheSize, ttl),
+		refreshInterval: cfg.JWKSRefreshInterval,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.refreshKeys(ctx); err != nil {
+		logger.FormatLog(ctx, "warn", fmt.Sprintf("[auth] fetch jwks failed, fallback to grpc: %v", err))
+	}
+	return s
+}
+
+func (l *LocalAuthService) AuthService() *grpc_auth_client.AuthClient {
+	return l.fallback.AuthService()
+}
+
+func (l *LocalAuthService) ValidateTokenOrSession(ctx context.Context, token, sessionId, deviceId string) (*AuthResult, error) {
+	if token != "" {
+		return l.validateJWT(ctx, token)
+	}
+	if sessionId != "" {
+		return l.validateSession(ctx, sessionId, deviceId)
+	}
+	return &AuthResult{
+		Valid: false,
+		Error: "no token and session id provided",
+	}, nil
+}
+
+// Purge 清空验证结果缓存，收到吊销事件时调用
+func (l *LocalAuthService) Purge() {
+	l.verdicts.Purge()
+}
+
+func (l *LocalAuthService) RefreshSession(ctx context.Context, sessionId string) (*AuthResult, error) {
+	l.verdicts.Delete(sessionCacheKey(sessionId))
+	return l.fallback.RefreshSession(ctx, sessionId)
+}
+
+func (l *LocalAuthService) validateJWT(ctx context.Context, token string) (*AuthResult, error) {
+	key := jwtCacheKey(token)
+	if result, ok := l.verdicts.Get(key); ok {
+		return result, nil
+	}
+
+	l.maybeRefreshKeys()
+
+	if mgr := l.jwtManager.Load(); mgr != nil {
+		claims, err := mgr.ParseToken(token)
+		if err == nil {
+			result := &AuthResult{
+				UserId:   claims.UserId,
+				DeviceId: claims.DeviceId,
+				TokenId:  claims.ID,
+				IssuedAt: claims.IssuedAtTime(),
+				Valid:    true,
+			}
+			if l.revocations != nil {
+				revoked, err := l.revocations.IsRevoked(ctx, claims.UserId, claims.ID, result.IssuedAt)
+				if err != nil {
+					return &AuthResult{Valid: false, Error: "check token revocation error"}, err
+				}
+				if revoked {
+					result = &AuthResult{Valid: false, Error: "token has been revoked"}
+				}
+			}
+			// 缓存时间不超过令牌剩余有效期
+			if claims.ExpiresAt != nil {
+				l.verdicts.SetWithTTL(key, result, time.Until(claims.ExpiresAt.Time))
+			} else {
+				l.verdicts.Set(key, result)
+			}
+			return result, nil
+		}
+		if !errors.Is(err, jwt_manager.ErrKeyNotFound) {
+			result := &AuthResult{Valid: false, Error: err.Error()}
+			l.verdicts.Set(key, result)
+			return result, nil
+		}
+		// 未知 kid，可能是 verify_server 已轮换密钥
+		l.forceRefreshKeys()
+	}
+
+	result, err := l.fallback.ValidateTokenOrSession(ctx, token, "", "")
+	if err != nil {
+		return result, err
+	}
+	l.verdicts.Set(key, result)
+	return result, nil
+}
+
+func (l *LocalAuthService) validateSession(ctx context.Context, sessionId, deviceId string) (*AuthResult, error) {
+	key := sessionCacheKey(sessionId)
+	if result, ok := l.verdicts.Get(key); ok {
+		// 返回首次验证的时间，吊销事件丢失或与查询并发时仍然可以按签发时间匹配吊销
+		return &AuthResult{
+			UserId:    result.UserId,
+			DeviceId:  deviceId,
+			SessionId: sessionId,
+			IssuedAt:  result.IssuedAt,
+			Valid:     result.Valid,
+			Error:     result.Error,
+		}, nil
+	}
+
+	result, err := l.fallback.ValidateTokenOrSession(ctx, "", sessionId, deviceId)
+	if err != nil {
+		return result, err
+	}
+	l.verdicts.Set(key, result)
+	return result, nil
+}
+
+// refreshKeys 拉取公钥，HS256 等没有公开公钥的情况下清空本地公钥，全部回退到 gRPC
+func (l *LocalAuthService) refreshKeys(ctx context.Context) error {
+	l.refreshMu.Lock()
+	l.lastRefresh = time.Now()
+	l.refreshMu.Unlock()
+
+	if l.fetcher == nil {
+		return errors.New("no jwks fetcher")
+	}
+	jwks, err := l.fetcher.FetchJWKS(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := jwks.KeySet()
+	if err != nil {
+		return err
+	}
+	if keys.Len() == 0 {
+		l.jwtManager.Store(nil)
+		return nil
+	}
+	l.jwtManager.Store(jwt_manager.NewJWTWithKeySet(keys, "verify_server", 0))
+	return nil
+}
+
+// maybeRefreshKeys 公钥超过刷新间隔时在后台刷新
+func (l *LocalAuthService) maybeRefreshKeys() {
+	l.refreshMu.Lock()
+	due := time.Since(l.lastRefresh) >= l.refreshInterval
+	l.refreshMu.Unlock()
+	if due {
+		l.refreshAsync()
+	}
+}
+
+// forceRefreshKeys 遇到未知 kid 时在后台刷新，受最小间隔限制
+func (l *LocalAuthService) forceRefreshKeys() {
+	l.refreshMu.Lock()
+	due := time.Since(l.lastRefresh) >= minJWKSRefreshInterval
+	l.refreshMu.Unlock()
+	if due {
+		l.refreshAsync()
+	}
+}
+
+func (l *LocalAuthService) refreshAsync() {
+	if !l.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer l.refreshing.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := l.refreshKeys(ctx); err != nil {
+			logger.FormatLog(ctx, "warn", fmt.Sprintf("[auth] refresh jwks failed: %v", err))
+		}
+	}()
+}
+
+// 缓存 key 只保存令牌的哈希
+func jwtCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "jwt:" + hex.EncodeToString(sum[:])
+}
+
+func sessionCacheKey(sessionId string) string {
+	return "session:" + sessionId
+}
@@ -0,0 +1,218 @@
+package auth_user
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mxxmstar/learning/gate_server/gate_config"
+	grpc_auth_client "github.com/mxxmstar/learning/gate_server/internal/grpc/auth"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/mxxmstar/learning/pkg/revocation"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	pb "github.com/mxxmstar/learning/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// fakeVerifyServer 记录 gRPC 验证的调用次数，jwt 全部验证为用户 7
+type fakeVerifyServer struct {
+	pb.UnimplementedAuthServer
+	jwtCalls     atomic.Int32
+	sessionCalls atomic.Int32
+}
+
+func (s *fakeVerifyServer) VerifyJWT(ctx context.Context, req *pb.VerifyJWTRequest) (*pb.VerifyJWTResponse, error) {
+	s.jwtCalls.Add(1)
+	return &pb.VerifyJWTResponse{Valid: true, UserId: 7, TokenId: "grpc", IssuedAtMs: time.Now().UnixMilli()}, nil
+}
+
+func (s *fakeVerifyServer) VerifySession(ctx context.Context, req *pb.VerifySessionRequest) (*pb.VerifySessionResponse, error) {
+	s.sessionCalls.Add(1)
+	return &pb.VerifySessionResponse{Valid: true, UserId: 8}, nil
+}
+
+// fakeFetcher 返回当前设置的公钥并记录拉取次数
+type fakeFetcher struct {
+	mu      sync.Mutex
+	jwks    *jwt_manager.JWKS
+	fetches atomic.Int32
+}
+
+func (f *fakeFetcher) FetchJWKS(ctx context.Context) (*jwt_manager.JWKS, error) {
+	f.fetches.Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jwks, nil
+}
+
+func (f *fakeFetcher) set(keys ...*jwt_manager.Key) {
+	ks, _ := jwt_manager.NewKeySet(nil, keys...)
+	f.mu.Lock()
+	f.jwks = ks.JWKS()
+	f.mu.Unlock()
+}
+
+func newSigner(t *testing.T, kid string) (*jwt_manager.Key, *jwt_manager.JWT) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key := &jwt_manager.Key{Kid: kid, Method: jwt.SigningMethodEdDSA, PrivateKey: priv, PublicKey: pub}
+	ks, err := jwt_manager.NewKeySet(key)
+	require.NoError(t, err)
+	return key, jwt_manager.NewJWTWithKeySet(ks, "verify_server", 60)
+}
+
+func newLocalAuth(t *testing.T, fetcher *fakeFetcher, revocations *revocation.Store) (*LocalAuthService, *fakeVerifyServer) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	verify := &fakeVerifyServer{}
+	server := grpc.NewServer()
+	pb.RegisterAuthServer(server, verify)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	fallback := NewGRPCAuthService(grpc_auth_client.NewAuthClientWithConn(conn, &gate_config.Config{}))
+	return NewLocalAuthService(fallback, fetcher, revocations, gate_config.AuthConfig{
+		VerdictCacheSize:    100,
+		VerdictCacheTTL:     time.Minute,
+		JWKSRefreshInterval: time.Hour,
+	}), verify
+}
+
+func TestLocalAuthVerifyLocally(t *testing.T) {
+	key, signer := newSigner(t, "k1")
+	fetcher := &fakeFetcher{}
+	fetcher.set(key)
+	l, verify := newLocalAuth(t, fetcher, nil)
+
+	token, err := signer.GenerateToken(1, "phone")
+	require.NoError(t, err)
+	result, err := l.ValidateTokenOrSession(context.Background(), token, "", "")
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, uint64(1), result.UserId)
+	assert.Equal(t, "phone", result.DeviceId)
+	assert.NotEmpty(t, result.TokenId)
+	assert.Equal(t, int32(0), verify.jwtCalls.Load(), "有公钥时应该本地验签")
+}
+
+func TestLocalAuthFallbackWithoutKeys(t *testing.T) {
+	_, signer := newSigner(t, "k1")
+	fetcher := &fakeFetcher{}
+	fetcher.set()
+	l, verify := newLocalAuth(t, fetcher, nil)
+
+	token, err := signer.GenerateToken(1, "phone")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		result, err := l.ValidateTokenOrSession(context.Background(), token, "", "")
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, uint64(7), result.UserId, "没有公钥时应该回退到 gRPC 验证")
+	}
+	assert.Equal(t, int32(1), verify.jwtCalls.Load(), "gRPC 验证的结果应该被缓存")
+}
+
+func TestLocalAuthUnknownKidRefresh(t *testing.T) {
+	oldKey, _ := newSigner(t, "k1")
+	newKey, newSignerJWT := newSigner(t, "k2")
+	_, unknownSigner := newSigner(t, "k3")
+	fetcher := &fakeFetcher{}
+	fetcher.set(oldKey)
+	l, verify := newLocalAuth(t, fetcher, nil)
+	require.Equal(t, int32(1), fetcher.fetches.Load())
+
+	// 刚拉取过公钥，未知 kid 不会触发刷新，回退到 gRPC
+	token, err := newSignerJWT.GenerateToken(1, "")
+	require.NoError(t, err)
+	result, err := l.ValidateTokenOrSession(context.Background(), token, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), result.UserId)
+	assert.Equal(t, int32(1), fetcher.fetches.Load(), "最小间隔内不应该重新拉取公钥")
+
+	// 超过最小间隔后未知 kid 触发刷新，verify_server 已轮换到新密钥
+	fetcher.set(oldKey, newKey)
+	l.refreshMu.Lock()
+	l.lastRefresh = time.Now().Add(-minJWKSRefreshInterval)
+	l.refreshMu.Unlock()
+	token, err = newSignerJWT.GenerateToken(2, "")
+	require.NoError(t, err)
+	_, err = l.ValidateTokenOrSession(context.Background(), token, "", "")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return fetcher.fetches.Load() == 2 && !l.refreshing.Load() }, time.Second, 10*time.Millisecond, "未知 kid 应该触发刷新")
+
+	calls := verify.jwtCalls.Load()
+	token, err = newSignerJWT.GenerateToken(3, "")
+	require.NoError(t, err)
+	result, err = l.ValidateTokenOrSession(context.Background(), token, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), result.UserId, "刷新后新密钥签发的令牌应该本地验签")
+	assert.Equal(t, calls, verify.jwtCalls.Load())
+
+	// 伪造的 kid 不能在最小间隔内反复触发拉取
+	for i := 0; i < 5; i++ {
+		token, err = unknownSigner.GenerateToken(uint64(10+i), "")
+		require.NoError(t, err)
+		_, err = l.ValidateTokenOrSession(context.Background(), token, "", "")
+		require.NoError(t, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), fetcher.fetches.Load(), "未知 kid 的刷新应该受最小间隔限制")
+}
+
+func TestLocalAuthVerdictCachePurge(t *testing.T) {
+	mr := miniredis.RunT(t)
+	revocations := revocation.NewStore(redis.NewRedisClient(mr.Addr(), "", 0), time.Hour)
+	key, signer := newSigner(t, "k1")
+	fetcher := &fakeFetcher{}
+	fetcher.set(key)
+	l, _ := newLocalAuth(t, fetcher, revocations)
+	ctx := context.Background()
+
+	token, err := signer.GenerateToken(1, "")
+	require.NoError(t, err)
+	result, err := l.ValidateTokenOrSession(ctx, token, "", "")
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+
+	require.NoError(t, revocations.RevokeUserBefore(ctx, 1, time.Now().Add(time.Second)))
+	result, err = l.ValidateTokenOrSession(ctx, token, "", "")
+	require.NoError(t, err)
+	assert.True(t, result.Valid, "缓存时间内使用缓存的验证结果")
+
+	// 收到吊销事件后清空缓存，重新检查吊销列表
+	l.Purge()
+	result, err = l.ValidateTokenOrSession(ctx, token, "", "")
+	require.NoError(t, err)
+	assert.False(t, result.Valid, "清空缓存后吊销应该生效")
+}
+
+func TestLocalAuthSessionCacheKeepsIssuedAt(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	fetcher.set()
+	l, verify := newLocalAuth(t, fetcher, nil)
+	ctx := context.Background()
+
+	first, err := l.ValidateTokenOrSession(ctx, "", "session-1", "phone")
+	require.NoError(t, err)
+	require.True(t, first.Valid)
+
+	time.Sleep(5 * time.Millisecond)
+	second, err := l.ValidateTokenOrSession(ctx, "", "session-1", "pc")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), verify.sessionCalls.Load(), "session 的验证结果应该被缓存")
+	assert.Equal(t, first.IssuedAt, second.IssuedAt, "缓存命中时应该返回首次验证的时间，不能晚于吊销时间")
+	assert.Equal(t, "pc", second.DeviceId)
+}
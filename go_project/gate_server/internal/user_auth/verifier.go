@@ -2,6 +2,7 @@ package auth_user
 
 import (
 	"context"
+	"time"
 
 	grpc_auth_client "github.com/mxxmstar/learning/gate_server/internal/grpc/auth"
 	http_auth_client "github.com/mxxmstar/learning/gate_server/internal/http/auth"
@@ -15,10 +16,13 @@ type AuthService interface {
 
 // AuthResult 认证结果
 type AuthResult struct {
-	UserId   uint64
-	DeviceId string
-	Valid    bool
-	Error    string
+	UserId    uint64
+	DeviceId  string
+	TokenId   string    // jwt 的 jti，用于吊销时匹配连接
+	SessionId string    // 使用 session 认证时的 session Id
+	IssuedAt  time.Time // jwt 签发时间，session 认证时为认证时间
+	Valid     bool
+	Error     string
 }
 
 type GRPCAuthService struct {
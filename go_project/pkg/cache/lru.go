@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU 带过期时间的有界 LRU 缓存，并发安全
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration // 默认过期时间
+	ll       *list.List
+	items    map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt time.Time
+}
+
+// NewLRU 创建 LRU 缓存，超出容量时淘汰最久未使用的条目
+func NewLRU[K comparable, V any](capacity int, ttl time.Duration) *LRU[K, V] {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	return &LRU[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		ll:       list.New(),
+		items:    make(map[K]*list.Element, capacity),
+	}
+}
+
+// Get 获取缓存，过期的条目会被删除
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[K, V])
+	if time.Now().After(entry.expireAt) {
+		c.removeElement(elem)
+		return zero, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set 使用默认过期时间写入缓存
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL 写入缓存，ttl 超过默认过期时间时使用默认过期时间
+func (c *LRU[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if c.ttl > 0 && ttl > c.ttl {
+		ttl = c.ttl
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expireAt := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry[K, V])
+		entry.value = value
+		entry.expireAt = expireAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry[K, V]{key: key, value: value, expireAt: expireAt})
+	for c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Delete 删除缓存
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// Purge 清空缓存
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[K]*list.Element, c.capacity)
+}
+
+// Len 缓存条目数量，包含尚未清理的过期条目
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRU[K, V]) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry[K, V]).key)
+}
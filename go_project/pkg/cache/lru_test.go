@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEviction(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	// 访问 a 后 b 成为最久未使用的条目
+	_, ok := c.Get("a")
+	assert.True(t, ok, "a 应该存在")
+	c.Set("c", 3)
+
+	_, ok = c.Get("b")
+	assert.False(t, ok, "b 应该被淘汰")
+	v, ok := c.Get("a")
+	assert.True(t, ok, "a 不应该被淘汰")
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.Len(), "缓存数量不应超过容量")
+}
+
+func TestLRUExpire(t *testing.T) {
+	c := NewLRU[string, int](10, 50*time.Millisecond)
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Hour)
+	c.SetWithTTL("c", 3, 0)
+
+	_, ok := c.Get("c")
+	assert.False(t, ok, "ttl <= 0 时不应该写入缓存")
+
+	time.Sleep(80 * time.Millisecond)
+	_, ok = c.Get("a")
+	assert.False(t, ok, "a 应该已过期")
+	_, ok = c.Get("b")
+	assert.False(t, ok, "过期时间不应超过默认过期时间")
+	assert.Equal(t, 0, c.Len(), "过期条目应该被删除")
+}
+
+func TestLRUDelete(t *testing.T) {
+	c := NewLRU[string, int](10, time.Minute)
+	c.Set("a", 1)
+	c.Set("a", 2)
+	v, _ := c.Get("a")
+	assert.Equal(t, 2, v, "重复写入应该覆盖旧值")
+
+	c.Delete("a")
+	_, ok := c.Get("a")
+	assert.False(t, ok, "a 应该被删除")
+}
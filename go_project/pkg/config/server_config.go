@@ -17,7 +17,7 @@ type GateServerConfig struct {
 }
 
 type GateGRPCServerConfig struct {
-	Host string `mapstructure:"host"`
+	Host string `mapstructure:"host"` // 内部 gRPC 服务只校验共享令牌且不加密，必须是内网地址
 	Port int    `mapstructure:"port"`
 }
 
@@ -77,6 +77,7 @@ type ServiceConfig struct {
 type GlobalConfig struct {
 	Env             string `mapstructure:"env"`
 	DefaultLogLevel string `mapstructure:"default_log_level"` // 默认日志级别
+	InternalToken   string `mapstructure:"internal_token"`    // 服务间调用的共享令牌，verify_server 的登出、吊销等内部接口及 gate 的 GateRelay/Push 需要携带
 }
 
 // 负载均衡配置
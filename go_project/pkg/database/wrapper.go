@@ -14,6 +14,8 @@ type DBContextInterface interface {
 	Create(value interface{}) DBContextInterface
 	Where(query interface{}, args ...interface{}) DBContextInterface
 	First(dest interface{}) DBContextInterface
+	Model(value interface{}) DBContextInterface
+	Updates(values interface{}) DBContextInterface
 	Error() error
 }
 
@@ -63,6 +65,14 @@ func (w *GORMContextWrapper) First(dest interface{}) DBContextInterface {
 	return &GORMContextWrapper{db: w.db.First(dest)}
 }
 
+func (w *GORMContextWrapper) Model(value interface{}) DBContextInterface {
+	return &GORMContextWrapper{db: w.db.Model(value)}
+}
+
+func (w *GORMContextWrapper) Updates(values interface{}) DBContextInterface {
+	return &GORMContextWrapper{db: w.db.Updates(values)}
+}
+
 func (w *GORMContextWrapper) Error() error {
 	return w.db.Error
 }
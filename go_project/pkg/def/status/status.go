@@ -35,6 +35,9 @@ type HTTPAddress struct {
 }
 
 func (req *GRPCAddress) ConvertToStatusServiceInfo() *status_model.GRPCAddress {
+	if req == nil {
+		return nil
+	}
 	grpc := &status_model.GRPCAddress{
 		Host: req.Host,
 		Port: req.Port,
@@ -43,6 +46,9 @@ func (req *GRPCAddress) ConvertToStatusServiceInfo() *status_model.GRPCAddress {
 }
 
 func (req *HTTPAddress) ConvertToStatusServiceInfo() *status_model.HTTPAddress {
+	if req == nil {
+		return nil
+	}
 	http := &status_model.HTTPAddress{
 		Host: req.Host,
 		Port: req.Port,
@@ -94,9 +100,12 @@ type ServiceDeregisterResponse struct {
 
 // ServiceHeartbeatRequest 服务心跳请求
 type ServiceHeartbeatRequest struct {
-	ServiceId string `json:"service_id"` // 服务Id
-	Status    string `json:"status"`     // 服务状态
-	Timestamp int64  `json:"timestamp"`  // 服务心跳时间
+	ServiceType string `json:"service_type"` // 服务类型
+	ServiceId   string `json:"service_id"`   // 服务Id
+	Status      string `json:"status"`       // 服务状态
+	Timestamp   int64  `json:"timestamp"`    // 服务心跳时间
+	Load        int    `json:"load"`         // 当前负载，gate 为连接数
+	MaxLoad     int    `json:"max_load"`     // 最大负载，0 表示不限制
 }
 
 type ServiceHeartbeatResponse struct {
@@ -127,6 +136,8 @@ type ServiceInfo struct {
 	Weight         int               `json:"weight"`             // 权重
 	Status         string            `json:"status"`             // 服务状态
 	LastHeartbeat  int64             `json:"last_heartbeat"`     // 最后一次心跳时间
+	Load           int               `json:"load"`               // 当前负载
+	MaxLoad        int               `json:"max_load"`           // 最大负载，0 表示不限制
 }
 
 // ServiceDiscoveryResponse 服务发现响应
@@ -165,6 +176,7 @@ type ServiceDiscoveryByTagsRequest struct {
 	Tags        []string          `json:"tags,omitempty"`     // 标签
 	Metadata    map[string]string `json:"metadata,omitempty"` // 元数据
 	Strategy    string            `json:"strategy"`           // 负载均衡策略
+	Exclude     []string          `json:"exclude,omitempty"`  // 不参与选择的服务 Id，如请求方自身
 }
 
 type ServiceDiscoveryByTagsResponse struct {
@@ -14,11 +14,19 @@ type VerifyJWTRequest struct {
 	JWTToken string `json:"jwtToken"`
 }
 
+// 服务间调用携带共享令牌的 http 头与 grpc metadata 键
+const (
+	InternalTokenHeader      = "X-Internal-Token"
+	InternalTokenMetadataKey = "x-internal-token"
+)
+
 type VerifyJWTResponse struct {
-	Valid    bool   `json:"valid"`
-	UserId   uint64 `json:"userId,omitempty"`
-	DeviceId string `json:"deviceId,omitempty"`
-	Error    string `json:"error,omitempty"`
+	Valid      bool   `json:"valid"`
+	UserId     uint64 `json:"userId,omitempty"`
+	DeviceId   string `json:"deviceId,omitempty"`
+	TokenId    string `json:"tokenId,omitempty"`
+	IssuedAtMs int64  `json:"issuedAtMs,omitempty"` // jwt 签发时间，unix 毫秒
+	Error      string `json:"error,omitempty"`
 }
 
 type RefreshSessionRequest struct {
@@ -37,10 +45,11 @@ type LoginByEmailRequest struct {
 }
 
 type LoginByEmailResponse struct {
-	SessionId string `json:"sessionId"`
-	JWTToken  string `json:"jwtToken"`
-	UserId    uint64 `json:"userId"`
-	Error     string `json:"error,omitempty"`
+	SessionId    string `json:"sessionId"`
+	JWTToken     string `json:"jwtToken"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	UserId       uint64 `json:"userId"`
+	Error        string `json:"error,omitempty"`
 }
 
 type SignUpRequest struct {
@@ -54,3 +63,79 @@ type SignUpResponse struct {
 	Success bool   `json:"success"`
 	Error   string `json:"error,omitempty"`
 }
+
+type RefreshJWTRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshJWTResponse struct {
+	Success      bool   `json:"success"`
+	JWTToken     string `json:"jwtToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	UserId       uint64 `json:"userId,omitempty"`
+	ExpiresIn    int64  `json:"expiresIn,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type LogoutRequest struct {
+	SessionId string `json:"sessionId,omitempty"`
+	JWTToken  string `json:"jwtToken,omitempty"`
+}
+
+type LogoutResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type RevokeUserTokensRequest struct {
+	UserId uint64 `json:"userId"`
+}
+
+type RevokeUserTokensResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type SessionInfo struct {
+	Handle     string `json:"handle"` // session 的不透明标识，不能用于认证
+	DeviceId   string `json:"deviceId,omitempty"`
+	IPAddress  string `json:"ipAddress,omitempty"`
+	UserAgent  string `json:"userAgent,omitempty"`
+	CreatedAt  int64  `json:"createdAt"`
+	LastSeenAt int64  `json:"lastSeenAt"`
+	Current    bool   `json:"current,omitempty"` // 是否为调用方当前使用的 session
+}
+
+// ListSessionsRequest 用户由调用方的 session 或 jwt 确定
+type ListSessionsRequest struct {
+	SessionId string `json:"sessionId,omitempty"`
+	JWTToken  string `json:"jwtToken,omitempty"`
+}
+
+type ListSessionsResponse struct {
+	Sessions []SessionInfo `json:"sessions"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type RevokeSessionRequest struct {
+	SessionId string `json:"sessionId,omitempty"`
+	JWTToken  string `json:"jwtToken,omitempty"`
+	Handle    string `json:"handle"` // 要注销的 session，取自 ListSessions
+}
+
+type RevokeSessionResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RevokeOtherSessionsRequest 使用 session 认证时保留该 session，使用 jwt 认证时保留同一设备的 session
+type RevokeOtherSessionsRequest struct {
+	SessionId string `json:"sessionId,omitempty"`
+	JWTToken  string `json:"jwtToken,omitempty"`
+}
+
+type RevokeOtherSessionsResponse struct {
+	Success bool   `json:"success"`
+	Revoked int    `json:"revoked"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,152 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params argon2id 参数
+type Argon2Params struct {
+	Memory      uint32 `mapstructure:"memory"`      // 内存开销，单位 KiB
+	Iterations  uint32 `mapstructure:"iterations"`  // 迭代次数
+	Parallelism uint8  `mapstructure:"parallelism"` // 并行度
+	SaltLength  uint32 `mapstructure:"salt_length"` // 盐长度，单位字节
+	KeyLength   uint32 `mapstructure:"key_length"`  // 哈希长度，单位字节
+}
+
+// DefaultArgon2Params 默认参数，参考 RFC 9106 的推荐配置
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// 解析哈希时允许的参数上限，超过时按无效哈希处理，避免损坏或伪造的哈希在每次登录时占用过多内存和 CPU
+const (
+	maxArgon2KeyLength  = 1024
+	maxArgon2Memory     = 1024 * 1024 // 1 GiB，单位 KiB
+	maxArgon2Iterations = 64
+)
+
+// Argon2idHasher argon2id 密码哈希器
+// 哈希格式: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>，salt 和 hash 为无填充的 base64
+type Argon2idHasher struct {
+	params Argon2Params
+}
+
+func NewArgon2idHasher(params Argon2Params) *Argon2idHasher {
+	if params.Memory == 0 {
+		params.Memory = DefaultArgon2Params.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = DefaultArgon2Params.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = DefaultArgon2Params.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = DefaultArgon2Params.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = DefaultArgon2Params.KeyLength
+	}
+	return &Argon2idHasher{params: params}
+}
+
+// validate 检查配置的参数不超过解析时的上限，否则生成的哈希无法校验
+func (p Argon2Params) validate() error {
+	if p.Memory > maxArgon2Memory || p.Iterations > maxArgon2Iterations || p.KeyLength > maxArgon2KeyLength {
+		return fmt.Errorf("argon2 params exceed limits: m<=%d, t<=%d, key_length<=%d", maxArgon2Memory, maxArgon2Iterations, maxArgon2KeyLength)
+	}
+	return nil
+}
+
+func (h *Argon2idHasher) Algorithm() string {
+	return AlgorithmArgon2id
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id,
+		argon2.Version,
+		h.params.Memory,
+		h.params.Iterations,
+		h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		params.KeyLength != h.params.KeyLength ||
+		uint32(len(salt)) != h.params.SaltLength
+}
+
+// decodeArgon2id 解析 argon2id 的 PHC 字符串
+func decodeArgon2id(encoded string) (params Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, ErrIncompatibleVersion
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	// argon2.IDKey 在 t 或 p 为 0 时会 panic，参数必须在解析时校验
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 ||
+		params.Memory > maxArgon2Memory || params.Iterations > maxArgon2Iterations ||
+		parts[3] != fmt.Sprintf("m=%d,t=%d,p=%d", params.Memory, params.Iterations, params.Parallelism) {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err = base64.RawStdEncoding.Strict().DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err = base64.RawStdEncoding.Strict().DecodeString(parts[5])
+	if err != nil || len(key) == 0 || len(key) > maxArgon2KeyLength {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
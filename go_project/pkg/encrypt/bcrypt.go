@@ -0,0 +1,43 @@
+package encrypt
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptHasher bcrypt 密码哈希器
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Algorithm() string {
+	return AlgorithmBcrypt
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	return HashPasswordWithCost(password, h.cost)
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
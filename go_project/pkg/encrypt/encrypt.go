@@ -4,7 +4,15 @@ import "golang.org/x/crypto/bcrypt"
 
 // HashPassword 使用 bcrypt 对密码进行哈希处理
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return HashPasswordWithCost(password, bcrypt.DefaultCost)
+}
+
+// HashPasswordWithCost 使用指定 cost 的 bcrypt 对密码进行哈希处理，cost 不合法时使用默认值
+func HashPasswordWithCost(password string, cost int) (string, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	return string(bytes), err
 }
 
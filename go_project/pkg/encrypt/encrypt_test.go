@@ -0,0 +1,134 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试用的低开销 argon2id 参数
+var testArgon2Params = Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestHashPasswordWithCost(t *testing.T) {
+	hash, err := HashPasswordWithCost("123456", bcrypt.MinCost)
+	assert.NoError(t, err)
+	assert.Equal(t, AlgorithmBcrypt, Identify(hash), "生成的不是 bcrypt 哈希")
+	assert.True(t, CheckPasswordHash("123456", hash), "密码校验失败")
+	assert.False(t, CheckPasswordHash("654321", hash), "错误密码校验通过")
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	assert.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost, "cost 不一致")
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2Params)
+
+	hash, err := h.Hash("123456")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), "哈希格式错误: %s", hash)
+	assert.Equal(t, AlgorithmArgon2id, Identify(hash))
+
+	ok, err := h.Verify("123456", hash)
+	assert.NoError(t, err)
+	assert.True(t, ok, "密码校验失败")
+
+	ok, err = h.Verify("654321", hash)
+	assert.NoError(t, err)
+	assert.False(t, ok, "错误密码校验通过")
+
+	assert.False(t, h.NeedsRehash(hash), "参数一致时不需要重新哈希")
+
+	stronger := testArgon2Params
+	stronger.Iterations = 2
+	assert.True(t, NewArgon2idHasher(stronger).NeedsRehash(hash), "参数变化时需要重新哈希")
+
+	_, err = h.Verify("123456", "$argon2id$v=19$broken")
+	assert.ErrorIs(t, err, ErrInvalidHash)
+
+	// 非法参数应该在解析时报错，不能让 argon2 panic
+	salt := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	key := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	for _, params := range []string{"m=1024,t=0,p=1", "m=1024,t=1,p=0", "m=0,t=1,p=1", "m=1024,t=1,p=1x", "m=4294967295,t=1,p=1", "m=1024,t=100000,p=1"} {
+		_, err = h.Verify("123456", fmt.Sprintf("$argon2id$v=19$%s$%s$%s", params, salt, key))
+		assert.ErrorIs(t, err, ErrInvalidHash, "参数非法: %s", params)
+	}
+	longKey := base64.RawStdEncoding.EncodeToString(make([]byte, maxArgon2KeyLength+1))
+	_, err = h.Verify("123456", fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s", salt, longKey))
+	assert.ErrorIs(t, err, ErrInvalidHash, "哈希长度超过上限应该报错")
+
+	huge := testArgon2Params
+	huge.Memory = maxArgon2Memory + 1
+	_, err = NewPasswordHasher(AlgorithmArgon2id, bcrypt.MinCost, huge)
+	assert.Error(t, err, "配置的参数超过上限时应该报错")
+}
+
+func TestMultiHasher(t *testing.T) {
+	bcryptHasher := NewBcryptHasher(bcrypt.MinCost)
+	argon2Hasher := NewArgon2idHasher(testArgon2Params)
+
+	bcryptHash, err := bcryptHasher.Hash("123456")
+	assert.NoError(t, err)
+	argon2Hash, err := argon2Hasher.Hash("123456")
+	assert.NoError(t, err)
+
+	t.Run("DispatchByAlgorithm", func(t *testing.T) {
+		m := NewMultiHasher(argon2Hasher, bcryptHasher)
+
+		// 新哈希使用主算法
+		hash, err := m.Hash("123456")
+		assert.NoError(t, err)
+		assert.Equal(t, AlgorithmArgon2id, Identify(hash))
+
+		// 存量 bcrypt 哈希依然可以校验，但需要迁移到主算法
+		ok, err := m.Verify("123456", bcryptHash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, m.NeedsRehash(bcryptHash))
+
+		ok, err = m.Verify("123456", argon2Hash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.False(t, m.NeedsRehash(argon2Hash))
+	})
+
+	t.Run("UnsupportedAlgorithm", func(t *testing.T) {
+		m := NewMultiHasher(bcryptHasher)
+		_, err := m.Verify("123456", argon2Hash)
+		assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	})
+
+	t.Run("LegacyPlaintext", func(t *testing.T) {
+		m := NewMultiHasher(bcryptHasher)
+		_, err := m.Verify("123456", "123456")
+		assert.ErrorIs(t, err, ErrPlaintextPasswordDenied)
+
+		m.AllowPlaintext(true)
+		ok, err := m.Verify("123456", "123456")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, m.NeedsRehash("123456"), "明文密码需要重新哈希")
+
+		ok, err = m.Verify("654321", "123456")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("OutdatedCost", func(t *testing.T) {
+		m := NewMultiHasher(NewBcryptHasher(bcrypt.MinCost + 1))
+		ok, err := m.Verify("123456", bcryptHash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, m.NeedsRehash(bcryptHash), "cost 变化时需要重新哈希")
+	})
+}
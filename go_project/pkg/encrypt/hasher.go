@@ -0,0 +1,127 @@
+package encrypt
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidHash             = errors.New("invalid password hash format")
+	ErrIncompatibleVersion     = errors.New("incompatible password hash version")
+	ErrUnsupportedAlgorithm    = errors.New("unsupported password hash algorithm")
+	ErrPlaintextPasswordDenied = errors.New("plaintext password is not allowed")
+)
+
+// 支持的密码哈希算法，与 PHC 字符串中的算法标识保持一致
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// PasswordHasher 密码哈希器接口
+// 哈希结果是自描述的 PHC 格式字符串（bcrypt 使用其自身的 $2b$ 格式），校验时可根据前缀选择算法
+type PasswordHasher interface {
+	// Algorithm 返回算法标识
+	Algorithm() string
+	// Hash 生成密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码与哈希是否匹配
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash 判断哈希的算法参数是否与当前配置不一致，需要重新哈希
+	NeedsRehash(encoded string) bool
+}
+
+// Identify 解析哈希字符串中的算法标识，不是 PHC 格式（如历史明文密码）时返回空字符串
+func Identify(encoded string) string {
+	if !strings.HasPrefix(encoded, "$") {
+		return ""
+	}
+	id, _, ok := strings.Cut(encoded[1:], "$")
+	if !ok {
+		return ""
+	}
+	switch id {
+	case "2a", "2b", "2y":
+		return AlgorithmBcrypt
+	default:
+		return id
+	}
+}
+
+// MultiHasher 组合多个算法的哈希器
+// 新哈希统一使用 primary 生成，校验时根据哈希中的算法标识分派，便于不停机迁移算法
+type MultiHasher struct {
+	primary        PasswordHasher
+	hashers        map[string]PasswordHasher
+	allowPlaintext bool // 是否兼容历史明文密码
+}
+
+func NewMultiHasher(primary PasswordHasher, others ...PasswordHasher) *MultiHasher {
+	m := &MultiHasher{
+		primary: primary,
+		hashers: make(map[string]PasswordHasher),
+	}
+	for _, h := range others {
+		m.hashers[h.Algorithm()] = h
+	}
+	m.hashers[primary.Algorithm()] = primary
+	return m
+}
+
+// AllowPlaintext 设置是否兼容历史明文密码，兼容时明文密码校验成功后会被要求重新哈希
+func (m *MultiHasher) AllowPlaintext(allow bool) *MultiHasher {
+	m.allowPlaintext = allow
+	return m
+}
+
+func (m *MultiHasher) Algorithm() string {
+	return m.primary.Algorithm()
+}
+
+func (m *MultiHasher) Hash(password string) (string, error) {
+	return m.primary.Hash(password)
+}
+
+func (m *MultiHasher) Verify(password, encoded string) (bool, error) {
+	algorithm := Identify(encoded)
+	if algorithm == "" {
+		if !m.allowPlaintext {
+			return false, ErrPlaintextPasswordDenied
+		}
+		// 历史明文密码，使用常量时间比较避免时序攻击
+		return subtle.ConstantTimeCompare([]byte(password), []byte(encoded)) == 1, nil
+	}
+
+	h, ok := m.hashers[algorithm]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+	return h.Verify(password, encoded)
+}
+
+func (m *MultiHasher) NeedsRehash(encoded string) bool {
+	if Identify(encoded) != m.primary.Algorithm() {
+		return true
+	}
+	return m.primary.NeedsRehash(encoded)
+}
+
+// NewPasswordHasher 根据配置创建密码哈希器，algorithm 指定新密码使用的算法，其余算法仅用于校验存量哈希
+func NewPasswordHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (*MultiHasher, error) {
+	if err := argon2Params.validate(); err != nil {
+		return nil, err
+	}
+	bcryptHasher := NewBcryptHasher(bcryptCost)
+	argon2Hasher := NewArgon2idHasher(argon2Params)
+
+	switch algorithm {
+	case AlgorithmBcrypt, "":
+		return NewMultiHasher(bcryptHasher, argon2Hasher), nil
+	case AlgorithmArgon2id:
+		return NewMultiHasher(argon2Hasher, bcryptHasher), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
@@ -0,0 +1,54 @@
+package internalauth
+
+import (
+	"context"
+	"crypto/subtle"
+
+	auth_def "github.com/mxxmstar/learning/pkg/def/verify/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Valid 常量时间比较调用方携带的令牌，未配置令牌时总是返回 false
+func Valid(got, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// FromIncomingContext 读取 grpc metadata 中的共享令牌
+func FromIncomingContext(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(auth_def.InternalTokenMetadataKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// IsInternal grpc 调用方是否携带了正确的共享令牌
+func IsInternal(ctx context.Context, token string) bool {
+	return Valid(FromIncomingContext(ctx), token)
+}
+
+// AppendToOutgoingContext 为发出的 grpc 调用附加共享令牌
+func AppendToOutgoingContext(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, auth_def.InternalTokenMetadataKey, token)
+}
+
+// UnaryServerInterceptor 要求所有方法都携带共享令牌，用于只供内部服务调用的 grpc 服务
+func UnaryServerInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !IsInternal(ctx, token) {
+			return nil, status.Error(codes.Unauthenticated, "invalid internal token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// UnaryClientInterceptor 为连接上的所有调用附加共享令牌
+func UnaryClientInterceptor(token string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(AppendToOutgoingContext(ctx, token), method, req, reply, cc, opts...)
+	}
+}
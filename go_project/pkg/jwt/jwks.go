@@ -0,0 +1,121 @@
+package jwt_manager
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK 单个公钥的 JSON Web Key 表示 (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`           // 密钥类型 RSA/OKP
+	Kid string `json:"kid"`           // 密钥 Id
+	Use string `json:"use,omitempty"` // 用途，固定为 sig
+	Alg string `json:"alg,omitempty"` // 签名算法
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // OKP 曲线，Ed25519
+	X   string `json:"x,omitempty"`   // OKP 公钥
+}
+
+// JWKS 公钥集合，用于 /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 导出所有非对称验签公钥，HMAC 密钥不会被导出
+func (ks *KeySet) JWKS() *JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := &JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, k := range ks.keys {
+		jwk, ok := toJWK(k)
+		if ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+func toJWK(k *Key) (JWK, bool) {
+	switch pub := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.Kid,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.Kid,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
+
+// Key 将 JWK 解析为验签密钥
+func (j *JWK) Key() (*Key, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid rsa modulus", ErrInvalidKey)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid rsa exponent", ErrInvalidKey)
+		}
+		return &Key{
+			Kid:    j.Kid,
+			Method: jwt.SigningMethodRS256,
+			PublicKey: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			},
+		}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: unsupported curve %s", ErrInvalidKey, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid ed25519 public key", ErrInvalidKey)
+		}
+		return &Key{
+			Kid:       j.Kid,
+			Method:    jwt.SigningMethodEdDSA,
+			PublicKey: ed25519.PublicKey(x),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %s", ErrInvalidKey, j.Kty)
+	}
+}
+
+// KeySet 将 JWKS 解析为只能验签的密钥集合，无法解析的密钥会被跳过
+func (j *JWKS) KeySet() (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for i := range j.Keys {
+		k, err := j.Keys[i].Key()
+		if err != nil {
+			continue
+		}
+		if err := ks.Add(k); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
@@ -1,6 +1,8 @@
 package jwt_manager
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"strconv"
 	"time"
@@ -16,43 +18,103 @@ type JWTConfig struct {
 }
 
 type CustomClaims struct {
-	UserId   uint64 `json:"user_id"`
-	DeviceId string `json:"device_id,omitempty"`
+	UserId     uint64 `json:"user_id"`
+	DeviceId   string `json:"device_id,omitempty"`
+	IssuedAtMs int64  `json:"iat_ms,omitempty"` // 毫秒精度的签发时间，iat 只精确到秒，吊销判断需要更高精度
 	jwt.RegisteredClaims
 }
 
+// IssuedAtTime 返回签发时间，优先使用毫秒精度的 iat_ms
+func (c *CustomClaims) IssuedAtTime() time.Time {
+	if c.IssuedAtMs > 0 {
+		return time.UnixMilli(c.IssuedAtMs)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
 type JWT struct {
 	config *JWTConfig
+	keys   *KeySet
 }
 
+// NewJWT 创建使用 HS256 签名的 JWT 管理器
 func NewJWT(secretKey []byte, issuer string, expire int) *JWT {
+	keys, _ := NewKeySet(NewHMACKey("", secretKey))
 	return &JWT{
 		config: &JWTConfig{
 			SecretKey: secretKey,
 			Issuer:    issuer,
 			Expire:    expire,
 		},
+		keys: keys,
+	}
+}
+
+// NewJWTWithKeySet 使用密钥集合创建 JWT 管理器，签名使用当前签名密钥，验签根据 kid 选择密钥
+func NewJWTWithKeySet(keys *KeySet, issuer string, expire int) *JWT {
+	return &JWT{
+		config: &JWTConfig{
+			Issuer: issuer,
+			Expire: expire,
+		},
+		keys: keys,
 	}
 }
 
+// KeySet 获取密钥集合，用于轮换密钥
+func (j *JWT) KeySet() *KeySet {
+	return j.keys
+}
+
+// JWKS 导出验签公钥
+func (j *JWT) JWKS() *JWKS {
+	return j.keys.JWKS()
+}
+
+// Expire 令牌有效期(秒)
+func (j *JWT) Expire() int {
+	return j.config.Expire
+}
+
 func (j *JWT) GenerateToken(userId uint64, deviceId string) (string, error) {
 	if userId == 0 {
 		return "", errors.New("user id is null")
 	}
 
+	// jti 用于吊销单个令牌
+	tokenId, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := CustomClaims{
-		UserId:   userId,
-		DeviceId: deviceId,
+		UserId:     userId,
+		DeviceId:   deviceId,
+		IssuedAtMs: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
 			Issuer:    j.config.Issuer,
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(j.config.Expire) * time.Second)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(j.config.Expire) * time.Second)),
 			Subject:   strconv.FormatUint(userId, 10),
 		},
 	}
 
-	// 创建并签名JWT 这里是HS256算法
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.config.SecretKey)
+	key, err := j.keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	// 创建并签名JWT，kid 用于验签时选择密钥
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.Kid != "" {
+		token.Header["kid"] = key.Kid
+	}
+	tokenString, err := token.SignedString(key.PrivateKey)
 	if err != nil {
 		return "", err
 	}
@@ -65,12 +127,7 @@ func (j *JWT) ParseToken(tokenString string) (*CustomClaims, error) {
 		return nil, errors.New("token is null")
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return j.config.SecretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, j.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -82,3 +139,24 @@ func (j *JWT) ParseToken(tokenString string) (*CustomClaims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// keyFunc 根据 kid 选择验签密钥，并要求令牌算法与密钥算法一致，防止算法混淆
+func (j *JWT) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, err := j.keys.Lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.PublicKey, nil
+}
+
+func newTokenId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
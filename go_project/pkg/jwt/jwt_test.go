@@ -0,0 +1,207 @@
+package jwt_manager
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRSAKey(t *testing.T, kid string) *Key {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return &Key{Kid: kid, Method: jwt.SigningMethodRS256, PrivateKey: priv, PublicKey: &priv.PublicKey}
+}
+
+func newEd25519Key(t *testing.T, kid string) *Key {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return &Key{Kid: kid, Method: jwt.SigningMethodEdDSA, PrivateKey: priv, PublicKey: pub}
+}
+
+func TestHS256(t *testing.T) {
+	j := NewJWT([]byte("secret"), "test", 60)
+	token, err := j.GenerateToken(1, "device")
+	assert.NoError(t, err, "生成令牌应该成功")
+
+	claims, err := j.ParseToken(token)
+	assert.NoError(t, err, "解析令牌应该成功")
+	assert.Equal(t, uint64(1), claims.UserId, "用户Id应该一致")
+	assert.Equal(t, "device", claims.DeviceId, "设备Id应该一致")
+	assert.NotEmpty(t, claims.ID, "令牌应该携带 jti")
+	assert.NotNil(t, claims.IssuedAt, "令牌应该携带签发时间")
+
+	other, err := j.GenerateToken(1, "device")
+	assert.NoError(t, err)
+	otherClaims, err := j.ParseToken(other)
+	assert.NoError(t, err)
+	assert.NotEqual(t, claims.ID, otherClaims.ID, "每个令牌的 jti 应该不同")
+
+	_, err = NewJWT([]byte("other"), "test", 60).ParseToken(token)
+	assert.Error(t, err, "使用错误的密钥应该验签失败")
+	assert.Empty(t, j.JWKS().Keys, "HMAC 密钥不应该被导出")
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	for _, key := range []*Key{newRSAKey(t, "rsa-1"), newEd25519Key(t, "ed-1")} {
+		ks, err := NewKeySet(key)
+		assert.NoError(t, err)
+		j := NewJWTWithKeySet(ks, "test", 60)
+
+		token, err := j.GenerateToken(42, "")
+		assert.NoError(t, err, "生成令牌应该成功")
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &CustomClaims{})
+		assert.NoError(t, err)
+		assert.Equal(t, key.Kid, parsed.Header["kid"], "令牌头部应该携带 kid")
+		assert.Equal(t, key.Method.Alg(), parsed.Header["alg"], "令牌算法应该与密钥一致")
+
+		claims, err := j.ParseToken(token)
+		assert.NoError(t, err, "解析令牌应该成功")
+		assert.Equal(t, uint64(42), claims.UserId, "用户Id应该一致")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := newRSAKey(t, "old")
+	ks, err := NewKeySet(oldKey)
+	assert.NoError(t, err)
+	j := NewJWTWithKeySet(ks, "test", 60)
+
+	oldToken, err := j.GenerateToken(1, "")
+	assert.NoError(t, err)
+
+	assert.NoError(t, ks.Rotate(newEd25519Key(t, "new")), "轮换签名密钥应该成功")
+	newToken, err := j.GenerateToken(1, "")
+	assert.NoError(t, err)
+
+	_, err = j.ParseToken(oldToken)
+	assert.NoError(t, err, "轮换期内旧令牌应该仍然有效")
+	_, err = j.ParseToken(newToken)
+	assert.NoError(t, err, "新令牌应该有效")
+	assert.Len(t, j.JWKS().Keys, 2, "JWKS 应该包含新旧两个公钥")
+
+	ks.Remove("old")
+	_, err = j.ParseToken(oldToken)
+	assert.Error(t, err, "移除旧密钥后旧令牌应该失效")
+
+	ks.Remove("new")
+	_, err = j.ParseToken(newToken)
+	assert.NoError(t, err, "当前签名密钥不能被移除")
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	key := newRSAKey(t, "rsa")
+	ks, err := NewKeySet(key)
+	assert.NoError(t, err)
+	j := NewJWTWithKeySet(ks, "test", 60)
+
+	// 使用公钥作为 HMAC 密钥伪造令牌
+	pubDER, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+	assert.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, CustomClaims{UserId: 1})
+	forged.Header["kid"] = "rsa"
+	forgedToken, err := forged.SignedString(pubDER)
+	assert.NoError(t, err)
+
+	_, err = j.ParseToken(forgedToken)
+	assert.Error(t, err, "算法与密钥不一致的令牌应该被拒绝")
+}
+
+func TestJWKSRoundTrip(t *testing.T) {
+	ks, err := NewKeySet(newRSAKey(t, "rsa"), newEd25519Key(t, "ed"))
+	assert.NoError(t, err)
+	signer := NewJWTWithKeySet(ks, "test", 60)
+	token, err := signer.GenerateToken(7, "")
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(signer.JWKS())
+	assert.NoError(t, err)
+
+	var jwks JWKS
+	assert.NoError(t, json.Unmarshal(data, &jwks))
+	verifyKeys, err := jwks.KeySet()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, verifyKeys.Len(), "应该解析出两个公钥")
+
+	claims, err := NewJWTWithKeySet(verifyKeys, "test", 60).ParseToken(token)
+	assert.NoError(t, err, "使用 JWKS 公钥应该能验签")
+	assert.Equal(t, uint64(7), claims.UserId)
+
+	_, err = NewJWTWithKeySet(verifyKeys, "test", 60).GenerateToken(7, "")
+	assert.ErrorIs(t, err, ErrNoSigningKey, "只有公钥时不能签发令牌")
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	writePEM := func(name, typ string, der []byte) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600))
+		return path
+	}
+
+	signing := newEd25519Key(t, "ed-2")
+	privDER, err := x509.MarshalPKCS8PrivateKey(signing.PrivateKey)
+	assert.NoError(t, err)
+	old := newRSAKey(t, "rsa-1")
+	pubDER, err := x509.MarshalPKIXPublicKey(old.PublicKey)
+	assert.NoError(t, err)
+
+	ks, err := LoadKeySet(KeySetConfig{
+		Algorithm: AlgorithmEdDSA,
+		SigningKey: KeyFileConfig{
+			Kid:            "ed-2",
+			PrivateKeyFile: writePEM("signing.pem", "PRIVATE KEY", privDER),
+		},
+		VerificationKeys: []KeyFileConfig{{
+			Kid:           "rsa-1",
+			Algorithm:     AlgorithmRS256,
+			PublicKeyFile: writePEM("old.pem", "PUBLIC KEY", pubDER),
+		}},
+	}, nil)
+	assert.NoError(t, err, "加载密钥文件应该成功")
+	assert.Equal(t, 2, ks.Len())
+
+	oldToken, err := NewJWTWithKeySet(mustKeySet(t, old), "test", 60).GenerateToken(1, "")
+	assert.NoError(t, err)
+	_, err = NewJWTWithKeySet(ks, "test", 60).ParseToken(oldToken)
+	assert.NoError(t, err, "旧密钥签发的令牌应该能被验签")
+
+	_, err = LoadKeySet(KeySetConfig{
+		Algorithm: AlgorithmRS256,
+		SigningKey: KeyFileConfig{
+			Kid:            "wrong",
+			PrivateKeyFile: filepath.Join(dir, "signing.pem"),
+		},
+	}, nil)
+	assert.ErrorIs(t, err, ErrInvalidKey, "密钥类型与算法不一致应该报错")
+
+	// X25519 私钥不能签名，应该报错而不是 panic
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	x25519DER, err := x509.MarshalPKCS8PrivateKey(x25519)
+	assert.NoError(t, err)
+	_, err = LoadKeySet(KeySetConfig{
+		Algorithm: AlgorithmEdDSA,
+		SigningKey: KeyFileConfig{
+			Kid:            "x25519",
+			PrivateKeyFile: writePEM("x25519.pem", "PRIVATE KEY", x25519DER),
+		},
+	}, nil)
+	assert.ErrorIs(t, err, ErrInvalidKey, "不能签名的私钥应该报错")
+}
+
+func mustKeySet(t *testing.T, signing *Key) *KeySet {
+	ks, err := NewKeySet(signing)
+	assert.NoError(t, err)
+	return ks
+}
@@ -0,0 +1,281 @@
+package jwt_manager
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrKeyNotFound          = errors.New("jwt key not found")
+	ErrNoSigningKey         = errors.New("jwt signing key not set")
+	ErrUnsupportedAlgorithm = errors.New("unsupported jwt signing algorithm")
+	ErrInvalidKey           = errors.New("invalid jwt key")
+)
+
+// 支持的签名算法
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Key 签名/验签密钥
+type Key struct {
+	Kid        string            // 密钥 Id，写入 JWT 头部的 kid 字段
+	Method     jwt.SigningMethod // 签名算法
+	PrivateKey crypto.PrivateKey // 签名密钥，只有当前签名密钥需要；HMAC 时为 []byte
+	PublicKey  crypto.PublicKey  // 验签密钥；HMAC 时为 []byte
+}
+
+// KeySet 密钥集合，同一时间只有一个签名密钥，但可以有多个验签密钥，用于密钥轮换
+type KeySet struct {
+	mu      sync.RWMutex
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeySet 创建密钥集合，签名密钥自动加入验签集合
+func NewKeySet(signing *Key, verification ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for _, k := range verification {
+		if err := ks.Add(k); err != nil {
+			return nil, err
+		}
+	}
+	if signing != nil {
+		if err := ks.Rotate(signing); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Add 添加一个验签密钥
+func (ks *KeySet) Add(k *Key) error {
+	if k == nil || k.Method == nil || k.PublicKey == nil {
+		return ErrInvalidKey
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[k.Kid] = k
+	return nil
+}
+
+// Rotate 切换签名密钥，旧签名密钥保留在验签集合中，直到调用 Remove
+func (ks *KeySet) Rotate(signing *Key) error {
+	if signing == nil || signing.PrivateKey == nil {
+		return ErrInvalidKey
+	}
+	if err := ks.Add(signing); err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.signing = signing
+	return nil
+}
+
+// Remove 移除一个验签密钥，不能移除当前签名密钥
+func (ks *KeySet) Remove(kid string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.signing != nil && ks.signing.Kid == kid {
+		return
+	}
+	delete(ks.keys, kid)
+}
+
+// SigningKey 获取当前签名密钥
+func (ks *KeySet) SigningKey() (*Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if ks.signing == nil {
+		return nil, ErrNoSigningKey
+	}
+	return ks.signing, nil
+}
+
+// Lookup 根据 kid 查找验签密钥
+func (ks *KeySet) Lookup(kid string) (*Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return k, nil
+}
+
+// Len 验签密钥数量
+func (ks *KeySet) Len() int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return len(ks.keys)
+}
+
+// SigningMethod 根据算法名称获取签名算法
+func SigningMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgorithmHS256, "":
+		return jwt.SigningMethodHS256, nil
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// NewHMACKey 创建 HMAC 密钥，签名和验签使用同一个密钥
+func NewHMACKey(kid string, secret []byte) *Key {
+	return &Key{
+		Kid:        kid,
+		Method:     jwt.SigningMethodHS256,
+		PrivateKey: secret,
+		PublicKey:  secret,
+	}
+}
+
+// KeyFileConfig 密钥文件配置
+type KeyFileConfig struct {
+	Kid            string `mapstructure:"kid"`              // 密钥 Id
+	Algorithm      string `mapstructure:"algorithm"`        // 签名算法 RS256/EdDSA，为空时使用 KeySetConfig.Algorithm
+	PrivateKeyFile string `mapstructure:"private_key_file"` // PEM 格式私钥文件，签名密钥必填
+	PublicKeyFile  string `mapstructure:"public_key_file"`  // PEM 格式公钥文件，只用于验签的旧密钥必填
+}
+
+// KeySetConfig 密钥集合配置
+type KeySetConfig struct {
+	Algorithm        string          `mapstructure:"algorithm"`         // 签名算法 HS256/RS256/EdDSA
+	SigningKey       KeyFileConfig   `mapstructure:"signing_key"`       // 当前签名密钥
+	VerificationKeys []KeyFileConfig `mapstructure:"verification_keys"` // 轮换期内仍然有效的旧验签密钥
+}
+
+// LoadKeySet 根据配置加载密钥集合，HS256 时使用 hmacSecret 作为密钥
+func LoadKeySet(cfg KeySetConfig, hmacSecret []byte) (*KeySet, error) {
+	if cfg.Algorithm == "" || cfg.Algorithm == AlgorithmHS256 {
+		kid := cfg.SigningKey.Kid
+		return NewKeySet(NewHMACKey(kid, hmacSecret))
+	}
+
+	signing, err := loadKeyFile(cfg.SigningKey, cfg.Algorithm, true)
+	if err != nil {
+		return nil, fmt.Errorf("load jwt signing key: %w", err)
+	}
+
+	var verification []*Key
+	for _, kc := range cfg.VerificationKeys {
+		k, err := loadKeyFile(kc, cfg.Algorithm, false)
+		if err != nil {
+			return nil, fmt.Errorf("load jwt verification key %s: %w", kc.Kid, err)
+		}
+		verification = append(verification, k)
+	}
+	return NewKeySet(signing, verification...)
+}
+
+func loadKeyFile(kc KeyFileConfig, defaultAlgorithm string, signing bool) (*Key, error) {
+	algorithm := kc.Algorithm
+	if algorithm == "" {
+		algorithm = defaultAlgorithm
+	}
+	method, err := SigningMethod(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if method == jwt.SigningMethodHS256 {
+		return nil, fmt.Errorf("%w: HS256 keys can not be loaded from pem files", ErrInvalidKey)
+	}
+
+	k := &Key{Kid: kc.Kid, Method: method}
+	if kc.PrivateKeyFile != "" {
+		data, err := os.ReadFile(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%w: private key %T can not sign", ErrInvalidKey, priv)
+		}
+		k.PrivateKey = priv
+		k.PublicKey = signer.Public()
+	} else if signing {
+		return nil, fmt.Errorf("%w: private key file is required", ErrInvalidKey)
+	}
+
+	if k.PublicKey == nil {
+		data, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		k.PublicKey = pub
+	}
+
+	if err := checkKeyType(k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// 检查密钥类型与签名算法是否匹配
+func checkKeyType(k *Key) error {
+	switch k.Method {
+	case jwt.SigningMethodRS256:
+		if _, ok := k.PublicKey.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("%w: %s requires an rsa key", ErrInvalidKey, k.Method.Alg())
+		}
+	case jwt.SigningMethodEdDSA:
+		if _, ok := k.PublicKey.(ed25519.PublicKey); !ok {
+			return fmt.Errorf("%w: %s requires an ed25519 key", ErrInvalidKey, k.Method.Alg())
+		}
+	}
+	return nil
+}
+
+// ParsePrivateKeyPEM 解析 PEM 格式私钥，支持 PKCS#8 和 PKCS#1(RSA)
+func ParsePrivateKeyPEM(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no pem block found", ErrInvalidKey)
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported private key format", ErrInvalidKey)
+}
+
+// ParsePublicKeyPEM 解析 PEM 格式公钥，支持 PKIX 和 PKCS#1(RSA)
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no pem block found", ErrInvalidKey)
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported public key format", ErrInvalidKey)
+}
@@ -6,4 +6,5 @@ type Registry interface {
 	DiscoverServicesByType(serviceType string) ([]*ServiceInfo, error)
 	GetAllServices() ([]*ServiceInfo, error)
 	DeregisterService(serviceType, serviceId string) error
+	Heartbeat(serviceType, serviceId string, report *HeartbeatReport) error // 刷新心跳时间，report 不为空时同时更新服务状态与负载
 }
@@ -23,7 +23,9 @@ type ServiceInfo struct {
 	Weight         int               `json:"weight"`                                // 权重
 	Enable         bool              `json:"enable"`                                // 是否启用
 	Idc            string            `json:"idc"`                                   // 机房
-	Status         string            `json:"status"`                                // 服务状态 "offline" "online" "active" "inactive"
+	Status         string            `json:"status"`                                // 服务状态 "offline" "online" "active" "inactive" "draining"
+	Load           int               `json:"load"`                                  // 当前负载，gate 为连接数，由心跳上报
+	MaxLoad        int               `json:"max_load"`                              // 最大负载，0 表示不限制，由心跳上报
 	LastHeartbeat  int64             `json:"last_heartbeat" redis:"last_heartbeat"` // 最后心跳时间
 	TTLSeconds     int64             `json:"ttl_seconds" redis:"ttl_seconds"`       // 心跳超时允许时间
 }
@@ -39,12 +41,44 @@ type HTTPAddress struct {
 }
 
 // metadata 中存储的内容：
-// MaxLoad       int    `json:"max_load"`       // 最大负载
-// Load          int    `json:"load"`           // 当前负载
 // UpdatedAt     int64  `json:"updated_at"`     // 最后更新时间
 // Version       string `json:"version"`        // 服务版本
 
+// HeartbeatReport 心跳上报的状态与负载
+type HeartbeatReport struct {
+	Status  string // 服务状态，为空时不修改
+	Load    int    // 当前负载
+	MaxLoad int    // 最大负载，0 表示不限制
+}
+
+// Apply 将心跳上报的内容写入服务信息
+func (r *HeartbeatReport) Apply(s *ServiceInfo) {
+	if r.Status != "" {
+		s.Status = r.Status
+	}
+	s.Load, s.MaxLoad = r.Load, r.MaxLoad
+}
+
 // IsExpired 检查服务是否已过期
 func (s *ServiceInfo) IsExpired() bool { return s.LastHeartbeat+s.TTLSeconds < GetCurrentTimestamp() }
 
+// IsAvailable 服务是否可以分配给客户端，排空中或下线的服务不参与服务发现
+func (s *ServiceInfo) IsAvailable() bool { return s.Status == "online" || s.Status == "active" }
+
+// LoadRatio 负载率，没有最大负载的服务为 0
+func (s *ServiceInfo) LoadRatio() float64 {
+	if s.MaxLoad <= 0 {
+		return 0
+	}
+	return float64(s.Load) / float64(s.MaxLoad)
+}
+
+// LessLoaded 负载率低的服务更空闲，负载率相同时比较负载
+func (s *ServiceInfo) LessLoaded(other *ServiceInfo) bool {
+	if a, b := s.LoadRatio(), other.LoadRatio(); a != b {
+		return a < b
+	}
+	return s.Load < other.Load
+}
+
 func GetCurrentTimestamp() int64 { return time.Now().Unix() }
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/mxxmstar/learning/pkg/store/redis"
+)
+
+// LockoutConfig 失败锁定配置
+type LockoutConfig struct {
+	MaxFailures   int           `mapstructure:"max_failures"`   // 窗口内允许的最大失败次数，达到后锁定
+	FailureWindow time.Duration `mapstructure:"failure_window"` // 失败计数窗口
+	BaseDuration  time.Duration `mapstructure:"base_duration"`  // 首次锁定时长，之后每次翻倍
+	MaxDuration   time.Duration `mapstructure:"max_duration"`   // 最长锁定时长
+	ResetAfter    time.Duration `mapstructure:"reset_after"`    // 锁定次数的保留时间，超过后退避重新从 BaseDuration 开始
+}
+
+// recordFailureScript 记录一次失败，达到阈值时按指数退避锁定
+// KEYS[1] 失败计数 key KEYS[2] 锁定 key KEYS[3] 锁定次数 key
+// ARGV[1] 当前时间(ms) ARGV[2] 失败窗口(ms) ARGV[3] 最大失败次数 ARGV[4] 首次锁定时长(ms)
+// ARGV[5] 最长锁定时长(ms) ARGV[6] 锁定次数保留时间(ms) ARGV[7] 本次失败的唯一成员
+// 返回本次触发的锁定时长(ms)，未触发锁定返回 0
+const recordFailureScript = `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local maxFailures = tonumber(ARGV[3])
+local base = tonumber(ARGV[4])
+local maxLock = tonumber(ARGV[5])
+local resetAfter = tonumber(ARGV[6])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], 0, now - window)
+redis.call("ZADD", KEYS[1], now, ARGV[7])
+redis.call("PEXPIRE", KEYS[1], window)
+if redis.call("ZCARD", KEYS[1]) < maxFailures then
+	return 0
+end
+
+local n = redis.call("INCR", KEYS[3])
+redis.call("PEXPIRE", KEYS[3], resetAfter)
+local duration = base * math.pow(2, n - 1)
+if duration > maxLock then
+	duration = maxLock
+end
+redis.call("SET", KEYS[2], n, "PX", duration)
+redis.call("DEL", KEYS[1])
+return duration
+`
+
+// Lockout 基于 Redis 的失败锁定器，连续失败达到阈值后按指数退避临时锁定
+type Lockout struct {
+	client *redis.RedisClient
+	prefix string
+	config LockoutConfig
+}
+
+func NewLockout(client *redis.RedisClient, prefix string, config LockoutConfig) *Lockout {
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = 5
+	}
+	if config.FailureWindow <= 0 {
+		config.FailureWindow = 15 * time.Minute
+	}
+	if config.BaseDuration <= 0 {
+		config.BaseDuration = time.Minute
+	}
+	if config.MaxDuration < config.BaseDuration {
+		config.MaxDuration = config.BaseDuration
+	}
+	if config.ResetAfter <= 0 {
+		config.ResetAfter = 24 * time.Hour
+	}
+	return &Lockout{
+		client: client,
+		prefix: prefix,
+		config: config,
+	}
+}
+
+func (l *Lockout) failuresKey(key string) string  { return l.prefix + "failures:" + key }
+func (l *Lockout) lockKey(key string) string      { return l.prefix + "lock:" + key }
+func (l *Lockout) lockCountKey(key string) string { return l.prefix + "lock_count:" + key }
+
+// Locked 判断是否处于锁定状态，返回剩余锁定时间
+func (l *Lockout) Locked(ctx context.Context, key string) (bool, time.Duration, error) {
+	ttl, err := l.client.PTTL(ctx, l.lockKey(key))
+	if err != nil {
+		return false, 0, err
+	}
+	// key 不存在返回 -2，没有过期时间返回 -1
+	if ttl <= 0 {
+		return false, 0, nil
+	}
+	return true, ttl, nil
+}
+
+// RecordFailure 记录一次失败，触发锁定时返回锁定时长
+func (l *Lockout) RecordFailure(ctx context.Context, key string) (time.Duration, error) {
+	member, err := newMember()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := l.client.Eval(ctx, recordFailureScript,
+		[]string{l.failuresKey(key), l.lockKey(key), l.lockCountKey(key)},
+		time.Now().UnixMilli(),
+		l.config.FailureWindow.Milliseconds(),
+		l.config.MaxFailures,
+		l.config.BaseDuration.Milliseconds(),
+		l.config.MaxDuration.Milliseconds(),
+		l.config.ResetAfter.Milliseconds(),
+		member,
+	).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(res) * time.Millisecond, nil
+}
+
+// Reset 成功后清空失败计数和退避次数
+func (l *Lockout) Reset(ctx context.Context, key string) error {
+	return l.client.Del(ctx, l.failuresKey(key), l.lockCountKey(key))
+}
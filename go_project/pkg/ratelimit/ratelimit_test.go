@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, *redis.RedisClient) {
+	mr := miniredis.RunT(t)
+	return mr, redis.NewRedisClient(mr.Addr(), "", 0)
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	_, client := newTestClient(t)
+	l := NewSlidingWindowLimiter(client, "login:ip:", 3, 200*time.Millisecond)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		ok, _, err := l.Allow(ctx, "1.1.1.1")
+		require.NoError(t, err)
+		assert.True(t, ok, "窗口内未达到上限的请求应该允许")
+	}
+	ok, retry, err := l.Allow(ctx, "1.1.1.1")
+	require.NoError(t, err)
+	assert.False(t, ok, "超过上限的请求应该拒绝")
+	assert.True(t, retry > 0 && retry <= 200*time.Millisecond, "等待时间应该不超过窗口大小: %s", retry)
+
+	ok, _, err = l.Allow(ctx, "2.2.2.2")
+	require.NoError(t, err)
+	assert.True(t, ok, "不同 key 分别计数")
+
+	// 窗口滑过后恢复
+	time.Sleep(220 * time.Millisecond)
+	ok, _, err = l.Allow(ctx, "1.1.1.1")
+	require.NoError(t, err)
+	assert.True(t, ok, "窗口滑过后应该允许")
+
+	require.NoError(t, l.Reset(ctx, "1.1.1.1"))
+	unlimited := NewSlidingWindowLimiter(client, "x:", 0, time.Second)
+	ok, _, err = unlimited.Allow(ctx, "any")
+	require.NoError(t, err)
+	assert.True(t, ok, "limit 为 0 时不限流")
+}
+
+func TestLockoutBackoff(t *testing.T) {
+	mr, client := newTestClient(t)
+	l := NewLockout(client, "login:", LockoutConfig{
+		MaxFailures:   2,
+		FailureWindow: time.Minute,
+		BaseDuration:  time.Second,
+		MaxDuration:   3 * time.Second,
+		ResetAfter:    time.Hour,
+	})
+	ctx := context.Background()
+
+	d, err := l.RecordFailure(ctx, "a@b.c")
+	require.NoError(t, err)
+	assert.Zero(t, d, "未达到阈值时不锁定")
+	locked, _, err := l.Locked(ctx, "a@b.c")
+	require.NoError(t, err)
+	assert.False(t, locked)
+
+	// 每次锁定时长翻倍，不超过最长锁定时长
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if want != time.Second {
+			_, err = l.RecordFailure(ctx, "a@b.c")
+			require.NoError(t, err)
+		}
+		d, err = l.RecordFailure(ctx, "a@b.c")
+		require.NoError(t, err)
+		assert.Equal(t, want, d)
+
+		locked, ttl, err := l.Locked(ctx, "a@b.c")
+		require.NoError(t, err)
+		assert.True(t, locked)
+		assert.Equal(t, want, ttl)
+	}
+
+	mr.FastForward(3 * time.Second)
+	locked, _, err = l.Locked(ctx, "a@b.c")
+	require.NoError(t, err)
+	assert.False(t, locked, "锁定到期后解除")
+
+	// 成功后重置退避
+	require.NoError(t, l.Reset(ctx, "a@b.c"))
+	_, err = l.RecordFailure(ctx, "a@b.c")
+	require.NoError(t, err)
+	d, err = l.RecordFailure(ctx, "a@b.c")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, d, "重置后重新从首次锁定时长开始")
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/mxxmstar/learning/pkg/store/redis"
+)
+
+var ErrUnexpectedScriptResult = errors.New("unexpected lua script result")
+
+// slidingWindowScript 基于有序集合的滑动窗口限流
+// KEYS[1] 计数 key
+// ARGV[1] 当前时间(ms) ARGV[2] 窗口大小(ms) ARGV[3] 窗口内允许的次数 ARGV[4] 本次请求的唯一成员
+// 返回 {是否允许(1/0), 需要等待的时间(ms)}
+const slidingWindowScript = `
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", key, 0, now - window)
+local count = redis.call("ZCARD", key)
+if count >= limit then
+	local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+	local retry = window
+	if oldest[2] then
+		retry = tonumber(oldest[2]) + window - now
+	end
+	return {0, retry}
+end
+
+redis.call("ZADD", key, now, ARGV[4])
+redis.call("PEXPIRE", key, window)
+return {1, 0}
+`
+
+// SlidingWindowLimiter 基于 Redis 的滑动窗口限流器，多实例共享同一份计数
+type SlidingWindowLimiter struct {
+	client *redis.RedisClient
+	prefix string        // key 前缀
+	limit  int           // 窗口内允许的次数
+	window time.Duration // 窗口大小
+}
+
+func NewSlidingWindowLimiter(client *redis.RedisClient, prefix string, limit int, window time.Duration) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{
+		client: client,
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// Allow 记录一次请求并判断是否允许，不允许时返回需要等待的时间
+// limit <= 0 表示不限流
+func (l *SlidingWindowLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	if l.limit <= 0 {
+		return true, 0, nil
+	}
+
+	member, err := newMember()
+	if err != nil {
+		return false, 0, err
+	}
+
+	now := time.Now().UnixMilli()
+	res, err := l.client.Eval(ctx, slidingWindowScript, []string{l.prefix + key},
+		now, l.window.Milliseconds(), l.limit, member).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, ErrUnexpectedScriptResult
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// Reset 清空指定 key 的计数
+func (l *SlidingWindowLimiter) Reset(ctx context.Context, key string) error {
+	return l.client.Del(ctx, l.prefix+key)
+}
+
+// 生成有序集合成员，避免同一毫秒内的请求互相覆盖
+func newMember() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
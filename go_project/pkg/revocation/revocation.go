@@ -0,0 +1,166 @@
+package revocation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mxxmstar/learning/pkg/logger"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+)
+
+const (
+	// Channel 吊销事件的发布订阅频道，gate 订阅后关闭受影响的连接
+	Channel = "auth:revocation"
+
+	tokenKeyPrefix = "revoked_jwt:"            // 单个 jwt 的吊销记录，随令牌过期
+	userKeyPrefix  = "revoked_user_before_ms:" // 用户在某时间之前签发的凭证全部失效，值为 unix 毫秒
+)
+
+// EventType 吊销事件类型
+type EventType string
+
+const (
+	EventToken   EventType = "token"   // 吊销单个 jwt
+	EventSession EventType = "session" // 吊销单个 session
+	EventUser    EventType = "user"    // 吊销用户在某时间之前签发的所有凭证
+)
+
+// Event 吊销事件
+type Event struct {
+	Type      EventType `json:"type"`
+	UserId    uint64    `json:"user_id"`
+	TokenId   string    `json:"token_id,omitempty"`   // EventToken 时为 jwt 的 jti
+	SessionId string    `json:"session_id,omitempty"` // EventSession 时为 session Id
+	Before    int64     `json:"before_ms,omitempty"`  // EventUser 时为截止时间，unix 毫秒
+}
+
+// Store 基于 Redis 的凭证吊销列表
+type Store struct {
+	client *redis.RedisClient
+	// 用户级吊销记录的保留时间，不能小于访问令牌的有效期，否则吊销前签发的令牌会重新生效
+	userTTL time.Duration
+}
+
+func NewStore(client *redis.RedisClient, maxTokenLifetime time.Duration) *Store {
+	if maxTokenLifetime <= 0 {
+		maxTokenLifetime = 24 * time.Hour
+	}
+	return &Store{
+		client:  client,
+		userTTL: maxTokenLifetime,
+	}
+}
+
+// RevokeToken 吊销单个 jwt，记录在令牌过期后自动删除
+func (s *Store) RevokeToken(ctx context.Context, userId uint64, tokenId string, expiresAt time.Time) error {
+	if tokenId == "" {
+		return fmt.Errorf("revoke token: empty token id")
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// 令牌已过期，无需记录
+		return nil
+	}
+	if err := s.client.Set(ctx, tokenKeyPrefix+tokenId, strconv.FormatUint(userId, 10), ttl); err != nil {
+		return err
+	}
+	return s.publish(ctx, Event{Type: EventToken, UserId: userId, TokenId: tokenId})
+}
+
+// RevokeSession 通知 gate 关闭使用该 session 认证的连接，session 本身由调用方删除
+func (s *Store) RevokeSession(ctx context.Context, userId uint64, sessionId string) error {
+	return s.publish(ctx, Event{Type: EventSession, UserId: userId, SessionId: sessionId})
+}
+
+// RevokeUserBefore 吊销用户在 before 之前（含同一毫秒）签发的所有 jwt，用于修改密码、封禁等场景
+// 按毫秒比较，吊销后同一秒内重新登录签发的令牌不受影响
+func (s *Store) RevokeUserBefore(ctx context.Context, userId uint64, before time.Time) error {
+	if err := s.client.Set(ctx, userKey(userId), before.UnixMilli(), s.userTTL); err != nil {
+		return err
+	}
+	return s.publish(ctx, Event{Type: EventUser, UserId: userId, Before: before.UnixMilli()})
+}
+
+// IsRevoked 判断 jwt 是否已被吊销
+func (s *Store) IsRevoked(ctx context.Context, userId uint64, tokenId string, issuedAt time.Time) (bool, error) {
+	values, err := s.client.MGet(ctx, tokenKeyPrefix+tokenId, userKey(userId)).Result()
+	if err != nil {
+		return false, err
+	}
+	if tokenId != "" && values[0] != nil {
+		return true, nil
+	}
+	if v, ok := values[1].(string); ok {
+		before, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		if issuedBefore(issuedAt, before) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Subscribe 订阅吊销事件，阻塞直到 ctx 结束
+func (s *Store) Subscribe(ctx context.Context, handler func(Event)) error {
+	pubsub := s.client.Subscribe(ctx, Channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				logger.FormatLog(ctx, "error", fmt.Sprintf("[revocation] invalid event: %v", err))
+				continue
+			}
+			handler(event)
+		}
+	}
+}
+
+func (s *Store) publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, Channel, data)
+}
+
+func userKey(userId uint64) string {
+	return userKeyPrefix + strconv.FormatUint(userId, 10)
+}
+
+// Matches 判断使用该凭证认证的连接是否受本事件影响
+// issuedAt 为 jwt 签发时间，session 认证时为认证时间
+func (e Event) Matches(tokenId, sessionId string, issuedAt time.Time) bool {
+	switch e.Type {
+	case EventToken:
+		return tokenId != "" && tokenId == e.TokenId
+	case EventSession:
+		return sessionId != "" && sessionId == e.SessionId
+	case EventUser:
+		return issuedBefore(issuedAt, e.Before)
+	default:
+		return false
+	}
+}
+
+// issuedBefore 凭证是否在截止时间之前（含同一毫秒）签发，before 为 unix 毫秒
+func issuedBefore(issuedAt time.Time, before int64) bool {
+	return issuedAt.UnixMilli() <= before
+}
@@ -0,0 +1,54 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventMatches(t *testing.T) {
+	now := time.Now()
+
+	tokenEvent := Event{Type: EventToken, UserId: 1, TokenId: "jti-1"}
+	assert.True(t, tokenEvent.Matches("jti-1", "", now), "相同 jti 的连接应该被关闭")
+	assert.False(t, tokenEvent.Matches("jti-2", "", now), "其他 jti 的连接不应该被关闭")
+	assert.False(t, tokenEvent.Matches("", "session-1", now), "session 认证的连接不应该被关闭")
+
+	sessionEvent := Event{Type: EventSession, UserId: 1, SessionId: "session-1"}
+	assert.True(t, sessionEvent.Matches("", "session-1", now), "相同 session 的连接应该被关闭")
+	assert.False(t, sessionEvent.Matches("jti-1", "", now), "jwt 认证的连接不应该被关闭")
+
+	userEvent := Event{Type: EventUser, UserId: 1, Before: now.UnixMilli()}
+	assert.True(t, userEvent.Matches("jti-1", "", now.Add(-time.Hour)), "截止时间之前签发的凭证应该失效")
+	assert.True(t, userEvent.Matches("", "session-1", now), "截止时间签发的凭证应该失效")
+	assert.False(t, userEvent.Matches("jti-2", "", now.Add(time.Second)), "截止时间之后签发的凭证不应该失效")
+}
+
+func TestRevokeUserSameSecond(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewStore(redis.NewRedisClient(mr.Addr(), "", 0), time.Hour)
+	ctx := context.Background()
+
+	// 吊销发生在某一秒的开头，之后同一秒内重新登录签发的令牌不应该失效
+	revokedAt := time.Now().Truncate(time.Second).Add(100 * time.Millisecond)
+	assert.NoError(t, store.RevokeUserBefore(ctx, 1, revokedAt))
+
+	revoked, err := store.IsRevoked(ctx, 1, "jti-old", revokedAt.Add(-50*time.Millisecond))
+	assert.NoError(t, err)
+	assert.True(t, revoked, "吊销之前同一秒签发的令牌应该失效")
+
+	revoked, err = store.IsRevoked(ctx, 1, "jti-new", revokedAt.Add(500*time.Millisecond))
+	assert.NoError(t, err)
+	assert.False(t, revoked, "吊销之后同一秒签发的令牌不应该失效")
+
+	revoked, err = store.IsRevoked(ctx, 2, "jti-other", revokedAt.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, revoked, "其他用户的令牌不受影响")
+
+	event := Event{Type: EventUser, UserId: 1, Before: revokedAt.UnixMilli()}
+	assert.False(t, event.Matches("jti-new", "", revokedAt.Add(500*time.Millisecond)), "吊销之后同一秒认证的连接不应该被关闭")
+}
@@ -3,31 +3,72 @@ package session
 
 import (
 	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"time"
 )
 
-// TCPConnection TCP连接适配器
+const (
+	frameHeaderSize     = 4           // 长度前缀，大端 uint32
+	DefaultMaxFrameSize = 1024 * 1024 // 默认最大帧长度 1M
+)
+
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// ReadFrame 读取一帧 4 字节大端长度前缀的消息，maxSize 不大于 0 时使用默认值
+func ReadFrame(r io.Reader, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if uint64(size) > uint64(maxSize) {
+		return nil, fmt.Errorf("%w: %d > %d", ErrFrameTooLarge, size, maxSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteFrame 写入一帧 4 字节大端长度前缀的消息，长度前缀与消息体一次写入
+func WriteFrame(w io.Writer, data []byte) error {
+	if uint64(len(data)) > uint64(^uint32(0)) {
+		return fmt.Errorf("%w: %d", ErrFrameTooLarge, len(data))
+	}
+	buf := make([]byte, frameHeaderSize+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[frameHeaderSize:], data)
+	_, err := w.Write(buf)
+	return err
+}
+
+// TCPConnection TCP连接适配器，消息使用 4 字节长度前缀分帧
 type TCPConnection struct {
-	Conn   *net.TCPConn
-	reader *bufio.Reader
+	Conn         net.Conn
+	MaxFrameSize int // 最大帧长度，不大于 0 时使用 DefaultMaxFrameSize
+	reader       *bufio.Reader
 }
 
 func (tcp *TCPConnection) ReadMessage() ([]byte, error) {
 	if tcp.reader == nil {
 		tcp.reader = bufio.NewReader(tcp.Conn)
 	}
-
-	// 这里假设使用简单的长度前缀协议
-	// 实际应用中可能需要根据具体协议实现
-	// 例如读取4字节长度，再读取对应长度的数据
-	// 此处仅为示例
-	return tcp.reader.ReadBytes('\n')
+	return ReadFrame(tcp.reader, tcp.MaxFrameSize)
 }
 
 func (tcp *TCPConnection) WriteMessage(data []byte) error {
-	_, err := tcp.Conn.Write(data)
-	return err
+	return WriteFrame(tcp.Conn, data)
 }
 
 func (tcp *TCPConnection) Close() error {
@@ -47,6 +88,6 @@ func (tcp *TCPConnection) SetWriteDeadline(t time.Time) error {
 }
 
 // NewTCPConnection 创建TCP连接适配器
-func NewTCPConnection(conn *net.TCPConn) *TCPConnection {
+func NewTCPConnection(conn net.Conn) *TCPConnection {
 	return &TCPConnection{Conn: conn}
 }
@@ -53,6 +53,11 @@ func (rc *RedisClient) Expire(ctx context.Context, key string, expiration time.D
 	return rc.client.Expire(ctx, key, expiration).Err()
 }
 
+// PTTL 获取键的剩余过期时间（毫秒精度）
+func (rc *RedisClient) PTTL(ctx context.Context, key string) (time.Duration, error) {
+	return rc.client.PTTL(ctx, key).Result()
+}
+
 // Eval 执行 Lua 脚本
 func (rc *RedisClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
 	return rc.client.Eval(ctx, script, keys, args...)
@@ -88,6 +93,21 @@ func (rc *RedisClient) HSet(ctx context.Context, key, field string, value interf
 	return rc.client.HSet(ctx, key, field, value)
 }
 
+// MGet 批量获取键的值，不存在的键对应 nil
+func (rc *RedisClient) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	return rc.client.MGet(ctx, keys...)
+}
+
+// Publish 发布消息到频道
+func (rc *RedisClient) Publish(ctx context.Context, channel string, message interface{}) error {
+	return rc.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe 订阅频道，调用方负责关闭返回的 PubSub
+func (rc *RedisClient) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return rc.client.Subscribe(ctx, channels...)
+}
+
 // ---------- 分布式锁 ----------
 type DistributedLock struct {
 	client   *RedisClient
@@ -0,0 +1,970 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.2
+// source: gate.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// 单个连接的投递结果
+type DeliveryStatus int32
+
+const (
+	DeliveryStatus_DELIVERY_STATUS_UNKNOWN     DeliveryStatus = 0
+	DeliveryStatus_DELIVERY_STATUS_DELIVERED   DeliveryStatus = 1 // 已写入连接的发送队列
+	DeliveryStatus_DELIVERY_STATUS_NOT_FOUND   DeliveryStatus = 2 // 连接不在该 gate 上
+	DeliveryStatus_DELIVERY_STATUS_CLOSED      DeliveryStatus = 3 // 连接已关闭
+	DeliveryStatus_DELIVERY_STATUS_UNREACHABLE DeliveryStatus = 4 // 连接所在的 gate 无法访问
+	DeliveryStatus_DELIVERY_STATUS_QUEUE_FULL  DeliveryStatus = 5 // 连接的发送队列已满，消息按溢出策略处理
+)
+
+// Enum value maps for DeliveryStatus.
+var (
+	DeliveryStatus_name = map[int32]string{
+		0: "DELIVERY_STATUS_UNKNOWN",
+		1: "DELIVERY_STATUS_DELIVERED",
+		2: "DELIVERY_STATUS_NOT_FOUND",
+		3: "DELIVERY_STATUS_CLOSED",
+		4: "DELIVERY_STATUS_UNREACHABLE",
+		5: "DELIVERY_STATUS_QUEUE_FULL",
+	}
+	DeliveryStatus_value = map[string]int32{
+		"DELIVERY_STATUS_UNKNOWN":     0,
+		"DELIVERY_STATUS_DELIVERED":   1,
+		"DELIVERY_STATUS_NOT_FOUND":   2,
+		"DELIVERY_STATUS_CLOSED":      3,
+		"DELIVERY_STATUS_UNREACHABLE": 4,
+		"DELIVERY_STATUS_QUEUE_FULL":  5,
+	}
+)
+
+func (x DeliveryStatus) Enum() *DeliveryStatus {
+	p := new(DeliveryStatus)
+	*p = x
+	return p
+}
+
+func (x DeliveryStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeliveryStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_gate_proto_enumTypes[0].Descriptor()
+}
+
+func (DeliveryStatus) Type() protoreflect.EnumType {
+	return &file_gate_proto_enumTypes[0]
+}
+
+func (x DeliveryStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeliveryStatus.Descriptor instead.
+func (DeliveryStatus) EnumDescriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{0}
+}
+
+type DeliverRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConnIds       []string               `protobuf:"bytes,1,rep,name=conn_ids,json=connIds,proto3" json:"conn_ids,omitempty"`         // 目标连接 Id
+	UserIds       []uint64               `protobuf:"varint,2,rep,packed,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"` // 目标用户，投递到用户在该 gate 上的所有连接
+	Payload       []byte                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`                        // 原样写入连接的消息
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliverRequest) Reset() {
+	*x = DeliverRequest{}
+	mi := &file_gate_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliverRequest) ProtoMessage() {}
+
+func (x *DeliverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliverRequest.ProtoReflect.Descriptor instead.
+func (*DeliverRequest) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DeliverRequest) GetConnIds() []string {
+	if x != nil {
+		return x.ConnIds
+	}
+	return nil
+}
+
+func (x *DeliverRequest) GetUserIds() []uint64 {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+func (x *DeliverRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type DeliveryResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConnId        string                 `protobuf:"bytes,1,opt,name=conn_id,json=connId,proto3" json:"conn_id,omitempty"`
+	UserId        uint64                 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GateId        string                 `protobuf:"bytes,3,opt,name=gate_id,json=gateId,proto3" json:"gate_id,omitempty"`
+	Status        DeliveryStatus         `protobuf:"varint,4,opt,name=status,proto3,enum=gate.DeliveryStatus" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliveryResult) Reset() {
+	*x = DeliveryResult{}
+	mi := &file_gate_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliveryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliveryResult) ProtoMessage() {}
+
+func (x *DeliveryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliveryResult.ProtoReflect.Descriptor instead.
+func (*DeliveryResult) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DeliveryResult) GetConnId() string {
+	if x != nil {
+		return x.ConnId
+	}
+	return ""
+}
+
+func (x *DeliveryResult) GetUserId() uint64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *DeliveryResult) GetGateId() string {
+	if x != nil {
+		return x.GateId
+	}
+	return ""
+}
+
+func (x *DeliveryResult) GetStatus() DeliveryStatus {
+	if x != nil {
+		return x.Status
+	}
+	return DeliveryStatus_DELIVERY_STATUS_UNKNOWN
+}
+
+func (x *DeliveryResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type DeliverResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*DeliveryResult      `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliverResponse) Reset() {
+	*x = DeliverResponse{}
+	mi := &file_gate_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliverResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliverResponse) ProtoMessage() {}
+
+func (x *DeliverResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliverResponse.ProtoReflect.Descriptor instead.
+func (*DeliverResponse) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DeliverResponse) GetResults() []*DeliveryResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type PushToUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserIds       []uint64               `protobuf:"varint,1,rep,packed,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushToUserRequest) Reset() {
+	*x = PushToUserRequest{}
+	mi := &file_gate_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushToUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushToUserRequest) ProtoMessage() {}
+
+func (x *PushToUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushToUserRequest.ProtoReflect.Descriptor instead.
+func (*PushToUserRequest) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PushToUserRequest) GetUserIds() []uint64 {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+func (x *PushToUserRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type PushToConnectionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConnIds       []string               `protobuf:"bytes,1,rep,name=conn_ids,json=connIds,proto3" json:"conn_ids,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushToConnectionRequest) Reset() {
+	*x = PushToConnectionRequest{}
+	mi := &file_gate_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushToConnectionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushToConnectionRequest) ProtoMessage() {}
+
+func (x *PushToConnectionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushToConnectionRequest.ProtoReflect.Descriptor instead.
+func (*PushToConnectionRequest) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PushToConnectionRequest) GetConnIds() []string {
+	if x != nil {
+		return x.ConnIds
+	}
+	return nil
+}
+
+func (x *PushToConnectionRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type PushResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*DeliveryResult      `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushResponse) Reset() {
+	*x = PushResponse{}
+	mi := &file_gate_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushResponse) ProtoMessage() {}
+
+func (x *PushResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushResponse.ProtoReflect.Descriptor instead.
+func (*PushResponse) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PushResponse) GetResults() []*DeliveryResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BroadcastRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payload       []byte                 `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BroadcastRequest) Reset() {
+	*x = BroadcastRequest{}
+	mi := &file_gate_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BroadcastRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BroadcastRequest) ProtoMessage() {}
+
+func (x *BroadcastRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BroadcastRequest.ProtoReflect.Descriptor instead.
+func (*BroadcastRequest) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BroadcastRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type BroadcastResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Delivered     int64                  `protobuf:"varint,1,opt,name=delivered,proto3" json:"delivered,omitempty"` // 成功写入发送队列的连接数
+	Failed        int64                  `protobuf:"varint,2,opt,name=failed,proto3" json:"failed,omitempty"`       // 写入失败的连接数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BroadcastResponse) Reset() {
+	*x = BroadcastResponse{}
+	mi := &file_gate_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BroadcastResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BroadcastResponse) ProtoMessage() {}
+
+func (x *BroadcastResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BroadcastResponse.ProtoReflect.Descriptor instead.
+func (*BroadcastResponse) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BroadcastResponse) GetDelivered() int64 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+func (x *BroadcastResponse) GetFailed() int64 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+type KickUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        uint64                 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KickUserRequest) Reset() {
+	*x = KickUserRequest{}
+	mi := &file_gate_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KickUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KickUserRequest) ProtoMessage() {}
+
+func (x *KickUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KickUserRequest.ProtoReflect.Descriptor instead.
+func (*KickUserRequest) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *KickUserRequest) GetUserId() uint64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *KickUserRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type CloseConnectionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConnId        string                 `protobuf:"bytes,1,opt,name=conn_id,json=connId,proto3" json:"conn_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseConnectionRequest) Reset() {
+	*x = CloseConnectionRequest{}
+	mi := &file_gate_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseConnectionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseConnectionRequest) ProtoMessage() {}
+
+func (x *CloseConnectionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseConnectionRequest.ProtoReflect.Descriptor instead.
+func (*CloseConnectionRequest) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CloseConnectionRequest) GetConnId() string {
+	if x != nil {
+		return x.ConnId
+	}
+	return ""
+}
+
+func (x *CloseConnectionRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// 踢人结果，DELIVERED 表示本 gate 已关闭连接或已通知连接所在的 gate
+type KickResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*DeliveryResult      `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KickResponse) Reset() {
+	*x = KickResponse{}
+	mi := &file_gate_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KickResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KickResponse) ProtoMessage() {}
+
+func (x *KickResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KickResponse.ProtoReflect.Descriptor instead.
+func (*KickResponse) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *KickResponse) GetResults() []*DeliveryResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type PublishRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishRequest) Reset() {
+	*x = PublishRequest{}
+	mi := &file_gate_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishRequest) ProtoMessage() {}
+
+func (x *PublishRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishRequest.ProtoReflect.Descriptor instead.
+func (*PublishRequest) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PublishRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type PublishTopicResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Delivered     int64                  `protobuf:"varint,1,opt,name=delivered,proto3" json:"delivered,omitempty"` // 成功写入发送队列的连接数
+	Failed        int64                  `protobuf:"varint,2,opt,name=failed,proto3" json:"failed,omitempty"`       // 写入失败的连接数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishTopicResponse) Reset() {
+	*x = PublishTopicResponse{}
+	mi := &file_gate_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishTopicResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishTopicResponse) ProtoMessage() {}
+
+func (x *PublishTopicResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishTopicResponse.ProtoReflect.Descriptor instead.
+func (*PublishTopicResponse) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PublishTopicResponse) GetDelivered() int64 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+func (x *PublishTopicResponse) GetFailed() int64 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+type PublishResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Delivered        int64                  `protobuf:"varint,1,opt,name=delivered,proto3" json:"delivered,omitempty"`                                      // 所有 gate 上成功写入发送队列的连接数
+	Failed           int64                  `protobuf:"varint,2,opt,name=failed,proto3" json:"failed,omitempty"`                                            // 所有 gate 上写入失败的连接数
+	UnreachableGates []string               `protobuf:"bytes,3,rep,name=unreachable_gates,json=unreachableGates,proto3" json:"unreachable_gates,omitempty"` // 无法访问的 gate，其上的订阅者没有收到消息
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PublishResponse) Reset() {
+	*x = PublishResponse{}
+	mi := &file_gate_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishResponse) ProtoMessage() {}
+
+func (x *PublishResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gate_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishResponse.ProtoReflect.Descriptor instead.
+func (*PublishResponse) Descriptor() ([]byte, []int) {
+	return file_gate_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PublishResponse) GetDelivered() int64 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+func (x *PublishResponse) GetFailed() int64 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *PublishResponse) GetUnreachableGates() []string {
+	if x != nil {
+		return x.UnreachableGates
+	}
+	return nil
+}
+
+var File_gate_proto protoreflect.FileDescriptor
+
+const file_gate_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"gate.proto\x12\x04gate\"`\n" +
+	"\x0eDeliverRequest\x12\x19\n" +
+	"\bconn_ids\x18\x01 \x03(\tR\aconnIds\x12\x19\n" +
+	"\buser_ids\x18\x02 \x03(\x04R\auserIds\x12\x18\n" +
+	"\apayload\x18\x03 \x01(\fR\apayload\"\x9f\x01\n" +
+	"\x0eDeliveryResult\x12\x17\n" +
+	"\aconn_id\x18\x01 \x01(\tR\x06connId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x04R\x06userId\x12\x17\n" +
+	"\agate_id\x18\x03 \x01(\tR\x06gateId\x12,\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x14.gate.DeliveryStatusR\x06status\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"A\n" +
+	"\x0fDeliverResponse\x12.\n" +
+	"\aresults\x18\x01 \x03(\v2\x14.gate.DeliveryResultR\aresults\"H\n" +
+	"\x11PushToUserRequest\x12\x19\n" +
+	"\buser_ids\x18\x01 \x03(\x04R\auserIds\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\fR\apayload\"N\n" +
+	"\x17PushToConnectionRequest\x12\x19\n" +
+	"\bconn_ids\x18\x01 \x03(\tR\aconnIds\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\fR\apayload\">\n" +
+	"\fPushResponse\x12.\n" +
+	"\aresults\x18\x01 \x03(\v2\x14.gate.DeliveryResultR\aresults\",\n" +
+	"\x10BroadcastRequest\x12\x18\n" +
+	"\apayload\x18\x01 \x01(\fR\apayload\"I\n" +
+	"\x11BroadcastResponse\x12\x1c\n" +
+	"\tdelivered\x18\x01 \x01(\x03R\tdelivered\x12\x16\n" +
+	"\x06failed\x18\x02 \x01(\x03R\x06failed\"B\n" +
+	"\x0fKickUserRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x04R\x06userId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"I\n" +
+	"\x16CloseConnectionRequest\x12\x17\n" +
+	"\aconn_id\x18\x01 \x01(\tR\x06connId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\">\n" +
+	"\fKickResponse\x12.\n" +
+	"\aresults\x18\x01 \x03(\v2\x14.gate.DeliveryResultR\aresults\"@\n" +
+	"\x0ePublishRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\fR\apayload\"L\n" +
+	"\x14PublishTopicResponse\x12\x1c\n" +
+	"\tdelivered\x18\x01 \x01(\x03R\tdelivered\x12\x16\n" +
+	"\x06failed\x18\x02 \x01(\x03R\x06failed\"t\n" +
+	"\x0fPublishResponse\x12\x1c\n" +
+	"\tdelivered\x18\x01 \x01(\x03R\tdelivered\x12\x16\n" +
+	"\x06failed\x18\x02 \x01(\x03R\x06failed\x12+\n" +
+	"\x11unreachable_gates\x18\x03 \x03(\tR\x10unreachableGates*\xc8\x01\n" +
+	"\x0eDeliveryStatus\x12\x1b\n" +
+	"\x17DELIVERY_STATUS_UNKNOWN\x10\x00\x12\x1d\n" +
+	"\x19DELIVERY_STATUS_DELIVERED\x10\x01\x12\x1d\n" +
+	"\x19DELIVERY_STATUS_NOT_FOUND\x10\x02\x12\x1a\n" +
+	"\x16DELIVERY_STATUS_CLOSED\x10\x03\x12\x1f\n" +
+	"\x1bDELIVERY_STATUS_UNREACHABLE\x10\x04\x12\x1e\n" +
+	"\x1aDELIVERY_STATUS_QUEUE_FULL\x10\x052\x89\x01\n" +
+	"\tGateRelay\x128\n" +
+	"\aDeliver\x12\x14.gate.DeliverRequest\x1a\x15.gate.DeliverResponse\"\x00\x12B\n" +
+	"\fPublishTopic\x12\x14.gate.PublishRequest\x1a\x1a.gate.PublishTopicResponse\"\x002\x8b\x03\n" +
+	"\x04Push\x12;\n" +
+	"\n" +
+	"PushToUser\x12\x17.gate.PushToUserRequest\x1a\x12.gate.PushResponse\"\x00\x12G\n" +
+	"\x10PushToConnection\x12\x1d.gate.PushToConnectionRequest\x1a\x12.gate.PushResponse\"\x00\x12C\n" +
+	"\x0eBroadcastLocal\x12\x16.gate.BroadcastRequest\x1a\x17.gate.BroadcastResponse\"\x00\x127\n" +
+	"\bKickUser\x12\x15.gate.KickUserRequest\x1a\x12.gate.KickResponse\"\x00\x12E\n" +
+	"\x0fCloseConnection\x12\x1c.gate.CloseConnectionRequest\x1a\x12.gate.KickResponse\"\x00\x128\n" +
+	"\aPublish\x12\x14.gate.PublishRequest\x1a\x15.gate.PublishResponse\"\x00B\tZ\a./protob\x06proto3"
+
+var (
+	file_gate_proto_rawDescOnce sync.Once
+	file_gate_proto_rawDescData []byte
+)
+
+func file_gate_proto_rawDescGZIP() []byte {
+	file_gate_proto_rawDescOnce.Do(func() {
+		file_gate_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gate_proto_rawDesc), len(file_gate_proto_rawDesc)))
+	})
+	return file_gate_proto_rawDescData
+}
+
+var file_gate_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_gate_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_gate_proto_goTypes = []any{
+	(DeliveryStatus)(0),             // 0: gate.DeliveryStatus
+	(*DeliverRequest)(nil),          // 1: gate.DeliverRequest
+	(*DeliveryResult)(nil),          // 2: gate.DeliveryResult
+	(*DeliverResponse)(nil),         // 3: gate.DeliverResponse
+	(*PushToUserRequest)(nil),       // 4: gate.PushToUserRequest
+	(*PushToConnectionRequest)(nil), // 5: gate.PushToConnectionRequest
+	(*PushResponse)(nil),            // 6: gate.PushResponse
+	(*BroadcastRequest)(nil),        // 7: gate.BroadcastRequest
+	(*BroadcastResponse)(nil),       // 8: gate.BroadcastResponse
+	(*KickUserRequest)(nil),         // 9: gate.KickUserRequest
+	(*CloseConnectionRequest)(nil),  // 10: gate.CloseConnectionRequest
+	(*KickResponse)(nil),            // 11: gate.KickResponse
+	(*PublishRequest)(nil),          // 12: gate.PublishRequest
+	(*PublishTopicResponse)(nil),    // 13: gate.PublishTopicResponse
+	(*PublishResponse)(nil),         // 14: gate.PublishResponse
+}
+var file_gate_proto_depIdxs = []int32{
+	0,  // 0: gate.DeliveryResult.status:type_name -> gate.DeliveryStatus
+	2,  // 1: gate.DeliverResponse.results:type_name -> gate.DeliveryResult
+	2,  // 2: gate.PushResponse.results:type_name -> gate.DeliveryResult
+	2,  // 3: gate.KickResponse.results:type_name -> gate.DeliveryResult
+	1,  // 4: gate.GateRelay.Deliver:input_type -> gate.DeliverRequest
+	12, // 5: gate.GateRelay.PublishTopic:input_type -> gate.PublishRequest
+	4,  // 6: gate.Push.PushToUser:input_type -> gate.PushToUserRequest
+	5,  // 7: gate.Push.PushToConnection:input_type -> gate.PushToConnectionRequest
+	7,  // 8: gate.Push.BroadcastLocal:input_type -> gate.BroadcastRequest
+	9,  // 9: gate.Push.KickUser:input_type -> gate.KickUserRequest
+	10, // 10: gate.Push.CloseConnection:input_type -> gate.CloseConnectionRequest
+	12, // 11: gate.Push.Publish:input_type -> gate.PublishRequest
+	3,  // 12: gate.GateRelay.Deliver:output_type -> gate.DeliverResponse
+	13, // 13: gate.GateRelay.PublishTopic:output_type -> gate.PublishTopicResponse
+	6,  // 14: gate.Push.PushToUser:output_type -> gate.PushResponse
+	6,  // 15: gate.Push.PushToConnection:output_type -> gate.PushResponse
+	8,  // 16: gate.Push.BroadcastLocal:output_type -> gate.BroadcastResponse
+	11, // 17: gate.Push.KickUser:output_type -> gate.KickResponse
+	11, // 18: gate.Push.CloseConnection:output_type -> gate.KickResponse
+	14, // 19: gate.Push.Publish:output_type -> gate.PublishResponse
+	12, // [12:20] is the sub-list for method output_type
+	4,  // [4:12] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_gate_proto_init() }
+func file_gate_proto_init() {
+	if File_gate_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gate_proto_rawDesc), len(file_gate_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_gate_proto_goTypes,
+		DependencyIndexes: file_gate_proto_depIdxs,
+		EnumInfos:         file_gate_proto_enumTypes,
+		MessageInfos:      file_gate_proto_msgTypes,
+	}.Build()
+	File_gate_proto = out.File
+	file_gate_proto_goTypes = nil
+	file_gate_proto_depIdxs = nil
+}
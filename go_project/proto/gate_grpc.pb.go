@@ -0,0 +1,477 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             v6.33.2
+// source: gate.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GateRelay_Deliver_FullMethodName      = "/gate.GateRelay/Deliver"
+	GateRelay_PublishTopic_FullMethodName = "/gate.GateRelay/PublishTopic"
+)
+
+// GateRelayClient is the client API for GateRelay service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// gate 之间转发消息的内部服务
+// GateRelay 与 Push 的调用方需要在 metadata x-internal-token 中携带共享令牌，不加密，gate 的 gRPC 端口只能监听内网地址
+type GateRelayClient interface {
+	// 将消息投递到本 gate 上的连接
+	Deliver(ctx context.Context, in *DeliverRequest, opts ...grpc.CallOption) (*DeliverResponse, error)
+	// 将主题消息投递到本 gate 上订阅了主题的连接
+	PublishTopic(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishTopicResponse, error)
+}
+
+type gateRelayClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGateRelayClient(cc grpc.ClientConnInterface) GateRelayClient {
+	return &gateRelayClient{cc}
+}
+
+func (c *gateRelayClient) Deliver(ctx context.Context, in *DeliverRequest, opts ...grpc.CallOption) (*DeliverResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeliverResponse)
+	err := c.cc.Invoke(ctx, GateRelay_Deliver_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gateRelayClient) PublishTopic(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishTopicResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishTopicResponse)
+	err := c.cc.Invoke(ctx, GateRelay_PublishTopic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GateRelayServer is the server API for GateRelay service.
+// All implementations must embed UnimplementedGateRelayServer
+// for forward compatibility.
+//
+// gate 之间转发消息的内部服务
+// GateRelay 与 Push 的调用方需要在 metadata x-internal-token 中携带共享令牌，不加密，gate 的 gRPC 端口只能监听内网地址
+type GateRelayServer interface {
+	// 将消息投递到本 gate 上的连接
+	Deliver(context.Context, *DeliverRequest) (*DeliverResponse, error)
+	// 将主题消息投递到本 gate 上订阅了主题的连接
+	PublishTopic(context.Context, *PublishRequest) (*PublishTopicResponse, error)
+	mustEmbedUnimplementedGateRelayServer()
+}
+
+// UnimplementedGateRelayServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGateRelayServer struct{}
+
+func (UnimplementedGateRelayServer) Deliver(context.Context, *DeliverRequest) (*DeliverResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Deliver not implemented")
+}
+func (UnimplementedGateRelayServer) PublishTopic(context.Context, *PublishRequest) (*PublishTopicResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PublishTopic not implemented")
+}
+func (UnimplementedGateRelayServer) mustEmbedUnimplementedGateRelayServer() {}
+func (UnimplementedGateRelayServer) testEmbeddedByValue()                   {}
+
+// UnsafeGateRelayServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GateRelayServer will
+// result in compilation errors.
+type UnsafeGateRelayServer interface {
+	mustEmbedUnimplementedGateRelayServer()
+}
+
+func RegisterGateRelayServer(s grpc.ServiceRegistrar, srv GateRelayServer) {
+	// If the following call panics, it indicates UnimplementedGateRelayServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GateRelay_ServiceDesc, srv)
+}
+
+func _GateRelay_Deliver_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeliverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GateRelayServer).Deliver(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GateRelay_Deliver_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GateRelayServer).Deliver(ctx, req.(*DeliverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GateRelay_PublishTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GateRelayServer).PublishTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GateRelay_PublishTopic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GateRelayServer).PublishTopic(ctx, req.(*PublishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GateRelay_ServiceDesc is the grpc.ServiceDesc for GateRelay service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GateRelay_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gate.GateRelay",
+	HandlerType: (*GateRelayServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Deliver",
+			Handler:    _GateRelay_Deliver_Handler,
+		},
+		{
+			MethodName: "PublishTopic",
+			Handler:    _GateRelay_PublishTopic_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "gate.proto",
+}
+
+const (
+	Push_PushToUser_FullMethodName       = "/gate.Push/PushToUser"
+	Push_PushToConnection_FullMethodName = "/gate.Push/PushToConnection"
+	Push_BroadcastLocal_FullMethodName   = "/gate.Push/BroadcastLocal"
+	Push_KickUser_FullMethodName         = "/gate.Push/KickUser"
+	Push_CloseConnection_FullMethodName  = "/gate.Push/CloseConnection"
+	Push_Publish_FullMethodName          = "/gate.Push/Publish"
+)
+
+// PushClient is the client API for Push service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// 供后端服务调用的推送服务，后端无需直接使用 WebSocket 即可通知在线用户
+type PushClient interface {
+	// 推送到用户在所有 gate 上的连接
+	PushToUser(ctx context.Context, in *PushToUserRequest, opts ...grpc.CallOption) (*PushResponse, error)
+	// 推送到指定连接，连接可以在任意 gate 上
+	PushToConnection(ctx context.Context, in *PushToConnectionRequest, opts ...grpc.CallOption) (*PushResponse, error)
+	// 只广播到本 gate 上的所有连接，集群广播需要调用每个 gate
+	BroadcastLocal(ctx context.Context, in *BroadcastRequest, opts ...grpc.CallOption) (*BroadcastResponse, error)
+	// 踢掉用户在所有 gate 上的连接，客户端会先收到 kicked 消息
+	KickUser(ctx context.Context, in *KickUserRequest, opts ...grpc.CallOption) (*KickResponse, error)
+	// 关闭指定连接，其他 gate 上的连接由所在 gate 踢掉
+	CloseConnection(ctx context.Context, in *CloseConnectionRequest, opts ...grpc.CallOption) (*KickResponse, error)
+	// 发布到所有 gate 上订阅了主题的连接
+	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+}
+
+type pushClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPushClient(cc grpc.ClientConnInterface) PushClient {
+	return &pushClient{cc}
+}
+
+func (c *pushClient) PushToUser(ctx context.Context, in *PushToUserRequest, opts ...grpc.CallOption) (*PushResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PushResponse)
+	err := c.cc.Invoke(ctx, Push_PushToUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pushClient) PushToConnection(ctx context.Context, in *PushToConnectionRequest, opts ...grpc.CallOption) (*PushResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PushResponse)
+	err := c.cc.Invoke(ctx, Push_PushToConnection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pushClient) BroadcastLocal(ctx context.Context, in *BroadcastRequest, opts ...grpc.CallOption) (*BroadcastResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BroadcastResponse)
+	err := c.cc.Invoke(ctx, Push_BroadcastLocal_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pushClient) KickUser(ctx context.Context, in *KickUserRequest, opts ...grpc.CallOption) (*KickResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KickResponse)
+	err := c.cc.Invoke(ctx, Push_KickUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pushClient) CloseConnection(ctx context.Context, in *CloseConnectionRequest, opts ...grpc.CallOption) (*KickResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KickResponse)
+	err := c.cc.Invoke(ctx, Push_CloseConnection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pushClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, Push_Publish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PushServer is the server API for Push service.
+// All implementations must embed UnimplementedPushServer
+// for forward compatibility.
+//
+// 供后端服务调用的推送服务，后端无需直接使用 WebSocket 即可通知在线用户
+type PushServer interface {
+	// 推送到用户在所有 gate 上的连接
+	PushToUser(context.Context, *PushToUserRequest) (*PushResponse, error)
+	// 推送到指定连接，连接可以在任意 gate 上
+	PushToConnection(context.Context, *PushToConnectionRequest) (*PushResponse, error)
+	// 只广播到本 gate 上的所有连接，集群广播需要调用每个 gate
+	BroadcastLocal(context.Context, *BroadcastRequest) (*BroadcastResponse, error)
+	// 踢掉用户在所有 gate 上的连接，客户端会先收到 kicked 消息
+	KickUser(context.Context, *KickUserRequest) (*KickResponse, error)
+	// 关闭指定连接，其他 gate 上的连接由所在 gate 踢掉
+	CloseConnection(context.Context, *CloseConnectionRequest) (*KickResponse, error)
+	// 发布到所有 gate 上订阅了主题的连接
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	mustEmbedUnimplementedPushServer()
+}
+
+// UnimplementedPushServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPushServer struct{}
+
+func (UnimplementedPushServer) PushToUser(context.Context, *PushToUserRequest) (*PushResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PushToUser not implemented")
+}
+func (UnimplementedPushServer) PushToConnection(context.Context, *PushToConnectionRequest) (*PushResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PushToConnection not implemented")
+}
+func (UnimplementedPushServer) BroadcastLocal(context.Context, *BroadcastRequest) (*BroadcastResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BroadcastLocal not implemented")
+}
+func (UnimplementedPushServer) KickUser(context.Context, *KickUserRequest) (*KickResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method KickUser not implemented")
+}
+func (UnimplementedPushServer) CloseConnection(context.Context, *CloseConnectionRequest) (*KickResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CloseConnection not implemented")
+}
+func (UnimplementedPushServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedPushServer) mustEmbedUnimplementedPushServer() {}
+func (UnimplementedPushServer) testEmbeddedByValue()              {}
+
+// UnsafePushServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PushServer will
+// result in compilation errors.
+type UnsafePushServer interface {
+	mustEmbedUnimplementedPushServer()
+}
+
+func RegisterPushServer(s grpc.ServiceRegistrar, srv PushServer) {
+	// If the following call panics, it indicates UnimplementedPushServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Push_ServiceDesc, srv)
+}
+
+func _Push_PushToUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PushToUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PushServer).PushToUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Push_PushToUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PushServer).PushToUser(ctx, req.(*PushToUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Push_PushToConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PushToConnectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PushServer).PushToConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Push_PushToConnection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PushServer).PushToConnection(ctx, req.(*PushToConnectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Push_BroadcastLocal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PushServer).BroadcastLocal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Push_BroadcastLocal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PushServer).BroadcastLocal(ctx, req.(*BroadcastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Push_KickUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KickUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PushServer).KickUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Push_KickUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PushServer).KickUser(ctx, req.(*KickUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Push_CloseConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseConnectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PushServer).CloseConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Push_CloseConnection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PushServer).CloseConnection(ctx, req.(*CloseConnectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Push_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PushServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Push_Publish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PushServer).Publish(ctx, req.(*PublishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Push_ServiceDesc is the grpc.ServiceDesc for Push service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Push_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gate.Push",
+	HandlerType: (*PushServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PushToUser",
+			Handler:    _Push_PushToUser_Handler,
+		},
+		{
+			MethodName: "PushToConnection",
+			Handler:    _Push_PushToConnection_Handler,
+		},
+		{
+			MethodName: "BroadcastLocal",
+			Handler:    _Push_BroadcastLocal_Handler,
+		},
+		{
+			MethodName: "KickUser",
+			Handler:    _Push_KickUser_Handler,
+		},
+		{
+			MethodName: "CloseConnection",
+			Handler:    _Push_CloseConnection_Handler,
+		},
+		{
+			MethodName: "Publish",
+			Handler:    _Push_Publish_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "gate.proto",
+}
@@ -1,6 +1,12 @@
 package main
 
-import "github.com/mxxmstar/learning/status_server/status_config"
+import (
+	"log"
+
+	http_server "github.com/mxxmstar/learning/status_server/internal/server/httpserver"
+	"github.com/mxxmstar/learning/status_server/internal/server/httpserver/handler"
+	"github.com/mxxmstar/learning/status_server/status_config"
+)
 
 func main() {
 	cfg, err := status_config.Init()
@@ -8,4 +14,13 @@ func main() {
 		panic(err)
 	}
 
+	// TODO: etcd 地址在配置中配置
+	if err := handler.InitRegistry(nil); err != nil {
+		log.Fatalf("Error initializing registry: %v", err)
+	}
+
+	server := http_server.NewHttpServer(cfg)
+	if err := server.Run(cfg); err != nil {
+		log.Fatalf("Failed to start http server: %v", err)
+	}
 }
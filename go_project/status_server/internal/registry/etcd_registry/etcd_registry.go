@@ -9,7 +9,7 @@ import (
 
 	status_model "github.com/mxxmstar/learning/pkg/model/status"
 
-	"go.etcd.io/etcd/clientv3"
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 const (
@@ -117,7 +117,7 @@ func (r *EtcdRegistry) keepAlive(serviceId string, ServiceType string, leaseId c
 			}
 
 			ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			_, err = r.client.Put(ctxWithTimeout, serviceKey, string(serviceBytes))
+			_, err = r.client.Put(ctxWithTimeout, serviceKey, string(serviceBytes), clientv3.WithIgnoreLease())
 			cancel()
 			if err != nil {
 				// TODO: 日志记录
@@ -266,6 +266,41 @@ func (r *EtcdRegistry) DeregisterService(serviceType, serviceId string) error {
 	return nil
 }
 
+// Heartbeat 刷新心跳时间，report 不为空时同时更新服务状态与负载，保留原有租约
+func (r *EtcdRegistry) Heartbeat(serviceType, serviceId string, report *status_model.HeartbeatReport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceKey := ServicePrefix + serviceType + "/" + serviceId
+	getResp, err := r.client.Get(ctx, serviceKey)
+	if err != nil {
+		return fmt.Errorf("failed to get service info: %v", err)
+	}
+	if len(getResp.Kvs) == 0 {
+		return fmt.Errorf("service %s_%s not found", serviceType, serviceId)
+	}
+
+	var serviceInfo status_model.ServiceInfo
+	if err := json.Unmarshal(getResp.Kvs[0].Value, &serviceInfo); err != nil {
+		return fmt.Errorf("failed to unmarshal service info: %v", err)
+	}
+	serviceInfo.LastHeartbeat = status_model.GetCurrentTimestamp()
+	if report != nil {
+		report.Apply(&serviceInfo)
+	}
+
+	serviceBytes, err := json.Marshal(&serviceInfo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal service info: %v", err)
+	}
+	if _, err := r.client.Put(ctx, serviceKey, string(serviceBytes), clientv3.WithIgnoreLease()); err != nil {
+		return fmt.Errorf("failed to update heartbeat: %v", err)
+	}
+
+	r.cache.Store(serviceKey, &serviceInfo)
+	return nil
+}
+
 // Close 关闭连接
 func (r *EtcdRegistry) Close() error {
 	return r.client.Close()
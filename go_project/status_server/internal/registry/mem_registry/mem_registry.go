@@ -64,12 +64,14 @@ func (r *MemRegistry) GetService(serviceType, serviceId string) (*status_model.S
 	}
 
 	if service.IsExpired() {
-		delete(r.services, key)
+		// 过期的服务由定时任务清理
 		return nil, errors.New("service expired")
 	}
-	return service, nil
+	info := *service
+	return &info, nil
 }
 
+// DiscoverServicesByType 返回服务信息的副本，之后的心跳更新不会影响调用方
 func (r *MemRegistry) DiscoverServicesByType(serviceType string) ([]*status_model.ServiceInfo, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
@@ -78,7 +80,8 @@ func (r *MemRegistry) DiscoverServicesByType(serviceType string) ([]*status_mode
 	for key, service := range r.services {
 		if service.ServiceType == serviceType {
 			if !service.IsExpired() {
-				services = append(services, service)
+				info := *service
+				services = append(services, &info)
 			} else {
 				// 标记为过期，启动一个 goroutine ，在写锁中删除
 				go func(k string) {
@@ -104,7 +107,8 @@ func (r *MemRegistry) GetAllServices() ([]*status_model.ServiceInfo, error) {
 	var services []*status_model.ServiceInfo
 	for key, service := range r.services {
 		if !service.IsExpired() {
-			services = append(services, service)
+			info := *service
+			services = append(services, &info)
 		} else {
 			// 标记为过期，启动一个 goroutine ，在写锁中删除
 			go func(k string) {
@@ -135,6 +139,11 @@ func (r *MemRegistry) DeregisterService(serviceType, serviceId string) error {
 }
 
 func (r *MemRegistry) KeepAlive(serviceType, serviceId string) error {
+	return r.Heartbeat(serviceType, serviceId, nil)
+}
+
+// Heartbeat 刷新心跳时间，report 不为空时同时更新服务状态与负载
+func (r *MemRegistry) Heartbeat(serviceType, serviceId string, report *status_model.HeartbeatReport) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -145,6 +154,9 @@ func (r *MemRegistry) KeepAlive(serviceType, serviceId string) error {
 	}
 
 	service.LastHeartbeat = status_model.GetCurrentTimestamp()
+	if report != nil {
+		report.Apply(service)
+	}
 	return nil
 }
 
@@ -3,6 +3,7 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	status_def "github.com/mxxmstar/learning/pkg/def/status"
@@ -47,24 +48,49 @@ func ServiceRegisterHandler(c *gin.Context) {
 		return
 	}
 
+	if req.ServiceType == "" || req.ServiceId == "" {
+		c.JSON(http.StatusOK, status_def.ServiceRegisterResponse{
+			Code:    400,
+			Message: "Invalid service type or service id",
+		})
+		return
+	}
+
 	serviceInfo := req.ConvertToStatusServiceInfo()
+	serviceInfo.LastHeartbeat = status_model.GetCurrentTimestamp()
+	// 同一 Id 重新注册（如服务重启）时覆盖旧的注册信息
+	if _, err := registryInstance.GetService(serviceInfo.ServiceType, serviceInfo.ServiceId); err == nil {
+		_ = registryInstance.DeregisterService(serviceInfo.ServiceType, serviceInfo.ServiceId)
+	}
+	if err := registryInstance.RegisterService(serviceInfo); err != nil {
+		c.JSON(http.StatusOK, status_def.ServiceRegisterResponse{
+			Code:    409,
+			Message: err.Error(),
+		})
+		return
+	}
 
+	c.JSON(http.StatusOK, status_def.ServiceRegisterResponse{
+		ServiceId: serviceInfo.ServiceId,
+		Code:      200,
+		Message:   "Service registered successfully",
+	})
 }
 
 // ServiceDiscoveryByTagsHandler 服务发现处理器
 func ServiceDiscoveryByTagsHandler(c *gin.Context) {
-	var req status.ServiceDiscoveryByTagsRequest
+	var req status_def.ServiceDiscoveryByTagsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, status.ServiceDiscoveryByTagsResponse{
+		c.JSON(http.StatusBadRequest, status_def.ServiceDiscoveryByTagsResponse{
 			Code:    400,
 			Message: "Invalid request parameters",
 		})
 		return
 	}
 
-	services, err := registryInstance.DiscoverServicesByType(req.ServiceName)
+	services, err := availableServices(req.ServiceName, req.Exclude...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, status.ServiceDiscoveryByTagsResponse{
+		c.JSON(http.StatusInternalServerError, status_def.ServiceDiscoveryByTagsResponse{
 			Code:    500,
 			Message: "Internal server error",
 		})
@@ -72,7 +98,7 @@ func ServiceDiscoveryByTagsHandler(c *gin.Context) {
 	}
 
 	if len(services) == 0 {
-		c.JSON(http.StatusOK, status.ServiceDiscoveryByTagsResponse{
+		c.JSON(http.StatusOK, status_def.ServiceDiscoveryByTagsResponse{
 			Code:     404,
 			Message:  "Service not found",
 			Services: nil,
@@ -80,21 +106,72 @@ func ServiceDiscoveryByTagsHandler(c *gin.Context) {
 		return
 	}
 
-	// 根据策略选择服务（这里简化为选择第一个）
+	// 选择负载率最低的服务
 	selectedService := services[0]
+	for _, service := range services[1:] {
+		if service.LessLoaded(selectedService) {
+			selectedService = service
+		}
+	}
 
-	c.JSON(http.StatusOK, status.ServiceDiscoveryByTagsResponse{
+	c.JSON(http.StatusOK, status_def.ServiceDiscoveryByTagsResponse{
 		Code:     200,
 		Message:  "Service discovery successful",
 		Services: convertToServiceInfo(selectedService),
 	})
 }
 
+// ServiceDiscoveryListHandler 服务发现处理器，返回指定类型的所有可用实例
+func ServiceDiscoveryListHandler(c *gin.Context) {
+	var req status_def.ServiceDiscoveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, status_def.ServiceDiscoveryResponse{
+			Code:    400,
+			Message: "Invalid request parameters",
+		})
+		return
+	}
+
+	services, err := availableServices(req.ServiceName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, status_def.ServiceDiscoveryResponse{
+			Code:    500,
+			Message: "Internal server error",
+		})
+		return
+	}
+
+	infos := make([]*status_def.ServiceInfo, 0, len(services))
+	for _, service := range services {
+		infos = append(infos, convertToServiceInfo(service))
+	}
+	c.JSON(http.StatusOK, status_def.ServiceDiscoveryResponse{
+		Code:     200,
+		Message:  "Service discovery successful",
+		Services: infos,
+	})
+}
+
+// availableServices 查询指定类型中可以分配给客户端的服务，排除排空中和下线的服务
+func availableServices(serviceType string, exclude ...string) ([]*status_model.ServiceInfo, error) {
+	services, err := registryInstance.DiscoverServicesByType(serviceType)
+	if err != nil {
+		return nil, err
+	}
+	available := services[:0]
+	for _, service := range services {
+		if service.IsAvailable() && !slices.Contains(exclude, service.ServiceId) {
+			available = append(available, service)
+		}
+	}
+	return available, nil
+}
+
 // ServiceDeregisterHandler 服务注销处理器
 func ServiceDeregisterHandler(c *gin.Context) {
-	var req status.ServiceDeregisterRequest
+	var req status_def.ServiceDeregisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, status.ServiceDeregisterResponse{
+		c.JSON(http.StatusBadRequest, status_def.ServiceDeregisterResponse{
 			Code:    400,
 			Message: "Invalid request parameters",
 		})
@@ -104,17 +181,17 @@ func ServiceDeregisterHandler(c *gin.Context) {
 	// 从 ServiceID 解析服务类型，这里简化处理
 	// 实际应用中可能需要从其他途径获取服务类型
 	serviceType := "unknown" // 实际实现中需要解析
-	serviceID := req.ServiceID
+	serviceID := req.ServiceId
 
 	if err := registryInstance.DeregisterService(serviceType, serviceID); err != nil {
-		c.JSON(http.StatusInternalServerError, status.ServiceDeregisterResponse{
+		c.JSON(http.StatusInternalServerError, status_def.ServiceDeregisterResponse{
 			Code:    500,
 			Message: "Internal server error",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, status.ServiceDeregisterResponse{
+	c.JSON(http.StatusOK, status_def.ServiceDeregisterResponse{
 		Code:    200,
 		Message: "Service deregistered successfully",
 	})
@@ -131,7 +208,16 @@ func ServiceHeartbeatHandler(c *gin.Context) {
 		return
 	}
 
-	// 心跳信息会通过租约机制自动更新，这里只是接收心跳
+	// 刷新心跳时间并记录上报的状态与负载，排空中的服务不再参与服务发现
+	report := &status_model.HeartbeatReport{Status: req.Status, Load: req.Load, MaxLoad: req.MaxLoad}
+	if err := registryInstance.Heartbeat(req.ServiceType, req.ServiceId, report); err != nil {
+		c.JSON(http.StatusOK, status_def.ServiceHeartbeatResponse{
+			Code:    404,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, status_def.ServiceHeartbeatResponse{
 		Code:    200,
 		Message: "Heartbeat received",
@@ -158,15 +244,34 @@ func convertToServiceInfo(internal *status_model.ServiceInfo) *status_def.Servic
 		ServiceType: internal.ServiceType,
 		ServiceId:   internal.ServiceId,
 		Protocol:    internal.Protocol,
-		GRPCAddress: internal.GRPCAddress,
+		GRPCAddress: convertGRPCAddress(internal.GRPCAddress),
+		HTTPAddress: convertHTTPAddress(internal.HTTPAddress),
 
-		Env:            "default",
-		Tags:           []string{internal.IdC},
-		HealthCheckUrl: "",
-		Weight:         100,
-		Status:         "healthy",
+		Env:            internal.Env,
+		Tags:           internal.Tags,
+		Idc:            internal.Idc,
+		Metadata:       internal.Metadata,
+		HealthCheckUrl: internal.HealthCheckUrl,
+		Weight:         internal.Weight,
+		Status:         internal.Status,
 		LastHeartbeat:  internal.LastHeartbeat,
+		Load:           internal.Load,
+		MaxLoad:        internal.MaxLoad,
+	}
+}
+
+func convertGRPCAddress(addr *status_model.GRPCAddress) *status_def.GRPCAddress {
+	if addr == nil {
+		return nil
+	}
+	return &status_def.GRPCAddress{Host: addr.Host, Port: addr.Port}
+}
+
+func convertHTTPAddress(addr *status_model.HTTPAddress) *status_def.HTTPAddress {
+	if addr == nil {
+		return nil
 	}
+	return &status_def.HTTPAddress{Host: addr.Host, Port: addr.Port}
 }
 
 // ServiceDiscoveryByNameHandler 服务发现处理器（按名称）
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	status_def "github.com/mxxmstar/learning/pkg/def/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngine(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	require.NoError(t, InitRegistry(nil))
+
+	engine := gin.New()
+	engine.POST("/register", ServiceRegisterHandler)
+	engine.POST("/heartbeat", ServiceHeartbeatHandler)
+	engine.POST("/discovery/by-tags", ServiceDiscoveryByTagsHandler)
+	engine.POST("/discovery/list", ServiceDiscoveryListHandler)
+	return engine
+}
+
+func postJSON(t *testing.T, engine *gin.Engine, path string, req, res interface{}) {
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+}
+
+func registerGate(t *testing.T, engine *gin.Engine, id string) {
+	var res status_def.ServiceRegisterResponse
+	postJSON(t, engine, "/register", &status_def.ServiceRegisterRequest{
+		ServiceName: id,
+		ServiceType: "gate",
+		ServiceId:   id,
+		HTTPAddress: &status_def.HTTPAddress{Host: "127.0.0.1", Port: 8080},
+		Enable:      true,
+	}, &res)
+	require.Equal(t, 200, res.Code, res.Message)
+	assert.Equal(t, id, res.ServiceId)
+}
+
+func TestHeartbeatDrainingExcludedFromDiscovery(t *testing.T) {
+	engine := newTestEngine(t)
+	registerGate(t, engine, "gate_1")
+	registerGate(t, engine, "gate_2")
+
+	var hb status_def.ServiceHeartbeatResponse
+	postJSON(t, engine, "/heartbeat", &status_def.ServiceHeartbeatRequest{
+		ServiceType: "gate",
+		ServiceId:   "gate_1",
+		Status:      "draining",
+	}, &hb)
+	require.Equal(t, 200, hb.Code, hb.Message)
+
+	var list status_def.ServiceDiscoveryResponse
+	postJSON(t, engine, "/discovery/list", &status_def.ServiceDiscoveryRequest{ServiceName: "gate"}, &list)
+	require.Equal(t, 200, list.Code)
+	require.Len(t, list.Services, 1, "排空中的 gate 不应该被发现")
+	assert.Equal(t, "gate_2", list.Services[0].ServiceId)
+
+	var one status_def.ServiceDiscoveryByTagsResponse
+	postJSON(t, engine, "/discovery/by-tags", &status_def.ServiceDiscoveryByTagsRequest{ServiceName: "gate"}, &one)
+	require.Equal(t, 200, one.Code)
+	assert.Equal(t, "gate_2", one.Services.ServiceId)
+
+	// 恢复 active 后重新参与服务发现
+	postJSON(t, engine, "/heartbeat", &status_def.ServiceHeartbeatRequest{
+		ServiceType: "gate",
+		ServiceId:   "gate_1",
+		Status:      "active",
+	}, &hb)
+	postJSON(t, engine, "/discovery/list", &status_def.ServiceDiscoveryRequest{ServiceName: "gate"}, &list)
+	assert.Len(t, list.Services, 2)
+}
+
+func TestHeartbeatUnknownService(t *testing.T) {
+	engine := newTestEngine(t)
+
+	var hb status_def.ServiceHeartbeatResponse
+	postJSON(t, engine, "/heartbeat", &status_def.ServiceHeartbeatRequest{
+		ServiceType: "gate",
+		ServiceId:   "gate_1",
+		Status:      "active",
+	}, &hb)
+	assert.Equal(t, 404, hb.Code, "未注册的服务心跳应该返回 404，服务需要重新注册")
+}
+
+func TestDiscoveryByTagsExcludeAndHTTPAddress(t *testing.T) {
+	engine := newTestEngine(t)
+	registerGate(t, engine, "gate_1")
+	registerGate(t, engine, "gate_2")
+
+	var one status_def.ServiceDiscoveryByTagsResponse
+	postJSON(t, engine, "/discovery/by-tags", &status_def.ServiceDiscoveryByTagsRequest{ServiceName: "gate", Exclude: []string{"gate_1"}}, &one)
+	require.Equal(t, 200, one.Code)
+	assert.Equal(t, "gate_2", one.Services.ServiceId, "请求方排除的 gate 不应该被选择")
+	require.NotNil(t, one.Services.HTTPAddress, "响应应该携带 http 地址用于重定向")
+	assert.Equal(t, 8080, one.Services.HTTPAddress.Port)
+
+	postJSON(t, engine, "/discovery/by-tags", &status_def.ServiceDiscoveryByTagsRequest{ServiceName: "gate", Exclude: []string{"gate_1", "gate_2"}}, &one)
+	assert.Equal(t, 404, one.Code)
+}
+
+func TestDiscoveryByTagsLeastLoaded(t *testing.T) {
+	engine := newTestEngine(t)
+	loads := map[string][2]int{
+		"gate_1": {900, 1000},
+		"gate_2": {600, 2000},
+		"gate_3": {500, 1000},
+	}
+	for id, load := range loads {
+		registerGate(t, engine, id)
+		var hb status_def.ServiceHeartbeatResponse
+		postJSON(t, engine, "/heartbeat", &status_def.ServiceHeartbeatRequest{
+			ServiceType: "gate",
+			ServiceId:   id,
+			Status:      "active",
+			Load:        load[0],
+			MaxLoad:     load[1],
+		}, &hb)
+		require.Equal(t, 200, hb.Code, hb.Message)
+	}
+
+	// 多次查询结果一致，不依赖 map 的遍历顺序
+	for i := 0; i < 10; i++ {
+		var one status_def.ServiceDiscoveryByTagsResponse
+		postJSON(t, engine, "/discovery/by-tags", &status_def.ServiceDiscoveryByTagsRequest{ServiceName: "gate"}, &one)
+		require.Equal(t, 200, one.Code)
+		assert.Equal(t, "gate_2", one.Services.ServiceId, "应该选择负载率最低的 gate")
+		assert.Equal(t, 600, one.Services.Load)
+		assert.Equal(t, 2000, one.Services.MaxLoad)
+	}
+}
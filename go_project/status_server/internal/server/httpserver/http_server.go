@@ -6,7 +6,7 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/mxxmstar/learning/status_server/internal/handler"
+	"github.com/mxxmstar/learning/status_server/internal/server/httpserver/handler"
 	"github.com/mxxmstar/learning/status_server/status_config"
 )
 
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/mxxmstar/learning/status_server/internal/server/httpserver/handler"
 	"github.com/mxxmstar/learning/status_server/status_config"
 )
 
@@ -14,7 +15,7 @@ func RegisterQueryRoutes(server *gin.Engine, cfg *status_config.Config, redisCli
 }
 
 // 注册服务管理路由
-func RegisterServerRoutes(server *gin.Engine, cfg *status_config.Config, redisClient *redis.RedisClient) {
+func RegisterServerRoutes(server *gin.Engine) {
 	// 服务注册相关路由
 	api := server.Group("/api")
 	{
@@ -26,6 +27,7 @@ func RegisterServerRoutes(server *gin.Engine, cfg *status_config.Config, redisCl
 		// 服务发现相关路由
 		api.POST("/discovery/by-name", handler.ServiceDiscoveryByNameHandler)
 		api.POST("/discovery/by-tags", handler.ServiceDiscoveryByTagsHandler)
+		api.POST("/discovery/list", handler.ServiceDiscoveryListHandler)
 		api.POST("/discovery/by-metadata", handler.ServiceDiscoveryByMetadataHandler)
 
 		// 服务状态查询路由
@@ -43,6 +45,7 @@ func RegisterServerRoutes(server *gin.Engine, cfg *status_config.Config, redisCl
 		gate.POST("/service/register", handler.ServiceRegisterHandler)
 		gate.POST("/service/heartbeat", handler.ServiceHeartbeatHandler)
 		gate.POST("/discovery/by-tags", handler.ServiceDiscoveryByTagsHandler)
+		gate.POST("/discovery/list", handler.ServiceDiscoveryListHandler)
 	}
 
 	// 根路径
@@ -23,5 +23,9 @@ func main() {
 	server := web.InitWebServer(cfg)
 	// 启动服务
 	// server.Run(fmt.Sprintf(":%d", cfg.VerifyServer.Port))
-	server.Run(fmt.Sprintf("0.0.0.0:%d", cfg.ServerConfig.VerifyServers.Port))
+	verifyServer, err := cfg.VerifyServer()
+	if err != nil {
+		log.Fatalf("Error selecting verify server: %v", err)
+	}
+	server.Run(fmt.Sprintf("0.0.0.0:%d", verifyServer.HttpConfig.Port))
 }
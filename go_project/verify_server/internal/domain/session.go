@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// 登录会话信息，用于多设备管理
+type SessionInfo struct {
+	SessionId  string    `json:"session_id"`
+	UserId     uint64    `json:"user_id"`
+	DeviceId   string    `json:"device_id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Handle 返回 session 的不透明标识，用于对外展示和注销，session id 本身是认证凭证不能返回给客户端
+func (s *SessionInfo) Handle() string {
+	sum := sha256.Sum256([]byte(s.SessionId))
+	return hex.EncodeToString(sum[:16])
+}
@@ -18,5 +18,6 @@ type User struct {
 	Username string
 	Email    string
 	Password string
+	IsBanned bool
 	CTime    time.Time
 }
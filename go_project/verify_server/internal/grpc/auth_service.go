@@ -2,10 +2,12 @@ package grpc_server
 
 import (
 	"context"
+	"net"
 
 	pb "github.com/mxxmstar/learning/proto"
 	"github.com/mxxmstar/learning/verify_server/internal/domain"
 	"github.com/mxxmstar/learning/verify_server/internal/service"
+	"google.golang.org/grpc/peer"
 )
 
 type AuthService struct {
@@ -20,7 +22,7 @@ func NewAuthService(authService *service.AuthService) *AuthService {
 }
 
 func (s *AuthService) VerifySession(ctx context.Context, req *pb.VerifySessionRequest) (*pb.VerifySessionResponse, error) {
-	user, err := s.authService.GetSessionUser(ctx, req.GetSessionId())
+	user, err := s.authService.VerifySession(ctx, req.GetSessionId())
 	if err != nil {
 		return &pb.VerifySessionResponse{
 			Valid:  false,
@@ -46,7 +48,7 @@ func (s *AuthService) VerifySession(ctx context.Context, req *pb.VerifySessionRe
 }
 
 func (s *AuthService) VerifyJWT(ctx context.Context, req *pb.VerifyJWTRequest) (*pb.VerifyJWTResponse, error) {
-	claims, err := s.authService.ValidateAndParseJWT(req.GetJwtToken())
+	claims, err := s.authService.ValidateAndParseJWT(ctx, req.GetJwtToken())
 	if err != nil {
 		return &pb.VerifyJWTResponse{
 			Valid:    false,
@@ -56,11 +58,17 @@ func (s *AuthService) VerifyJWT(ctx context.Context, req *pb.VerifyJWTRequest) (
 		}, nil
 	}
 
+	var issuedAtMs int64
+	if issuedAt := claims.IssuedAtTime(); !issuedAt.IsZero() {
+		issuedAtMs = issuedAt.UnixMilli()
+	}
 	return &pb.VerifyJWTResponse{
-		Valid:    true,
-		UserId:   claims.UserId,
-		DeviceId: claims.DeviceId,
-		Error:    "",
+		Valid:      true,
+		UserId:     claims.UserId,
+		DeviceId:   claims.DeviceId,
+		Error:      "",
+		TokenId:    claims.ID,
+		IssuedAtMs: issuedAtMs,
 	}, nil
 }
 
@@ -81,9 +89,22 @@ func (s *AuthService) RefreshSession(ctx context.Context, req *pb.RefreshSession
 
 func (s *AuthService) LoginByEmail(ctx context.Context, req *pb.LoginByEmailRequest) (*pb.LoginByEmailResponse, error) {
 	loginCtx := &domain.LoginContext{
-		DeviceId: req.GetDeviceId(),
+		DeviceId:  req.GetDeviceId(),
+		UserAgent: req.GetUserAgent(),
 		// todo: 其他登录上下文信息
 	}
+	// 只信任内部服务透传的客户端 IP，否则任何调用方都可以伪造 IP 绕过按 IP 的登录限流
+	if isInternalCaller(ctx) {
+		loginCtx.IPAddress = req.GetIpAddress()
+	}
+	// 调用方未透传客户端 IP 时，使用对端地址参与限流
+	if loginCtx.IPAddress == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+				loginCtx.IPAddress = host
+			}
+		}
+	}
 
 	sessionId, err := s.authService.LoginByEmail(ctx, req.GetEmail(), req.GetPassword(), loginCtx)
 	if err != nil {
@@ -117,11 +138,42 @@ func (s *AuthService) LoginByEmail(ctx context.Context, req *pb.LoginByEmailRequ
 		}, nil
 	}
 
+	// 签发刷新令牌
+	refreshToken, err := s.authService.IssueRefreshToken(ctx, user, loginCtx)
+	if err != nil {
+		return &pb.LoginByEmailResponse{
+			SessionId: "",
+			JwtToken:  "",
+			UserId:    0,
+			Error:     "Failed to generate refresh token: " + err.Error(),
+		}, nil
+	}
+
 	return &pb.LoginByEmailResponse{
-		SessionId: sessionId,
-		JwtToken:  jwtToken,
-		UserId:    user.Id,
-		Error:     "",
+		SessionId:    sessionId,
+		JwtToken:     jwtToken,
+		RefreshToken: refreshToken,
+		UserId:       user.Id,
+		Error:        "",
+	}, nil
+}
+
+func (s *AuthService) RefreshJWT(ctx context.Context, req *pb.RefreshJWTRequest) (*pb.RefreshJWTResponse, error) {
+	jwtToken, refreshToken, userId, err := s.authService.RefreshJWT(ctx, req.GetRefreshToken())
+	if err != nil {
+		return &pb.RefreshJWTResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.RefreshJWTResponse{
+		Success:      true,
+		JwtToken:     jwtToken,
+		RefreshToken: refreshToken,
+		UserId:       userId,
+		ExpiresIn:    int64(s.authService.TokenLifetime()),
+		Error:        "",
 	}, nil
 }
 
@@ -153,3 +205,117 @@ func (s *AuthService) SignUp(ctx context.Context, req *pb.SignUpRequest) (*pb.Si
 		Error:   "",
 	}, nil
 }
+
+func (s *AuthService) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	if err := s.authService.Logout(ctx, req.GetSessionId(), req.GetJwtToken()); err != nil {
+		return &pb.LogoutResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.LogoutResponse{
+		Success: true,
+		Error:   "",
+	}, nil
+}
+
+func (s *AuthService) RevokeUserTokens(ctx context.Context, req *pb.RevokeUserTokensRequest) (*pb.RevokeUserTokensResponse, error) {
+	if req.GetUserId() == 0 {
+		return &pb.RevokeUserTokensResponse{
+			Success: false,
+			Error:   "user id is required",
+		}, nil
+	}
+
+	if err := s.authService.RevokeUserTokens(ctx, req.GetUserId()); err != nil {
+		return &pb.RevokeUserTokensResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.RevokeUserTokensResponse{
+		Success: true,
+		Error:   "",
+	}, nil
+}
+
+// ListSessions 列出调用方所有登录中的设备，用户由请求携带的 session 或 jwt 确定
+func (s *AuthService) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	caller, err := s.authService.Authenticate(ctx, req.GetSessionId(), req.GetJwtToken())
+	if err != nil {
+		return &pb.ListSessionsResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	sessions, err := s.authService.ListSessions(ctx, caller.UserId)
+	if err != nil {
+		return &pb.ListSessionsResponse{
+			Error: err.Error(),
+		}, nil
+	}
+
+	res := &pb.ListSessionsResponse{
+		Sessions: make([]*pb.SessionInfo, 0, len(sessions)),
+	}
+	for _, info := range sessions {
+		res.Sessions = append(res.Sessions, &pb.SessionInfo{
+			Handle:     info.Handle(),
+			DeviceId:   info.DeviceId,
+			IpAddress:  info.IPAddress,
+			UserAgent:  info.UserAgent,
+			CreatedAt:  info.CreatedAt.Unix(),
+			LastSeenAt: info.LastSeenAt.Unix(),
+			Current:    caller.IsCurrent(info),
+		})
+	}
+	return res, nil
+}
+
+func (s *AuthService) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionResponse, error) {
+	caller, err := s.authService.Authenticate(ctx, req.GetSessionId(), req.GetJwtToken())
+	if err != nil {
+		return &pb.RevokeSessionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	if err := s.authService.RevokeSession(ctx, caller.UserId, req.GetHandle()); err != nil {
+		return &pb.RevokeSessionResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.RevokeSessionResponse{
+		Success: true,
+		Error:   "",
+	}, nil
+}
+
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, req *pb.RevokeOtherSessionsRequest) (*pb.RevokeOtherSessionsResponse, error) {
+	caller, err := s.authService.Authenticate(ctx, req.GetSessionId(), req.GetJwtToken())
+	if err != nil {
+		return &pb.RevokeOtherSessionsResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	revoked, err := s.authService.RevokeOtherSessions(ctx, caller)
+	if err != nil {
+		return &pb.RevokeOtherSessionsResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.RevokeOtherSessionsResponse{
+		Success: true,
+		Revoked: int32(revoked),
+		Error:   "",
+	}, nil
+}
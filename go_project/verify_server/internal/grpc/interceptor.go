@@ -0,0 +1,37 @@
+package grpc_server
+
+import (
+	"context"
+
+	"github.com/mxxmstar/learning/pkg/internalauth"
+	pb "github.com/mxxmstar/learning/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// internalMethods 只允许携带共享令牌的内部服务调用的方法，登出和吊销会使其他凭证失效
+var internalMethods = map[string]bool{
+	pb.Auth_Logout_FullMethodName:           true,
+	pb.Auth_RevokeUserTokens_FullMethodName: true,
+}
+
+type internalCallerKey struct{}
+
+// isInternalCaller 调用方是否携带了正确的共享令牌
+func isInternalCaller(ctx context.Context) bool {
+	internal, _ := ctx.Value(internalCallerKey{}).(bool)
+	return internal
+}
+
+// internalAuthInterceptor 校验内部方法的共享令牌，未配置令牌时拒绝调用内部方法
+// 其他方法不要求令牌，但会记录调用方是否为内部服务，透传的客户端 IP 等信息只信任内部服务
+func internalAuthInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		internal := internalauth.IsInternal(ctx, token)
+		if internalMethods[info.FullMethod] && !internal {
+			return nil, status.Error(codes.Unauthenticated, "invalid internal token")
+		}
+		return handler(context.WithValue(ctx, internalCallerKey{}, internal), req)
+	}
+}
@@ -0,0 +1,51 @@
+package grpc_server
+
+import (
+	"context"
+	"testing"
+
+	auth_def "github.com/mxxmstar/learning/pkg/def/verify/auth"
+	pb "github.com/mxxmstar/learning/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestInternalAuthInterceptor(t *testing.T) {
+	interceptor := internalAuthInterceptor("secret")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	call := func(method, token string) error {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(auth_def.InternalTokenMetadataKey, token))
+		}
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(call(pb.Auth_RevokeUserTokens_FullMethodName, "")), "没有令牌不能吊销")
+	assert.Equal(t, codes.Unauthenticated, status.Code(call(pb.Auth_Logout_FullMethodName, "wrong")), "错误令牌不能登出")
+	assert.NoError(t, call(pb.Auth_RevokeUserTokens_FullMethodName, "secret"))
+	assert.NoError(t, call(pb.Auth_VerifyJWT_FullMethodName, ""), "非内部方法不需要令牌")
+}
+
+func TestInternalCallerTrustedIP(t *testing.T) {
+	interceptor := internalAuthInterceptor("secret")
+	var internal bool
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		internal = isInternalCaller(ctx)
+		return nil, nil
+	}
+	call := func(token string) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(auth_def.InternalTokenMetadataKey, token))
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: pb.Auth_LoginByEmail_FullMethodName}, handler)
+		assert.NoError(t, err)
+	}
+
+	call("wrong")
+	assert.False(t, internal, "错误令牌的调用方透传的 IP 不可信")
+	call("secret")
+	assert.True(t, internal)
+}
@@ -34,15 +34,20 @@ func NewGRPCServer(grpcService *GRPCService, config *verify_config.Config) *GRPC
 }
 
 func (s *GRPCServer) Start() error {
+	server, err := s.config.VerifyServer()
+	if err != nil {
+		return err
+	}
+
 	// 创建监听地址
-	listen, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.VerifyGRPCServer.Port))
+	listen, err := net.Listen("tcp", fmt.Sprintf(":%d", server.GRPCConfig.Port))
 	if err != nil {
-		logger.FormatLog(context.Background(), "error", fmt.Sprintf("Failed to start gRPC server at %d: %v", s.config.VerifyGRPCServer.Port, zap.Error(err)))
+		logger.FormatLog(context.Background(), "error", fmt.Sprintf("Failed to start gRPC server at %d: %v", server.GRPCConfig.Port, zap.Error(err)))
 		return err
 	}
 
 	// 创建 gRPC 服务器
-	s.server = grpc.NewServer()
+	s.server = grpc.NewServer(grpc.UnaryInterceptor(internalAuthInterceptor(s.config.ServerConfig.GlobalConfig.InternalToken)))
 
 	// 注册服务
 	authService := NewAuthService(s.grpcService.authService)
@@ -50,7 +55,7 @@ func (s *GRPCServer) Start() error {
 	pb.RegisterAuthServer(s.server, authService)
 
 	// 在开发环境中启用反射服务，以便使用 gRPC 客户端工具进行调试
-	if s.config.ProjectConfig.Env != "production" {
+	if s.config.ServerConfig.GlobalConfig.Env != "production" {
 		reflection.Register(s.server)
 		logger.FormatLog(context.Background(), "info", "gRPC reflection service enabled in non-production environment")
 	}
@@ -0,0 +1,160 @@
+// Package daotest 测试用的内存数据库，不依赖 MySQL 测试 repository 与 service
+package daotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mxxmstar/learning/pkg/database"
+	"github.com/mxxmstar/learning/verify_server/internal/repository/dao"
+	"gorm.io/gorm"
+)
+
+// MemDB 内存中的用户表，实现 database.DBInterface，只支持 UserDAO 用到的查询
+type MemDB struct {
+	mu     sync.Mutex
+	users  map[uint64]*dao.User
+	nextId uint64
+}
+
+func NewMemDB() *MemDB {
+	return &MemDB{users: make(map[uint64]*dao.User)}
+}
+
+// Put 直接写入用户，Id 为 0 时自动分配
+func (db *MemDB) Put(user *dao.User) *dao.User {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if user.Id == 0 {
+		db.nextId++
+		user.Id = db.nextId
+	}
+	u := *user
+	db.users[u.Id] = &u
+	return user
+}
+
+// Get 按 Id 查询用户，不存在时返回 nil
+func (db *MemDB) Get(id uint64) *dao.User {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if u, ok := db.users[id]; ok {
+		copied := *u
+		return &copied
+	}
+	return nil
+}
+
+func (db *MemDB) WithContext(ctx context.Context) database.DBContextInterface {
+	return &memContext{db: db}
+}
+
+func (db *MemDB) AutoMigrate(dst ...interface{}) error {
+	return nil
+}
+
+// memContext 记录 Where 条件，支持 "email = ?" 与 "id = ?"
+type memContext struct {
+	db    *MemDB
+	query string
+	arg   interface{}
+	err   error
+}
+
+func (c *memContext) Create(value interface{}) database.DBContextInterface {
+	user, ok := value.(*dao.User)
+	if !ok {
+		c.err = fmt.Errorf("unsupported model %T", value)
+		return c
+	}
+	c.db.mu.Lock()
+	for _, u := range c.db.users {
+		if u.Email == user.Email {
+			c.err = fmt.Errorf("%w: email", gorm.ErrDuplicatedKey)
+		} else if u.Username == user.Username {
+			c.err = fmt.Errorf("%w: username", gorm.ErrDuplicatedKey)
+		}
+	}
+	c.db.mu.Unlock()
+	if c.err == nil {
+		c.db.Put(user)
+	}
+	return c
+}
+
+func (c *memContext) Where(query interface{}, args ...interface{}) database.DBContextInterface {
+	c.query, _ = query.(string)
+	if len(args) > 0 {
+		c.arg = args[0]
+	}
+	return c
+}
+
+func (c *memContext) First(dest interface{}) database.DBContextInterface {
+	user, ok := dest.(*dao.User)
+	if !ok {
+		c.err = fmt.Errorf("unsupported model %T", dest)
+		return c
+	}
+	found := c.find()
+	if found == nil {
+		c.err = gorm.ErrRecordNotFound
+		return c
+	}
+	*user = *found
+	return c
+}
+
+func (c *memContext) Model(value interface{}) database.DBContextInterface {
+	return c
+}
+
+func (c *memContext) Updates(values interface{}) database.DBContextInterface {
+	fields, ok := values.(map[string]interface{})
+	if !ok {
+		c.err = fmt.Errorf("unsupported updates %T", values)
+		return c
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for _, u := range c.db.users {
+		if !c.match(u) {
+			continue
+		}
+		if password, ok := fields["password"].(string); ok {
+			u.Password = password
+		}
+		if updatedAt, ok := fields["updated_at"].(int64); ok {
+			u.UpdatedAt = updatedAt
+		}
+	}
+	return c
+}
+
+func (c *memContext) Error() error {
+	return c.err
+}
+
+func (c *memContext) find() *dao.User {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for _, u := range c.db.users {
+		if c.match(u) {
+			copied := *u
+			return &copied
+		}
+	}
+	return nil
+}
+
+func (c *memContext) match(u *dao.User) bool {
+	switch c.query {
+	case "email = ?":
+		return u.Email == c.arg
+	case "id = ?":
+		id, ok := c.arg.(uint64)
+		return ok && u.Id == id
+	}
+	return false
+}
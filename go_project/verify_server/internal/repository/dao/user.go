@@ -70,3 +70,15 @@ func (dao *UserDAO) FindByEmail(ctx context.Context, email string) (*User, error
 	}
 	return &user, nil
 }
+
+// UpdatePassword 更新用户密码（存储的是哈希值）
+func (dao *UserDAO) UpdatePassword(ctx context.Context, id uint64, password string) error {
+	dbCtx := dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":   password,
+		"updated_at": time.Now().UnixMilli(),
+	})
+	if dbCtx.Error() != nil {
+		return dao.errorConverter.ConvertError(dbCtx.Error())
+	}
+	return nil
+}
@@ -60,5 +60,10 @@ func (repo *UserRepository) GetUserByEmail(ctx context.Context, email string) (*
 		Username: user.Username,
 		Email:    user.Email,
 		Password: user.Password,
+		IsBanned: user.IsBanned,
 	}, nil
 }
+
+func (repo *UserRepository) UpdatePassword(ctx context.Context, id uint64, password string) error {
+	return repo.userDAO.UpdatePassword(ctx, id, password)
+}
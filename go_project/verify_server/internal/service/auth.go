@@ -6,11 +6,15 @@ import (
 	"errors"
 	"time"
 
+	"github.com/mxxmstar/learning/pkg/encrypt"
 	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/mxxmstar/learning/pkg/logger"
+	"github.com/mxxmstar/learning/pkg/revocation"
 	"github.com/mxxmstar/learning/pkg/session/token_session"
 	"github.com/mxxmstar/learning/pkg/store/redis"
 	"github.com/mxxmstar/learning/verify_server/internal/domain"
 	"github.com/mxxmstar/learning/verify_server/internal/repository"
+	"go.uber.org/zap"
 )
 
 var (
@@ -32,6 +36,15 @@ var (
 	// ErrInvalidUserInfo 表示用户提交的信息不符合要求
 	ErrInvalidUserInfo = errors.New("invalid user information")
 
+	// ErrTokenRevoked 表示令牌已被吊销
+	ErrTokenRevoked = errors.New("token has been revoked")
+
+	// ErrRevocationDisabled 表示未开启凭证吊销
+	ErrRevocationDisabled = errors.New("token revocation is disabled")
+
+	// ErrUnauthenticated 表示请求没有携带有效的 session 或 jwt
+	ErrUnauthenticated = errors.New("invalid or expired credential")
+
 	// SessionTTl 表示会话过期时间，默认24小时
 	SessionTTL = 24 * time.Hour
 )
@@ -39,24 +52,32 @@ var (
 type AuthService struct {
 	userRepo      *repository.UserRepository
 	redisClient   *redis.RedisClient
-	jwtSecret     string
 	tokenLifeTime int
 	jwtManager    *jwt_manager.JWT
+	hasher        encrypt.PasswordHasher // 密码哈希器，登录时算法或参数过期的存量哈希会被重新哈希
+	loginLimiter  *LoginLimiter          // 登录限流器，为 nil 时不限流
+	refreshTokens *RefreshTokenStore     // 刷新令牌存储，为 nil 时不签发刷新令牌
+	revocations   *revocation.Store      // 凭证吊销列表，为 nil 时不支持吊销
+	sessions      *SessionRegistry       // 用户的 session 索引
 }
 
-func NewAuthService(userRepo *repository.UserRepository, redisClient *redis.RedisClient, jwtSecret string, tokenLifetime int) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, redisClient *redis.RedisClient, hasher encrypt.PasswordHasher, loginLimiter *LoginLimiter, refreshTokens *RefreshTokenStore, revocations *revocation.Store, jwtKeys *jwt_manager.KeySet, tokenLifetime int) *AuthService {
 	if tokenLifetime <= 0 {
 		// 默认设置为1小时
 		tokenLifetime = 3600
 	}
 
-	jwtMgr := jwt_manager.NewJWT([]byte(jwtSecret), "verify_server", tokenLifetime)
+	jwtMgr := jwt_manager.NewJWTWithKeySet(jwtKeys, "verify_server", tokenLifetime)
 	return &AuthService{
 		userRepo:      userRepo,
 		redisClient:   redisClient,
-		jwtSecret:     jwtSecret,
 		tokenLifeTime: tokenLifetime,
 		jwtManager:    jwtMgr,
+		hasher:        hasher,
+		loginLimiter:  loginLimiter,
+		refreshTokens: refreshTokens,
+		revocations:   revocations,
+		sessions:      NewSessionRegistry(redisClient, SessionTTL),
 	}
 }
 
@@ -64,24 +85,61 @@ func (s *AuthService) Signup(ctx context.Context, user *domain.User) error {
 	if err := validateUser(user); err != nil {
 		return err
 	}
-	// 在这里调用 encrypt 对密码进行加密
+	// 只持久化密码哈希，不落库明文
+	hash, err := s.hasher.Hash(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
 
 	return s.userRepo.CreateUser(ctx, user)
 }
 
 // Login 用户登录并创建session，这里不通过 session 对象生成 JWT，在handler层统一整合
 func (s *AuthService) LoginByEmail(ctx context.Context, email, password string, loginCtx *domain.LoginContext) (string, error) {
+	ip := ""
+	if loginCtx != nil {
+		ip = loginCtx.IPAddress
+	}
+
+	// 登录限流和失败锁定检查
+	if s.loginLimiter != nil {
+		if err := s.loginLimiter.Allow(ctx, email, ip); err != nil {
+			logger.LogAuth(ctx, "login", false, "login throttled: "+err.Error())
+			return "", err
+		}
+	}
+
 	// 查找用户
 	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
+		s.recordLoginFailure(ctx, email)
 		return "", ErrInvalidCredentials
 	}
 
-	// 验证密码（这里应该使用加密验证）
-	if user.Password != password {
+	// 验证密码，哈希器根据存储的哈希格式选择算法
+	ok, err := s.hasher.Verify(password, user.Password)
+	if err != nil || !ok {
+		s.recordLoginFailure(ctx, email)
 		return "", ErrInvalidCredentials
 	}
 
+	// 密码正确后再检查封禁状态，避免泄露账户是否存在
+	if user.IsBanned {
+		return "", ErrUserDisabled
+	}
+
+	if s.loginLimiter != nil {
+		if err := s.loginLimiter.RecordSuccess(ctx, email); err != nil {
+			logger.FormatLog(ctx, "error", "reset login failures failed", zap.String("email", email), zap.Error(err))
+		}
+	}
+
+	// 明文密码或算法/参数过期的哈希，登录成功后原地升级
+	if s.hasher.NeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user, password)
+	}
+
 	// 创建session
 	// TODO: 权限待完善
 	deviceId := ""
@@ -112,11 +170,43 @@ func (s *AuthService) LoginByEmail(ctx context.Context, email, password string,
 		return "", err
 	}
 
-	// TODO: loginCtx信息的管理
+	// 记录登录设备信息，失败不影响本次登录
+	if err := s.sessions.Add(ctx, user.Id, t, loginCtx); err != nil {
+		logger.FormatLog(ctx, "error", "add session to registry failed", zap.Uint64("userId", user.Id), zap.Error(err))
+	}
 
 	return t, nil
 }
 
+// recordLoginFailure 记录登录失败，达到阈值时锁定账户，失败不影响本次登录结果
+func (s *AuthService) recordLoginFailure(ctx context.Context, email string) {
+	if s.loginLimiter == nil {
+		return
+	}
+	lockFor, err := s.loginLimiter.RecordFailure(ctx, email)
+	if err != nil {
+		logger.FormatLog(ctx, "error", "record login failure failed", zap.String("email", email), zap.Error(err))
+		return
+	}
+	if lockFor > 0 {
+		logger.FormatLog(ctx, "warn", "account locked after too many login failures", zap.String("email", email), zap.Duration("lockFor", lockFor))
+	}
+}
+
+// rehashPassword 使用当前配置的算法重新哈希密码并回写，失败不影响本次登录
+func (s *AuthService) rehashPassword(ctx context.Context, user *domain.User, password string) {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		logger.FormatLog(ctx, "error", "rehash password failed", zap.Uint64("userId", user.Id), zap.Error(err))
+		return
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.Id, hash); err != nil {
+		logger.FormatLog(ctx, "error", "update rehashed password failed", zap.Uint64("userId", user.Id), zap.Error(err))
+		return
+	}
+	user.Password = hash
+}
+
 // GenerateJWT 生成JWT令牌
 func (s *AuthService) GenerateJWT(user *domain.User, loginCtx *domain.LoginContext) (string, error) {
 	userId := user.Id
@@ -133,6 +223,55 @@ func (s *AuthService) GenerateJWT(user *domain.User, loginCtx *domain.LoginConte
 	return token, nil
 }
 
+// TokenLifetime 访问令牌的有效期，单位秒
+func (s *AuthService) TokenLifetime() int {
+	return s.tokenLifeTime
+}
+
+// JWKS 导出 jwt 验签公钥，HS256 时为空集合
+func (s *AuthService) JWKS() *jwt_manager.JWKS {
+	return s.jwtManager.JWKS()
+}
+
+// IssueRefreshToken 登录成功后签发刷新令牌，未开启刷新令牌时返回空字符串
+func (s *AuthService) IssueRefreshToken(ctx context.Context, user *domain.User, loginCtx *domain.LoginContext) (string, error) {
+	if s.refreshTokens == nil {
+		return "", nil
+	}
+	deviceId := ""
+	if loginCtx != nil {
+		deviceId = loginCtx.DeviceId
+	}
+	return s.refreshTokens.Issue(ctx, user.Id, deviceId)
+}
+
+// RefreshJWT 使用刷新令牌换取新的访问令牌，同时轮换刷新令牌
+// 已轮换的刷新令牌被再次使用时，整个令牌族会被吊销并返回 ErrRefreshTokenReused
+func (s *AuthService) RefreshJWT(ctx context.Context, refreshToken string) (jwtToken string, newRefreshToken string, userId uint64, err error) {
+	if s.refreshTokens == nil {
+		return "", "", 0, ErrRefreshTokenDisabled
+	}
+
+	newRefreshToken, claims, err := s.refreshTokens.Rotate(ctx, refreshToken)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		fields := []zap.Field{}
+		if claims != nil {
+			fields = append(fields, zap.String("familyId", claims.FamilyId), zap.Uint64("userId", claims.UserId))
+		}
+		logger.FormatLog(ctx, "warn", "refresh token reuse detected, token family revoked", fields...)
+		return "", "", 0, err
+	}
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	jwtToken, err = s.jwtManager.GenerateToken(claims.UserId, claims.DeviceId)
+	if err != nil {
+		return "", "", 0, err
+	}
+	return jwtToken, newRefreshToken, claims.UserId, nil
+}
+
 // GetSessionUser 从session中获取用户信息
 func (s *AuthService) GetSessionUser(ctx context.Context, sessionId string) (*domain.User, error) {
 	key := "session:" + sessionId
@@ -150,10 +289,169 @@ func (s *AuthService) GetSessionUser(ctx context.Context, sessionId string) (*do
 	return &user, nil
 }
 
-// Logout 用户登出，清除session
-func (s *AuthService) Logout(ctx context.Context, sessionId string) error {
-	key := "session:" + sessionId
-	return s.redisClient.Del(ctx, key)
+// Logout 用户登出，清除session并吊销本次登录的 jwt，jwtToken 可以为空
+func (s *AuthService) Logout(ctx context.Context, sessionId, jwtToken string) error {
+	if sessionId != "" {
+		user, err := s.GetSessionUser(ctx, sessionId)
+		if err == nil {
+			if err := s.revokeSessions(ctx, user.Id, sessionId); err != nil {
+				return err
+			}
+		} else if err := s.redisClient.Del(ctx, sessionPrefix+sessionId); err != nil {
+			return err
+		}
+	}
+
+	if jwtToken != "" {
+		return s.RevokeJWT(ctx, jwtToken)
+	}
+	return nil
+}
+
+// VerifySession 验证 session 并更新最后活跃时间
+func (s *AuthService) VerifySession(ctx context.Context, sessionId string) (*domain.User, error) {
+	user, err := s.GetSessionUser(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.Touch(ctx, user.Id, sessionId); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		logger.FormatLog(ctx, "error", "touch session failed", zap.Uint64("userId", user.Id), zap.Error(err))
+	}
+	return user, nil
+}
+
+// Caller 由请求携带的 session 或 jwt 确定的调用方
+type Caller struct {
+	UserId    uint64
+	DeviceId  string // 使用 jwt 认证时的设备
+	SessionId string // 使用 session 认证时的 session
+}
+
+// Authenticate 使用调用方自己的 session 或 jwt 确定用户，两者都提供时使用 session
+func (s *AuthService) Authenticate(ctx context.Context, sessionId, jwtToken string) (*Caller, error) {
+	if sessionId != "" {
+		user, err := s.VerifySession(ctx, sessionId)
+		if err != nil {
+			return nil, ErrUnauthenticated
+		}
+		return &Caller{UserId: user.Id, SessionId: sessionId}, nil
+	}
+	if jwtToken != "" {
+		claims, err := s.ValidateAndParseJWT(ctx, jwtToken)
+		if err != nil {
+			return nil, ErrUnauthenticated
+		}
+		return &Caller{UserId: claims.UserId, DeviceId: claims.DeviceId}, nil
+	}
+	return nil, ErrUnauthenticated
+}
+
+// IsCurrent 该 session 是否属于调用方当前的登录
+// 使用 session 认证时只匹配该 session，使用 jwt 认证时匹配同一设备的 session
+func (c *Caller) IsCurrent(info *domain.SessionInfo) bool {
+	if c.SessionId != "" {
+		return info.SessionId == c.SessionId
+	}
+	return c.DeviceId != "" && info.DeviceId == c.DeviceId
+}
+
+// ListSessions 列出用户所有登录中的设备
+func (s *AuthService) ListSessions(ctx context.Context, userId uint64) ([]*domain.SessionInfo, error) {
+	return s.sessions.List(ctx, userId)
+}
+
+// RevokeSession 按 ListSessions 返回的标识注销用户的某个 session，用于下线单个设备
+func (s *AuthService) RevokeSession(ctx context.Context, userId uint64, handle string) error {
+	sessions, err := s.sessions.List(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for _, info := range sessions {
+		if info.Handle() == handle {
+			return s.revokeSessions(ctx, userId, info.SessionId)
+		}
+	}
+	return ErrSessionNotFound
+}
+
+// RevokeOtherSessions 注销调用方当前登录以外的所有 session，返回注销的数量
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, caller *Caller) (int, error) {
+	return s.revokeSessionsExcept(ctx, caller.UserId, caller.IsCurrent)
+}
+
+// revokeSessionsExcept 注销用户所有 keep 返回 false 的 session，keep 为空时注销全部
+func (s *AuthService) revokeSessionsExcept(ctx context.Context, userId uint64, keep func(*domain.SessionInfo) bool) (int, error) {
+	sessions, err := s.sessions.List(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	var others []string
+	for _, info := range sessions {
+		if keep == nil || !keep(info) {
+			others = append(others, info.SessionId)
+		}
+	}
+	if err := s.revokeSessions(ctx, userId, others...); err != nil {
+		return 0, err
+	}
+	return len(others), nil
+}
+
+// revokeSessions 删除 session 及其索引，并通知 gate 关闭对应连接
+func (s *AuthService) revokeSessions(ctx context.Context, userId uint64, sessionIds ...string) error {
+	if len(sessionIds) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(sessionIds))
+	for _, id := range sessionIds {
+		keys = append(keys, sessionPrefix+id)
+	}
+	if err := s.redisClient.Del(ctx, keys...); err != nil {
+		return err
+	}
+	if err := s.sessions.Remove(ctx, userId, sessionIds...); err != nil {
+		return err
+	}
+
+	if s.revocations != nil {
+		for _, id := range sessionIds {
+			if err := s.revocations.RevokeSession(ctx, userId, id); err != nil {
+				logger.FormatLog(ctx, "error", "publish session revocation failed", zap.Uint64("userId", userId), zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// RevokeJWT 吊销单个 jwt，已过期或无效的令牌直接忽略
+func (s *AuthService) RevokeJWT(ctx context.Context, jwtToken string) error {
+	if s.revocations == nil {
+		return ErrRevocationDisabled
+	}
+	claims, err := s.jwtManager.ParseToken(jwtToken)
+	if err != nil {
+		return nil
+	}
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	return s.revocations.RevokeToken(ctx, claims.UserId, claims.ID, claims.ExpiresAt.Time)
+}
+
+// RevokeUserTokens 吊销用户当前已签发的所有 session、jwt 和刷新令牌，用于修改密码、封禁等场景
+func (s *AuthService) RevokeUserTokens(ctx context.Context, userId uint64) error {
+	if s.revocations == nil {
+		return ErrRevocationDisabled
+	}
+	if _, err := s.revokeSessionsExcept(ctx, userId, nil); err != nil {
+		return err
+	}
+	if s.refreshTokens != nil {
+		if err := s.refreshTokens.RevokeUser(ctx, userId); err != nil {
+			return err
+		}
+	}
+	return s.revocations.RevokeUserBefore(ctx, userId, time.Now())
 }
 
 // RefreshSession 刷新session的过期时间
@@ -162,14 +460,24 @@ func (s *AuthService) RefreshSession(ctx context.Context, sessionId string) erro
 	return s.redisClient.Expire(ctx, key, SessionTTL)
 }
 
-// 验证并解析 JWT 令牌
-func (s *AuthService) ValidateAndParseJWT(token string) (*jwt_manager.CustomClaims, error) {
+// 验证并解析 JWT 令牌，已吊销的令牌返回 ErrTokenRevoked
+func (s *AuthService) ValidateAndParseJWT(ctx context.Context, token string) (*jwt_manager.CustomClaims, error) {
 	// 解析JWT
 	claims, err := s.jwtManager.ParseToken(token)
 	if err != nil {
 		return nil, err
 	}
 
+	if s.revocations != nil {
+		revoked, err := s.revocations.IsRevoked(ctx, claims.UserId, claims.ID, claims.IssuedAtTime())
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+
 	return claims, nil
 }
 
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/mxxmstar/learning/pkg/ratelimit"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/mxxmstar/learning/verify_server/internal/domain"
+	"github.com/mxxmstar/learning/verify_server/internal/repository"
+	"github.com/mxxmstar/learning/verify_server/internal/repository/dao"
+	"github.com/mxxmstar/learning/verify_server/internal/repository/dao/daotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHasher 不做真正哈希的快速哈希器，"fake$" 前缀为当前算法，"old$" 前缀为需要升级的旧算法
+type fakeHasher struct {
+	hashes atomic.Int32
+}
+
+func (h *fakeHasher) Algorithm() string { return "fake" }
+
+func (h *fakeHasher) Hash(password string) (string, error) {
+	h.hashes.Add(1)
+	return "fake$" + password, nil
+}
+
+func (h *fakeHasher) Verify(password, encoded string) (bool, error) {
+	return encoded == "fake$"+password || encoded == "old$"+password, nil
+}
+
+func (h *fakeHasher) NeedsRehash(encoded string) bool {
+	return !strings.HasPrefix(encoded, "fake$")
+}
+
+func newTestAuthService(t *testing.T, limit *LoginLimiterConfig) (*AuthService, *daotest.MemDB, *fakeHasher) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewRedisClient(mr.Addr(), "", 0)
+	keys, err := jwt_manager.LoadKeySet(jwt_manager.KeySetConfig{}, []byte("secret"))
+	require.NoError(t, err)
+	var limiter *LoginLimiter
+	if limit != nil {
+		limiter = NewLoginLimiter(redisClient, *limit)
+	}
+	db := daotest.NewMemDB()
+	hasher := &fakeHasher{}
+	repo := repository.NewUserRepository(dao.NewUserDAO(db))
+	return NewAuthService(repo, redisClient, hasher, limiter, nil, nil, keys, 0), db, hasher
+}
+
+func TestSignupHashesPassword(t *testing.T) {
+	s, db, hasher := newTestAuthService(t, nil)
+	ctx := context.Background()
+
+	user := &domain.User{Email: "a@example.com", Username: "alice", Password: "123456"}
+	require.NoError(t, s.Signup(ctx, user))
+	stored, err := dao.NewUserDAO(db).FindByEmail(ctx, "a@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "fake$123456", stored.Password, "只能保存密码哈希")
+	assert.Equal(t, int32(1), hasher.hashes.Load())
+
+	err = s.Signup(ctx, &domain.User{Email: "a@example.com", Username: "bob", Password: "123456"})
+	assert.ErrorIs(t, err, ErrUserEmailConflict)
+	assert.ErrorIs(t, s.Signup(ctx, &domain.User{Email: "c@example.com", Username: "carol", Password: "123"}), ErrInvalidUserInfo, "密码过短应该在哈希之前拒绝")
+	assert.Equal(t, int32(2), hasher.hashes.Load())
+}
+
+func TestLoginRehashesOutdatedPassword(t *testing.T) {
+	s, db, hasher := newTestAuthService(t, nil)
+	ctx := context.Background()
+	outdated := db.Put(&dao.User{Email: "a@example.com", Username: "alice", Password: "old$123456"})
+	current := db.Put(&dao.User{Email: "b@example.com", Username: "bob", Password: "fake$123456"})
+
+	_, err := s.LoginByEmail(ctx, "a@example.com", "123456", &domain.LoginContext{DeviceId: "phone"})
+	require.NoError(t, err)
+	assert.Equal(t, "fake$123456", db.Get(outdated.Id).Password, "旧算法的哈希应该在登录成功后升级")
+
+	_, err = s.LoginByEmail(ctx, "b@example.com", "123456", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fake$123456", db.Get(current.Id).Password)
+	assert.Equal(t, int32(1), hasher.hashes.Load(), "当前算法的哈希不需要重新哈希")
+
+	_, err = s.LoginByEmail(ctx, "a@example.com", "wrong", nil)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Equal(t, int32(1), hasher.hashes.Load(), "登录失败不应该重新哈希")
+}
+
+func TestLoginBannedUser(t *testing.T) {
+	s, db, _ := newTestAuthService(t, nil)
+	ctx := context.Background()
+	db.Put(&dao.User{Email: "a@example.com", Username: "alice", Password: "fake$123456", IsBanned: true})
+
+	_, err := s.LoginByEmail(ctx, "a@example.com", "wrong", nil)
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "密码错误时不能暴露账户被封禁")
+	_, err = s.LoginByEmail(ctx, "a@example.com", "123456", nil)
+	assert.ErrorIs(t, err, ErrUserDisabled)
+	_, err = s.LoginByEmail(ctx, "nobody@example.com", "123456", nil)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLoginLimiterWiring(t *testing.T) {
+	s, db, _ := newTestAuthService(t, &LoginLimiterConfig{
+		MaxAttemptsPerEmail: 100,
+		MaxAttemptsPerIP:    100,
+		Window:              time.Minute,
+		Lockout:             ratelimit.LockoutConfig{MaxFailures: 2, BaseDuration: time.Minute},
+	})
+	ctx := context.Background()
+	db.Put(&dao.User{Email: "a@example.com", Username: "alice", Password: "fake$123456"})
+	db.Put(&dao.User{Email: "b@example.com", Username: "bob", Password: "fake$123456"})
+
+	// 登录成功清空失败计数
+	_, err := s.LoginByEmail(ctx, "a@example.com", "wrong", nil)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.LoginByEmail(ctx, "a@example.com", "123456", nil)
+	require.NoError(t, err)
+	_, err = s.LoginByEmail(ctx, "a@example.com", "wrong", nil)
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "成功登录后失败计数应该重新开始")
+
+	// 连续失败达到上限后锁定，正确的密码也不能登录
+	_, err = s.LoginByEmail(ctx, "a@example.com", "wrong", nil)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.LoginByEmail(ctx, "a@example.com", "123456", nil)
+	var throttled *LoginThrottledError
+	require.ErrorAs(t, err, &throttled, "锁定期间应该拒绝登录")
+	assert.Greater(t, throttled.RetryAfter, time.Duration(0))
+
+	_, err = s.LoginByEmail(ctx, "b@example.com", "123456", nil)
+	assert.NoError(t, err, "锁定只影响对应的邮箱")
+}
+
+func TestLoginLimiterPerIP(t *testing.T) {
+	s, db, _ := newTestAuthService(t, &LoginLimiterConfig{
+		MaxAttemptsPerEmail: 100,
+		MaxAttemptsPerIP:    2,
+		Window:              time.Minute,
+	})
+	ctx := context.Background()
+	db.Put(&dao.User{Email: "a@example.com", Username: "alice", Password: "fake$123456"})
+
+	ip := &domain.LoginContext{IPAddress: "10.0.0.1"}
+	_, err := s.LoginByEmail(ctx, "x@example.com", "123456", ip)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.LoginByEmail(ctx, "y@example.com", "123456", ip)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.LoginByEmail(ctx, "a@example.com", "123456", ip)
+	assert.ErrorIs(t, err, ErrTooManyLoginAttempts, "同一 IP 尝试不同邮箱也应该受限")
+
+	_, err = s.LoginByEmail(ctx, "a@example.com", "123456", &domain.LoginContext{IPAddress: "10.0.0.2"})
+	assert.NoError(t, err, "其他 IP 不受影响")
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mxxmstar/learning/pkg/session/token_session"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+)
+
+var (
+	// ErrRefreshTokenDisabled 表示未开启刷新令牌
+	ErrRefreshTokenDisabled = errors.New("refresh token is disabled")
+
+	// ErrInvalidRefreshToken 表示刷新令牌不存在、已过期或所属令牌族已被吊销
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+	// ErrRefreshTokenReused 表示已轮换的刷新令牌被再次使用，整个令牌族已被吊销
+	ErrRefreshTokenReused = errors.New("refresh token reused, token family revoked")
+)
+
+const (
+	refreshTokenPrefix  = "refresh_token:"
+	refreshFamilyPrefix = "refresh_family:"
+	refreshUserPrefix   = "refresh_user_families:" // 用户的所有令牌族，用于吊销用户的全部刷新令牌
+)
+
+// rotateRefreshTokenScript 原子地轮换刷新令牌
+// KEYS[1] 旧令牌 key KEYS[2] 令牌族 key KEYS[3] 新令牌 key，三者使用同一个 hash tag，在 Redis Cluster 中位于同一个 slot
+// ARGV[1] 令牌中携带的令牌族 Id
+// 返回 {状态, 令牌族, 用户Id, 设备Id}，状态: 1 成功 -1 令牌不存在 -2 令牌族已失效 -3 令牌被重复使用
+const rotateRefreshTokenScript = `
+local fields = redis.call("HMGET", KEYS[1], "family", "user_id", "device_id", "used")
+if not fields[1] or fields[1] ~= ARGV[1] then
+	return {-1}
+end
+
+local familyKey = KEYS[2]
+local revoked = redis.call("HGET", familyKey, "revoked")
+if not revoked or revoked == "1" then
+	return {-2}
+end
+
+if fields[4] == "1" then
+	redis.call("HSET", familyKey, "revoked", "1")
+	return {-3, fields[1], fields[2], fields[3]}
+end
+
+-- 旧令牌保留到令牌族过期，用于检测重复使用
+redis.call("HSET", KEYS[1], "used", "1")
+local ttl = redis.call("PTTL", familyKey)
+redis.call("HSET", KEYS[3], "family", fields[1], "user_id", fields[2], "device_id", fields[3], "used", "0")
+redis.call("PEXPIRE", KEYS[3], ttl)
+return {1, fields[1], fields[2], fields[3]}
+`
+
+// revokeFamilyScript 吊销令牌族，已过期的令牌族不会被重新创建
+// KEYS[1] 令牌族 key
+const revokeFamilyScript = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("HSET", KEYS[1], "revoked", "1")
+end
+return 1
+`
+
+// RefreshTokenClaims 刷新令牌对应的用户信息
+type RefreshTokenClaims struct {
+	FamilyId string
+	UserId   uint64
+	DeviceId string
+}
+
+// RefreshTokenStore 基于 Redis 的不透明刷新令牌存储
+// 同一次登录签发的刷新令牌属于同一个令牌族，每次使用都会轮换为新令牌；
+// 已轮换的令牌被再次使用时视为泄露，吊销整个令牌族
+// 令牌格式为 令牌族Id.随机串，令牌族与其令牌的 key 使用令牌族 Id 作为 hash tag
+type RefreshTokenStore struct {
+	redisClient *redis.RedisClient
+	ttl         time.Duration // 令牌族的有效期，轮换不会延长
+}
+
+func NewRefreshTokenStore(redisClient *redis.RedisClient, ttl time.Duration) *RefreshTokenStore {
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+	return &RefreshTokenStore{
+		redisClient: redisClient,
+		ttl:         ttl,
+	}
+}
+
+// Issue 为一次新的登录创建令牌族并签发第一个刷新令牌
+func (s *RefreshTokenStore) Issue(ctx context.Context, userId uint64, deviceId string) (string, error) {
+	familyId, err := token_session.GenerateToken(16)
+	if err != nil {
+		return "", err
+	}
+	token, err := newRefreshToken(familyId)
+	if err != nil {
+		return "", err
+	}
+
+	// 用户令牌族集合与令牌族不在同一个 slot，先记录令牌族，保证签发的令牌都能被 RevokeUser 吊销
+	uid := strconv.FormatUint(userId, 10)
+	userKey := refreshUserPrefix + uid
+	client := s.redisClient.GetClient()
+	if err := client.SAdd(ctx, userKey, familyId).Err(); err != nil {
+		return "", err
+	}
+	if err := client.Expire(ctx, userKey, s.ttl).Err(); err != nil {
+		return "", err
+	}
+
+	pipe := client.TxPipeline()
+	pipe.HSet(ctx, refreshFamilyKey(familyId), "user_id", uid, "device_id", deviceId, "revoked", "0")
+	pipe.Expire(ctx, refreshFamilyKey(familyId), s.ttl)
+	pipe.HSet(ctx, refreshTokenKey(familyId, token), "family", familyId, "user_id", uid, "device_id", deviceId, "used", "0")
+	pipe.Expire(ctx, refreshTokenKey(familyId, token), s.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Rotate 使用刷新令牌换取新的刷新令牌，旧令牌随即失效
+func (s *RefreshTokenStore) Rotate(ctx context.Context, token string) (string, *RefreshTokenClaims, error) {
+	familyId, ok := refreshTokenFamily(token)
+	if !ok {
+		return "", nil, ErrInvalidRefreshToken
+	}
+	newToken, err := newRefreshToken(familyId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	keys := []string{refreshTokenKey(familyId, token), refreshFamilyKey(familyId), refreshTokenKey(familyId, newToken)}
+	res, err := s.redisClient.Eval(ctx, rotateRefreshTokenScript, keys, familyId).Slice()
+	if err != nil {
+		return "", nil, err
+	}
+
+	status, _ := res[0].(int64)
+	switch status {
+	case 1:
+		claims, err := parseRefreshTokenClaims(res)
+		if err != nil {
+			return "", nil, err
+		}
+		return newToken, claims, nil
+	case -3:
+		claims, _ := parseRefreshTokenClaims(res)
+		return "", claims, ErrRefreshTokenReused
+	default:
+		return "", nil, ErrInvalidRefreshToken
+	}
+}
+
+// RevokeFamily 吊销整个令牌族，该族下所有刷新令牌都将失效
+func (s *RefreshTokenStore) RevokeFamily(ctx context.Context, familyId string) error {
+	return s.redisClient.HSet(ctx, refreshFamilyKey(familyId), "revoked", "1").Err()
+}
+
+// RevokeUser 吊销用户的所有令牌族，用于修改密码、封禁等场景
+// 令牌族分布在不同的 slot，逐个吊销
+func (s *RefreshTokenStore) RevokeUser(ctx context.Context, userId uint64) error {
+	userKey := refreshUserPrefix + strconv.FormatUint(userId, 10)
+	families, err := s.redisClient.GetClient().SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, familyId := range families {
+		if err := s.redisClient.Eval(ctx, revokeFamilyScript, []string{refreshFamilyKey(familyId)}).Err(); err != nil {
+			return err
+		}
+	}
+	return s.redisClient.GetClient().Del(ctx, userKey).Err()
+}
+
+// newRefreshToken 生成属于令牌族的新令牌
+func newRefreshToken(familyId string) (string, error) {
+	secret, err := token_session.GenerateToken(32)
+	if err != nil {
+		return "", err
+	}
+	return familyId + "." + secret, nil
+}
+
+// refreshTokenFamily 解析令牌中携带的令牌族 Id，令牌族 Id 只能是十六进制，不能用于构造其他 hash tag
+func refreshTokenFamily(token string) (string, bool) {
+	familyId, secret, ok := strings.Cut(token, ".")
+	if !ok || familyId == "" || secret == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(familyId); err != nil {
+		return "", false
+	}
+	return familyId, true
+}
+
+func refreshFamilyKey(familyId string) string {
+	return refreshFamilyPrefix + "{" + familyId + "}"
+}
+
+// Redis 中只保存令牌的哈希，避免存储泄露后令牌被直接使用
+func refreshTokenKey(familyId, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return refreshTokenPrefix + "{" + familyId + "}:" + hex.EncodeToString(sum[:])
+}
+
+func parseRefreshTokenClaims(res []interface{}) (*RefreshTokenClaims, error) {
+	if len(res) != 4 {
+		return nil, ErrInvalidRefreshToken
+	}
+	familyId, _ := res[1].(string)
+	uid, _ := res[2].(string)
+	deviceId, _ := res[3].(string)
+	userId, err := strconv.ParseUint(uid, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	return &RefreshTokenClaims{
+		FamilyId: familyId,
+		UserId:   userId,
+		DeviceId: deviceId,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRefreshTokenStore(t *testing.T, ttl time.Duration) (*RefreshTokenStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	return NewRefreshTokenStore(redis.NewRedisClient(mr.Addr(), "", 0), ttl), mr
+}
+
+func TestRefreshTokenRotate(t *testing.T) {
+	store, _ := newRefreshTokenStore(t, time.Hour)
+	ctx := context.Background()
+
+	token, err := store.Issue(ctx, 42, "device-1")
+	assert.NoError(t, err)
+
+	next, claims, err := store.Rotate(ctx, token)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, next, "轮换应该签发新令牌")
+	assert.Equal(t, uint64(42), claims.UserId)
+	assert.Equal(t, "device-1", claims.DeviceId)
+
+	_, _, err = store.Rotate(ctx, next)
+	assert.NoError(t, err, "新令牌应该可以继续轮换")
+
+	_, _, err = store.Rotate(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken, "不存在的令牌应该无效")
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	store, _ := newRefreshTokenStore(t, time.Hour)
+	ctx := context.Background()
+
+	token, err := store.Issue(ctx, 42, "device-1")
+	assert.NoError(t, err)
+	next, _, err := store.Rotate(ctx, token)
+	assert.NoError(t, err)
+
+	_, claims, err := store.Rotate(ctx, token)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused, "已轮换的令牌再次使用应该视为泄露")
+	assert.Equal(t, uint64(42), claims.UserId)
+
+	_, _, err = store.Rotate(ctx, next)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken, "令牌族被吊销后最新的令牌也应该失效")
+
+	// 其他令牌族不受影响
+	other, err := store.Issue(ctx, 42, "device-2")
+	assert.NoError(t, err)
+	_, _, err = store.Rotate(ctx, other)
+	assert.NoError(t, err)
+}
+
+func TestRefreshTokenExpiry(t *testing.T) {
+	store, mr := newRefreshTokenStore(t, time.Minute)
+	ctx := context.Background()
+
+	token, err := store.Issue(ctx, 42, "device-1")
+	assert.NoError(t, err)
+	next, _, err := store.Rotate(ctx, token)
+	assert.NoError(t, err)
+
+	// 轮换不会延长令牌族的有效期
+	mr.FastForward(30 * time.Second)
+	next, _, err = store.Rotate(ctx, next)
+	assert.NoError(t, err)
+	mr.FastForward(31 * time.Second)
+	_, _, err = store.Rotate(ctx, next)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken, "令牌族过期后令牌应该失效")
+}
+
+func TestRefreshTokenRevokeUser(t *testing.T) {
+	store, _ := newRefreshTokenStore(t, time.Hour)
+	ctx := context.Background()
+
+	a, err := store.Issue(ctx, 42, "device-1")
+	assert.NoError(t, err)
+	b, err := store.Issue(ctx, 42, "device-2")
+	assert.NoError(t, err)
+	c, err := store.Issue(ctx, 7, "device-1")
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.RevokeUser(ctx, 42))
+	for _, token := range []string{a, b} {
+		_, _, err = store.Rotate(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken, "用户的所有令牌族都应该被吊销")
+	}
+	_, _, err = store.Rotate(ctx, c)
+	assert.NoError(t, err, "其他用户的令牌不受影响")
+}
+
+func TestRefreshTokenKeysShareSlot(t *testing.T) {
+	store, mr := newRefreshTokenStore(t, time.Hour)
+	ctx := context.Background()
+
+	token, err := store.Issue(ctx, 42, "device-1")
+	assert.NoError(t, err)
+	familyId, ok := refreshTokenFamily(token)
+	assert.True(t, ok, "令牌应该携带令牌族 Id")
+	_, _, err = store.Rotate(ctx, token)
+	assert.NoError(t, err)
+
+	// 轮换脚本访问的 key 都在 KEYS 中声明，且使用同一个 hash tag
+	for _, key := range mr.Keys() {
+		if strings.HasPrefix(key, refreshUserPrefix) {
+			continue
+		}
+		assert.Contains(t, key, "{"+familyId+"}", "令牌族的 key 应该位于同一个 slot: %s", key)
+	}
+
+	// 伪造令牌族 Id 的令牌无效
+	other, err := store.Issue(ctx, 7, "device-1")
+	assert.NoError(t, err)
+	otherFamily, _ := refreshTokenFamily(other)
+	_, _, err = store.Rotate(ctx, familyId+strings.TrimPrefix(other, otherFamily))
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, _, err = store.Rotate(ctx, "{x}."+strings.TrimPrefix(other, otherFamily+"."))
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken, "令牌族 Id 只能是十六进制")
+}
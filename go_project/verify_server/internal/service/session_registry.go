@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/mxxmstar/learning/verify_server/internal/domain"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ErrSessionNotFound 表示 session 不存在或不属于该用户
+var ErrSessionNotFound = errors.New("session not found")
+
+const (
+	sessionPrefix     = "session:"
+	userSessionPrefix = "user_sessions:"
+
+	// 两次更新最后活跃时间的最小间隔，避免每次验证都写 Redis
+	sessionTouchInterval = time.Minute
+)
+
+// SessionRegistry 用户的 session 索引，记录每个 session 的设备和登录信息
+// 索引保存在 user_sessions:<userId> 哈希中，field 为 sessionId
+type SessionRegistry struct {
+	redisClient *redis.RedisClient
+	ttl         time.Duration // 索引有效期，与 session 有效期一致
+}
+
+func NewSessionRegistry(redisClient *redis.RedisClient, ttl time.Duration) *SessionRegistry {
+	return &SessionRegistry{
+		redisClient: redisClient,
+		ttl:         ttl,
+	}
+}
+
+// Add 登录成功后记录 session
+func (r *SessionRegistry) Add(ctx context.Context, userId uint64, sessionId string, loginCtx *domain.LoginContext) error {
+	now := time.Now()
+	info := &domain.SessionInfo{
+		SessionId:  sessionId,
+		UserId:     userId,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if loginCtx != nil {
+		info.DeviceId = loginCtx.DeviceId
+		info.IPAddress = loginCtx.IPAddress
+		info.UserAgent = loginCtx.UserAgent
+	}
+	return r.save(ctx, info)
+}
+
+// Get 获取用户的某个 session，不存在时返回 ErrSessionNotFound
+func (r *SessionRegistry) Get(ctx context.Context, userId uint64, sessionId string) (*domain.SessionInfo, error) {
+	data, err := r.redisClient.HGet(ctx, userSessionKey(userId), sessionId).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var info domain.SessionInfo
+	if err := json.Unmarshal([]byte(data), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Touch 更新 session 的最后活跃时间
+func (r *SessionRegistry) Touch(ctx context.Context, userId uint64, sessionId string) error {
+	info, err := r.Get(ctx, userId, sessionId)
+	if err != nil {
+		return err
+	}
+	if time.Since(info.LastSeenAt) < sessionTouchInterval {
+		return nil
+	}
+	info.LastSeenAt = time.Now()
+	return r.save(ctx, info)
+}
+
+// List 列出用户所有有效的 session，按最后活跃时间倒序，已过期的 session 会从索引中清除
+func (r *SessionRegistry) List(ctx context.Context, userId uint64) ([]*domain.SessionInfo, error) {
+	entries, err := r.redisClient.GetClient().HGetAll(ctx, userSessionKey(userId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+
+	// 检查 session 是否仍然存在
+	pipe := r.redisClient.GetClient().Pipeline()
+	exists := make([]*goredis.IntCmd, len(ids))
+	for i, id := range ids {
+		exists[i] = pipe.Exists(ctx, sessionPrefix+id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	var sessions []*domain.SessionInfo
+	var expired []string
+	for i, id := range ids {
+		if exists[i].Val() == 0 {
+			expired = append(expired, id)
+			continue
+		}
+		var info domain.SessionInfo
+		if err := json.Unmarshal([]byte(entries[id]), &info); err != nil {
+			expired = append(expired, id)
+			continue
+		}
+		sessions = append(sessions, &info)
+	}
+	if len(expired) > 0 {
+		if err := r.Remove(ctx, userId, expired...); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// Remove 从索引中删除 session，不会删除 session 本身
+func (r *SessionRegistry) Remove(ctx context.Context, userId uint64, sessionIds ...string) error {
+	if len(sessionIds) == 0 {
+		return nil
+	}
+	return r.redisClient.GetClient().HDel(ctx, userSessionKey(userId), sessionIds...).Err()
+}
+
+func (r *SessionRegistry) save(ctx context.Context, info *domain.SessionInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	key := userSessionKey(info.UserId)
+	pipe := r.redisClient.GetClient().TxPipeline()
+	pipe.HSet(ctx, key, info.SessionId, data)
+	pipe.Expire(ctx, key, r.ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func userSessionKey(userId uint64) string {
+	return userSessionPrefix + strconv.FormatUint(userId, 10)
+}
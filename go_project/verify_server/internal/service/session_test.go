@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/mxxmstar/learning/verify_server/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSessionAuthService(t *testing.T) *AuthService {
+	mr := miniredis.RunT(t)
+	keys, err := jwt_manager.LoadKeySet(jwt_manager.KeySetConfig{}, []byte("secret"))
+	require.NoError(t, err)
+	return NewAuthService(nil, redis.NewRedisClient(mr.Addr(), "", 0), nil, nil, nil, nil, keys, 0)
+}
+
+// addSession 模拟登录写入 session 及其索引
+func addSession(t *testing.T, s *AuthService, userId uint64, sessionId, deviceId string) {
+	ctx := context.Background()
+	user, err := json.Marshal(&domain.User{Id: userId})
+	require.NoError(t, err)
+	require.NoError(t, s.redisClient.Set(ctx, sessionPrefix+sessionId, user, SessionTTL))
+	require.NoError(t, s.sessions.Add(ctx, userId, sessionId, &domain.LoginContext{DeviceId: deviceId}))
+}
+
+func TestAuthenticateCaller(t *testing.T) {
+	s := newSessionAuthService(t)
+	ctx := context.Background()
+	addSession(t, s, 1, "session-1", "phone")
+
+	caller, err := s.Authenticate(ctx, "session-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), caller.UserId, "用户应该由 session 确定")
+
+	token, err := s.jwtManager.GenerateToken(2, "pc")
+	require.NoError(t, err)
+	caller, err = s.Authenticate(ctx, "", token)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), caller.UserId, "用户应该由 jwt 确定")
+	assert.Equal(t, "pc", caller.DeviceId)
+
+	_, err = s.Authenticate(ctx, "unknown", "")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = s.Authenticate(ctx, "", "")
+	assert.ErrorIs(t, err, ErrUnauthenticated, "没有凭证的请求应该被拒绝")
+}
+
+func TestRevokeSessionByHandle(t *testing.T) {
+	s := newSessionAuthService(t)
+	ctx := context.Background()
+	addSession(t, s, 1, "session-1", "phone")
+	addSession(t, s, 1, "session-2", "pc")
+	addSession(t, s, 2, "session-3", "phone")
+
+	sessions, err := s.ListSessions(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	var handle string
+	for _, info := range sessions {
+		assert.NotEqual(t, info.SessionId, info.Handle(), "handle 不能是 session id 本身")
+		if info.SessionId == "session-2" {
+			handle = info.Handle()
+		}
+	}
+
+	// 其他用户的 handle 不能注销
+	others, err := s.ListSessions(ctx, 2)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.RevokeSession(ctx, 1, others[0].Handle()), ErrSessionNotFound)
+
+	require.NoError(t, s.RevokeSession(ctx, 1, handle))
+	_, err = s.GetSessionUser(ctx, "session-2")
+	assert.Error(t, err, "注销后 session 应该失效")
+	_, err = s.GetSessionUser(ctx, "session-1")
+	assert.NoError(t, err)
+}
+
+func TestRevokeOtherSessionsKeepsCurrent(t *testing.T) {
+	s := newSessionAuthService(t)
+	ctx := context.Background()
+	addSession(t, s, 1, "session-1", "phone")
+	addSession(t, s, 1, "session-2", "pc")
+	addSession(t, s, 1, "session-3", "pad")
+
+	revoked, err := s.RevokeOtherSessions(ctx, &Caller{UserId: 1, SessionId: "session-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, revoked)
+	_, err = s.GetSessionUser(ctx, "session-1")
+	assert.NoError(t, err, "调用方当前的 session 应该保留")
+
+	// jwt 认证时保留同一设备的 session
+	addSession(t, s, 1, "session-4", "pc")
+	revoked, err = s.RevokeOtherSessions(ctx, &Caller{UserId: 1, DeviceId: "pc"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+	_, err = s.GetSessionUser(ctx, "session-4")
+	assert.NoError(t, err)
+}
@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	regexp "github.com/dlclark/regexp2"
 	"github.com/gin-gonic/gin"
-	auth_def "github.com/mxxmstar/learning/pkg/def/auth"
+	auth_def "github.com/mxxmstar/learning/pkg/def/verify/auth"
 	"github.com/mxxmstar/learning/pkg/logger"
 	"github.com/mxxmstar/learning/verify_server/internal/domain"
 	"github.com/mxxmstar/learning/verify_server/internal/service"
@@ -20,14 +23,17 @@ type AuthHandler struct {
 	emailExp *regexp.Regexp
 	// passwordExp 密码正则表达式
 	passwordExp *regexp.Regexp
+	// internalToken 服务间调用的共享令牌，携带时才信任透传的客户端 IP
+	internalToken string
 }
 
-func NewAuthHandler(authService *service.AuthService, userService *service.UserService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, userService *service.UserService, internalToken string) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		userService: userService,
-		emailExp:    regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`, regexp.None),
-		passwordExp: regexp.MustCompile(`^[a-zA-Z0-9_-]{6,20}$`, regexp.None),
+		authService:   authService,
+		userService:   userService,
+		internalToken: internalToken,
+		emailExp:      regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`, regexp.None),
+		passwordExp:   regexp.MustCompile(`^[a-zA-Z0-9_-]{6,20}$`, regexp.None),
 	}
 }
 
@@ -115,13 +121,23 @@ func (h *AuthHandler) LoginHandler(ctx *gin.Context) {
 	// 创建登录上下文
 	loginCtx := &domain.LoginContext{
 		DeviceId:  req.DeviceId,
-		IPAddress: ctx.ClientIP(),
+		IPAddress: clientIP(ctx, h.internalToken),
 		// UserAgent: ctx.GetHeader("User-Agent"),
 		UserAgent: ctx.Request.UserAgent(),
 	}
 
 	// 传统 session 登录方式
 	sessionId, err := h.authService.LoginByEmail(ctx, req.Email, req.Password, loginCtx)
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, response.ErrorResponse("too many login attempts, please try again later", nil))
+		return
+	}
+	if errors.Is(err, service.ErrUserDisabled) {
+		ctx.JSON(http.StatusOK, response.ErrorResponse("user account is disabled", nil))
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusOK, response.ErrorResponse("invalid username or password", nil))
 		return
@@ -140,11 +156,20 @@ func (h *AuthHandler) LoginHandler(ctx *gin.Context) {
 		return
 	}
 
+	refreshToken, err := h.authService.IssueRefreshToken(ctx, user, loginCtx)
+	if err != nil {
+		ctx.JSON(http.StatusOK, response.ErrorResponse("login success but failed to generate refresh token", nil))
+		return
+	}
+
 	responseData := map[string]interface{}{
 		"sessionId": sessionId,
 		"jwtToken":  jwtToken,
 		"userId":    user.Id,
 	}
+	if refreshToken != "" {
+		responseData["refreshToken"] = refreshToken
+	}
 
 	ctx.Header("x-jwt-token", jwtToken) // 将 JWT 令牌添加到响应头中
 	ctx.JSON(http.StatusOK, response.SuccessResponse("login success", responseData))
@@ -167,7 +192,7 @@ func (h *AuthHandler) VerifySessionHandler(ctx *gin.Context) {
 	}
 
 	// 从 Redis 中获取用户信息
-	user, err := h.authService.GetSessionUser(ctx, req.SessionId)
+	user, err := h.authService.VerifySession(ctx, req.SessionId)
 	if err != nil {
 		ctx.JSON(http.StatusOK, auth_def.VerifySessionResponse{
 			Valid: false,
@@ -193,7 +218,7 @@ func (h *AuthHandler) VerifyJWTHandler(ctx *gin.Context) {
 	}
 
 	// 验证 JWT 令牌
-	claims, err := h.authService.ValidateAndParseJWT(req.JWTToken)
+	claims, err := h.authService.ValidateAndParseJWT(ctx, req.JWTToken)
 	if err != nil {
 		ctx.JSON(http.StatusOK, auth_def.VerifyJWTResponse{
 			Valid: false,
@@ -202,10 +227,16 @@ func (h *AuthHandler) VerifyJWTHandler(ctx *gin.Context) {
 		return
 	}
 
+	var issuedAtMs int64
+	if issuedAt := claims.IssuedAtTime(); !issuedAt.IsZero() {
+		issuedAtMs = issuedAt.UnixMilli()
+	}
 	ctx.JSON(http.StatusOK, auth_def.VerifyJWTResponse{
-		Valid:    true,
-		UserId:   claims.UserId,
-		DeviceId: claims.DeviceId,
+		Valid:      true,
+		UserId:     claims.UserId,
+		DeviceId:   claims.DeviceId,
+		TokenId:    claims.ID,
+		IssuedAtMs: issuedAtMs,
 	})
 }
 
@@ -234,3 +265,198 @@ func (h *AuthHandler) RefreshSessionHandler(ctx *gin.Context) {
 		Success: true,
 	})
 }
+
+// 使用刷新令牌换取新的 JWT
+func (h *AuthHandler) RefreshJWTHandler(ctx *gin.Context) {
+	var req auth_def.RefreshJWTRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, auth_def.RefreshJWTResponse{
+			Success: false,
+			Error:   "invalid request",
+		})
+		return
+	}
+
+	jwtToken, refreshToken, userId, err := h.authService.RefreshJWT(ctx, req.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusOK, auth_def.RefreshJWTResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx.Header("x-jwt-token", jwtToken)
+	ctx.JSON(http.StatusOK, auth_def.RefreshJWTResponse{
+		Success:      true,
+		JWTToken:     jwtToken,
+		RefreshToken: refreshToken,
+		UserId:       userId,
+		ExpiresIn:    int64(h.authService.TokenLifetime()),
+	})
+}
+
+// 登出，删除 session 并吊销 jwt
+func (h *AuthHandler) LogoutHandler(ctx *gin.Context) {
+	var req auth_def.LogoutRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, auth_def.LogoutResponse{
+			Success: false,
+			Error:   "invalid request",
+		})
+		return
+	}
+
+	if err := h.authService.Logout(ctx, req.SessionId, req.JWTToken); err != nil {
+		ctx.JSON(http.StatusOK, auth_def.LogoutResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, auth_def.LogoutResponse{
+		Success: true,
+	})
+}
+
+// 吊销用户已签发的所有令牌
+func (h *AuthHandler) RevokeUserTokensHandler(ctx *gin.Context) {
+	var req auth_def.RevokeUserTokensRequest
+	if err := ctx.Bind(&req); err != nil || req.UserId == 0 {
+		ctx.JSON(http.StatusBadRequest, auth_def.RevokeUserTokensResponse{
+			Success: false,
+			Error:   "invalid request",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeUserTokens(ctx, req.UserId); err != nil {
+		ctx.JSON(http.StatusOK, auth_def.RevokeUserTokensResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, auth_def.RevokeUserTokensResponse{
+		Success: true,
+	})
+}
+
+// 列出调用方所有登录中的设备，用户由请求携带的 session 或 jwt 确定
+func (h *AuthHandler) ListSessionsHandler(ctx *gin.Context) {
+	var req auth_def.ListSessionsRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, auth_def.ListSessionsResponse{
+			Error: "invalid request",
+		})
+		return
+	}
+
+	caller, err := h.authService.Authenticate(ctx, req.SessionId, req.JWTToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, auth_def.ListSessionsResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(ctx, caller.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusOK, auth_def.ListSessionsResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	res := auth_def.ListSessionsResponse{
+		Sessions: make([]auth_def.SessionInfo, 0, len(sessions)),
+	}
+	for _, info := range sessions {
+		res.Sessions = append(res.Sessions, auth_def.SessionInfo{
+			Handle:     info.Handle(),
+			DeviceId:   info.DeviceId,
+			IPAddress:  info.IPAddress,
+			UserAgent:  info.UserAgent,
+			CreatedAt:  info.CreatedAt.Unix(),
+			LastSeenAt: info.LastSeenAt.Unix(),
+			Current:    caller.IsCurrent(info),
+		})
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// 注销调用方的某个 session
+func (h *AuthHandler) RevokeSessionHandler(ctx *gin.Context) {
+	var req auth_def.RevokeSessionRequest
+	if err := ctx.Bind(&req); err != nil || req.Handle == "" {
+		ctx.JSON(http.StatusBadRequest, auth_def.RevokeSessionResponse{
+			Success: false,
+			Error:   "invalid request",
+		})
+		return
+	}
+
+	caller, err := h.authService.Authenticate(ctx, req.SessionId, req.JWTToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, auth_def.RevokeSessionResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.RevokeSession(ctx, caller.UserId, req.Handle); err != nil {
+		ctx.JSON(http.StatusOK, auth_def.RevokeSessionResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, auth_def.RevokeSessionResponse{
+		Success: true,
+	})
+}
+
+// 注销调用方除当前登录以外的所有 session
+func (h *AuthHandler) RevokeOtherSessionsHandler(ctx *gin.Context) {
+	var req auth_def.RevokeOtherSessionsRequest
+	if err := ctx.Bind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, auth_def.RevokeOtherSessionsResponse{
+			Success: false,
+			Error:   "invalid request",
+		})
+		return
+	}
+
+	caller, err := h.authService.Authenticate(ctx, req.SessionId, req.JWTToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, auth_def.RevokeOtherSessionsResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(ctx, caller)
+	if err != nil {
+		ctx.JSON(http.StatusOK, auth_def.RevokeOtherSessionsResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, auth_def.RevokeOtherSessionsResponse{
+		Success: true,
+		Revoked: revoked,
+	})
+}
+
+// 公开 jwt 验签公钥
+func (h *AuthHandler) JWKSHandler(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.authService.JWKS())
+}
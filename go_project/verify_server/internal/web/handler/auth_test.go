@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	auth_def "github.com/mxxmstar/learning/pkg/def/verify/auth"
+	"github.com/mxxmstar/learning/pkg/encrypt"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/mxxmstar/learning/pkg/store/redis"
+	"github.com/mxxmstar/learning/verify_server/internal/repository"
+	"github.com/mxxmstar/learning/verify_server/internal/repository/dao"
+	"github.com/mxxmstar/learning/verify_server/internal/repository/dao/daotest"
+	"github.com/mxxmstar/learning/verify_server/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewRedisClient(mr.Addr(), "", 0)
+	keys, err := jwt_manager.LoadKeySet(jwt_manager.KeySetConfig{}, []byte("secret"))
+	require.NoError(t, err)
+	repo := repository.NewUserRepository(dao.NewUserDAO(daotest.NewMemDB()))
+	limiter := service.NewLoginLimiter(redisClient, service.LoginLimiterConfig{
+		MaxAttemptsPerEmail: 100,
+		MaxAttemptsPerIP:    2,
+		Window:              time.Minute,
+	})
+	authService := service.NewAuthService(repo, redisClient, encrypt.NewBcryptHasher(bcrypt.MinCost), limiter, nil, nil, keys, 0)
+	h := NewAuthHandler(authService, service.NewUserService(repo), "internal")
+
+	engine := gin.New()
+	require.NoError(t, engine.SetTrustedProxies(nil))
+	engine.POST("/login", h.LoginHandler)
+	login := func(i int, forwarded, token string) int {
+		body, err := json.Marshal(map[string]string{"email": fmt.Sprintf("user%d@example.com", i), "password": "123456"})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwarded)
+		if token != "" {
+			req.Header.Set(auth_def.InternalTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 同一个对端地址轮换 X-Forwarded-For 与邮箱，仍然共用一个 IP 的配额
+	assert.Equal(t, http.StatusOK, login(1, "1.1.1.1", ""))
+	assert.Equal(t, http.StatusOK, login(2, "2.2.2.2", "wrong"))
+	assert.Equal(t, http.StatusTooManyRequests, login(3, "3.3.3.3", ""), "伪造的 X-Forwarded-For 不能重置按 IP 的配额")
+
+	// 携带共享令牌的内部服务透传的 IP 可信，按客户端 IP 单独计数
+	assert.Equal(t, http.StatusOK, login(4, "4.4.4.4", "internal"))
+	assert.Equal(t, http.StatusOK, login(5, "4.4.4.4", "internal"))
+	assert.Equal(t, http.StatusTooManyRequests, login(6, "4.4.4.4", "internal"), "透传的客户端 IP 应该参与限流")
+}
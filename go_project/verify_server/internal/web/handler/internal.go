@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	auth_def "github.com/mxxmstar/learning/pkg/def/verify/auth"
+	"github.com/mxxmstar/learning/pkg/internalauth"
+)
+
+// isInternal 请求是否携带正确的共享令牌
+func isInternal(ctx *gin.Context, token string) bool {
+	return internalauth.Valid(ctx.GetHeader(auth_def.InternalTokenHeader), token)
+}
+
+// clientIP 客户端 IP，只有携带共享令牌的内部服务透传的 X-Forwarded-For 可信，其他请求使用对端地址
+// 否则任何人都可以伪造该请求头绕过按 IP 的登录限流
+func clientIP(ctx *gin.Context, token string) string {
+	if isInternal(ctx, token) {
+		forwarded, _, _ := strings.Cut(ctx.GetHeader("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded); ip != "" {
+			return ip
+		}
+	}
+	return ctx.RemoteIP()
+}
+
+// InternalAuth 校验服务间调用携带的共享令牌，用于登出、吊销等只允许 gate 等内部服务调用的接口
+// 未配置令牌时拒绝所有请求
+func InternalAuth(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !isInternal(ctx, token) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "unauthorized"})
+			return
+		}
+		ctx.Next()
+	}
+}
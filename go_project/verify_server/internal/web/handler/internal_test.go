@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	auth_def "github.com/mxxmstar/learning/pkg/def/verify/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newEngine := func(token string) *gin.Engine {
+		engine := gin.New()
+		engine.POST("/revoke", InternalAuth(token), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+		return engine
+	}
+	do := func(engine *gin.Engine, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/revoke", nil)
+		if token != "" {
+			req.Header.Set(auth_def.InternalTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	engine := newEngine("secret")
+	assert.Equal(t, http.StatusUnauthorized, do(engine, ""), "没有令牌的请求应该被拒绝")
+	assert.Equal(t, http.StatusUnauthorized, do(engine, "wrong"), "错误令牌的请求应该被拒绝")
+	assert.Equal(t, http.StatusOK, do(engine, "secret"))
+
+	assert.Equal(t, http.StatusUnauthorized, do(newEngine(""), ""), "未配置令牌时应该拒绝所有请求")
+}
@@ -36,6 +36,10 @@ func RegisterRoutes(server *gin.Engine, cfg *verify_config.Config) {
 
 func InitWebServer(cfg *verify_config.Config) *gin.Engine {
 	server := gin.Default()
+	// 不信任任何代理的 X-Forwarded-For，gate 透传的客户端 IP 只在携带共享令牌时使用，见 handler.clientIP
+	if err := server.SetTrustedProxies(nil); err != nil {
+		panic(err)
+	}
 	server.Use(cors.New(cors.Config{
 		// AllowOrigins: []string{"http://localhost:3000"},
 		// 不写就默认所有请求
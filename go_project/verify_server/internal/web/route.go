@@ -2,9 +2,13 @@ package web
 
 import (
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mxxmstar/learning/pkg/database"
+	"github.com/mxxmstar/learning/pkg/encrypt"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/mxxmstar/learning/pkg/revocation"
 	"github.com/mxxmstar/learning/pkg/store/redis"
 	"github.com/mxxmstar/learning/verify_server/internal/repository"
 	"github.com/mxxmstar/learning/verify_server/internal/repository/dao"
@@ -19,12 +23,48 @@ func RegisterUserRoutes(server *gin.Engine, cfg *verify_config.Config, db databa
 	userDAO := dao.NewUserDAO(db)
 	userRepo := repository.NewUserRepository(userDAO)
 
+	// 初始化密码哈希器，存量明文密码在登录时自动升级
+	hasher, err := encrypt.NewPasswordHasher(cfg.VerifyService.PasswordHashAlgo, cfg.VerifyService.BcryptCost, cfg.VerifyService.Argon2)
+	if err != nil {
+		panic(err)
+	}
+	hasher.AllowPlaintext(true)
+
+	// 初始化登录限流器
+	var loginLimiter *service.LoginLimiter
+	if limitCfg := cfg.VerifyService.LoginLimit; limitCfg.Enable {
+		loginLimiter = service.NewLoginLimiter(redisClient, service.LoginLimiterConfig{
+			MaxAttemptsPerEmail: limitCfg.MaxAttemptsPerEmail,
+			MaxAttemptsPerIP:    limitCfg.MaxAttemptsPerIP,
+			Window:              limitCfg.Window,
+			Lockout:             limitCfg.Lockout,
+		})
+	}
+
+	// 初始化刷新令牌存储
+	var refreshTokens *service.RefreshTokenStore
+	if cfg.VerifyService.RefreshToken {
+		refreshTokens = service.NewRefreshTokenStore(redisClient, time.Duration(cfg.VerifyService.RefreshTokenLifeTime)*time.Second)
+	}
+
+	// 初始化凭证吊销列表，用户级吊销记录至少保留一个访问令牌有效期
+	var revocations *revocation.Store
+	if cfg.VerifyService.TokenRevocation {
+		revocations = revocation.NewStore(redisClient, time.Duration(cfg.VerifyService.TokenLifeTime)*time.Second)
+	}
+
+	// 加载 jwt 密钥
+	jwtKeys, err := jwt_manager.LoadKeySet(cfg.VerifyService.JWTKeys, []byte(cfg.VerifyService.JWTSecret))
+	if err != nil {
+		panic(err)
+	}
+
 	// 初始化服务
-	authService := service.NewAuthService(userRepo, redisClient, cfg.VerifyService.JWTSecret, cfg.VerifyService.TokenLifeTime)
+	authService := service.NewAuthService(userRepo, redisClient, hasher, loginLimiter, refreshTokens, revocations, jwtKeys, cfg.VerifyService.TokenLifeTime)
 	userService := service.NewUserService(userRepo)
 
 	// 注册用户验证处理器
-	authHandler := handler.NewAuthHandler(authService, userService)
+	authHandler := handler.NewAuthHandler(authService, userService, cfg.ServerConfig.GlobalConfig.InternalToken)
 	// 注册用户处理器
 	userHandler := handler.NewUserHandler(userService)
 
@@ -40,13 +80,24 @@ func RegisterUserRoutes(server *gin.Engine, cfg *verify_config.Config, db databa
 	}
 
 	// 注册用户注册相关路由（与 gate 通信）
+	// 登出和吊销会使其他凭证失效，只允许携带共享令牌的内部服务调用
+	internalAuth := handler.InternalAuth(cfg.ServerConfig.GlobalConfig.InternalToken)
 	gateAuthGroup := server.Group("gate/user-auth")
 	{
 		gateAuthGroup.POST("/verify-session", authHandler.VerifySessionHandler)
 		gateAuthGroup.POST("/verify-jwt", authHandler.VerifyJWTHandler)
 		gateAuthGroup.POST("/refresh-session", authHandler.RefreshSessionHandler)
+		gateAuthGroup.POST("/refresh-jwt", authHandler.RefreshJWTHandler)
+		gateAuthGroup.POST("/logout", internalAuth, authHandler.LogoutHandler)
+		gateAuthGroup.POST("/revoke-user-tokens", internalAuth, authHandler.RevokeUserTokensHandler)
+		gateAuthGroup.POST("/sessions", authHandler.ListSessionsHandler)
+		gateAuthGroup.POST("/revoke-session", authHandler.RevokeSessionHandler)
+		gateAuthGroup.POST("/revoke-other-sessions", authHandler.RevokeOtherSessionsHandler)
 	}
 
+	// 公开 jwt 验签公钥，供 gate 等服务本地验签
+	server.GET("/.well-known/jwks.json", authHandler.JWKSHandler)
+
 	// 注册用户相关路由（测试用）
 	if cfg.ServerConfig.GlobalConfig.Env == "test" {
 		userGroup := server.Group("/user")
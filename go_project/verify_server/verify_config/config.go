@@ -1,15 +1,43 @@
 package verify_config
 
 import (
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/mxxmstar/learning/pkg/config"
+	"github.com/mxxmstar/learning/pkg/encrypt"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/mxxmstar/learning/pkg/ratelimit"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// 默认的 HS256 密钥，只允许在 test 环境使用
+const insecureJWTSecret = "secret"
+
+// 非 test 环境 HS256 密钥的最小长度
+const minJWTSecretLength = 32
+
 type VerifyServiceConfig struct {
-	JWTSecret     string `mapstructure:"jwt_secret"`     // jwt密钥
-	TokenLifeTime int    `mapstructure:"token_lifetime"` // token有效期
-	RefreshToken  bool   `mapstructure:"refresh_token"`  // 是否允许刷新token
+	JWTSecret            string                   `mapstructure:"jwt_secret"`             // jwt密钥，仅 HS256 使用
+	JWTKeys              jwt_manager.KeySetConfig `mapstructure:"jwt_keys"`               // jwt签名算法与密钥文件，支持 RS256/EdDSA 及多验签密钥轮换
+	TokenLifeTime        int                      `mapstructure:"token_lifetime"`         // 访问token有效期，单位秒，开启刷新token时应设置得较短
+	RefreshToken         bool                     `mapstructure:"refresh_token"`          // 是否允许刷新token
+	TokenRevocation      bool                     `mapstructure:"token_revocation"`       // 是否开启jwt吊销，开启后验证jwt需要查询redis
+	RefreshTokenLifeTime int                      `mapstructure:"refresh_token_lifetime"` // 刷新token族的有效期，单位秒，轮换不会延长
+	PasswordHashAlgo     string                   `mapstructure:"password_hash_algo"`     // 新密码使用的哈希算法 bcrypt/argon2id
+	BcryptCost           int                      `mapstructure:"bcrypt_cost"`            // 密码哈希的 bcrypt cost，登录时不同于该值的存量密码会被重新哈希
+	Argon2               encrypt.Argon2Params     `mapstructure:"argon2"`                 // argon2id 参数
+	LoginLimit           LoginLimitConfig         `mapstructure:"login_limit"`            // 登录限流配置
+}
+
+// LoginLimitConfig 登录限流配置，同时作用于 gRPC 和 HTTP 登录入口
+type LoginLimitConfig struct {
+	Enable              bool                    `mapstructure:"enable"`                 // 是否开启登录限流
+	MaxAttemptsPerEmail int                     `mapstructure:"max_attempts_per_email"` // 窗口内每个邮箱允许的登录次数
+	MaxAttemptsPerIP    int                     `mapstructure:"max_attempts_per_ip"`    // 窗口内每个 IP 允许的登录次数
+	Window              time.Duration           `mapstructure:"window"`                 // 限流窗口
+	Lockout             ratelimit.LockoutConfig `mapstructure:"lockout"`                // 连续失败锁定配置
 }
 
 type Config struct {
@@ -29,11 +57,32 @@ func Init() (*Config, error) {
 		ServerConfig: &baseCfg.Server,
 		Database:     baseCfg.Database,
 		Redis:        baseCfg.Redis,
-		// TODO: 从配置文件中读取,密钥由密钥管理服务生成
+		// 默认的 HS256 密钥只能用于 test 环境，其他环境必须在配置文件中配置 jwt_keys，推荐 EdDSA，见 pkg/config/config.yaml
 		VerifyService: VerifyServiceConfig{
-			JWTSecret:     "secret",
-			TokenLifeTime: 86400,
-			RefreshToken:  true,
+			JWTSecret: insecureJWTSecret,
+			JWTKeys: jwt_manager.KeySetConfig{
+				Algorithm: jwt_manager.AlgorithmHS256,
+			},
+			TokenLifeTime:        900,
+			RefreshToken:         true,
+			TokenRevocation:      true,
+			RefreshTokenLifeTime: 30 * 86400,
+			PasswordHashAlgo:     encrypt.AlgorithmBcrypt,
+			BcryptCost:           bcrypt.DefaultCost,
+			Argon2:               encrypt.DefaultArgon2Params,
+			LoginLimit: LoginLimitConfig{
+				Enable:              true,
+				MaxAttemptsPerEmail: 10,
+				MaxAttemptsPerIP:    50,
+				Window:              time.Minute,
+				Lockout: ratelimit.LockoutConfig{
+					MaxFailures:   5,
+					FailureWindow: 15 * time.Minute,
+					BaseDuration:  time.Minute,
+					MaxDuration:   time.Hour,
+					ResetAfter:    24 * time.Hour,
+				},
+			},
 		},
 	}
 
@@ -41,7 +90,34 @@ func Init() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	// 打印配置
 	log.Printf("Config: %+v\n", cfg)
 	return cfg, nil
 }
+
+// Validate 检查 jwt 密钥配置，非 test 环境拒绝使用默认密钥或过短的 HS256 密钥
+func (c *Config) Validate() error {
+	if c.ServerConfig != nil && c.ServerConfig.GlobalConfig.Env == "test" {
+		return nil
+	}
+	keys := c.VerifyService.JWTKeys
+	if keys.Algorithm != "" && keys.Algorithm != jwt_manager.AlgorithmHS256 {
+		return nil
+	}
+	secret := c.VerifyService.JWTSecret
+	if secret == insecureJWTSecret || len(secret) < minJWTSecretLength {
+		return fmt.Errorf("verify_service.jwt_secret must be set to at least %d bytes outside the test env, or configure asymmetric verify_service.jwt_keys", minJWTSecretLength)
+	}
+	return nil
+}
+
+// VerifyServer 当前 verify 服务的配置，使用配置中的第一个 verify 服务
+func (c *Config) VerifyServer() (*config.VerifyServerConfig, error) {
+	if c.ServerConfig == nil || len(c.ServerConfig.VerifyServers) == 0 {
+		return nil, fmt.Errorf("no verify server in config")
+	}
+	return &c.ServerConfig.VerifyServers[0], nil
+}
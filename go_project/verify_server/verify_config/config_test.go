@@ -0,0 +1,30 @@
+package verify_config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mxxmstar/learning/pkg/config"
+	jwt_manager "github.com/mxxmstar/learning/pkg/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateJWTSecret(t *testing.T) {
+	newConfig := func(env, secret, algorithm string) *Config {
+		serverCfg := &config.ServerConfig{}
+		serverCfg.GlobalConfig.Env = env
+		return &Config{
+			ServerConfig: serverCfg,
+			VerifyService: VerifyServiceConfig{
+				JWTSecret: secret,
+				JWTKeys:   jwt_manager.KeySetConfig{Algorithm: algorithm},
+			},
+		}
+	}
+
+	assert.NoError(t, newConfig("test", insecureJWTSecret, jwt_manager.AlgorithmHS256).Validate(), "test 环境允许默认密钥")
+	assert.Error(t, newConfig("prod", insecureJWTSecret, jwt_manager.AlgorithmHS256).Validate(), "非 test 环境不能使用默认密钥")
+	assert.Error(t, newConfig("prod", "short", "").Validate(), "非 test 环境不能使用过短的密钥")
+	assert.NoError(t, newConfig("prod", strings.Repeat("k", minJWTSecretLength), jwt_manager.AlgorithmHS256).Validate())
+	assert.NoError(t, newConfig("prod", insecureJWTSecret, jwt_manager.AlgorithmEdDSA).Validate(), "非对称密钥不使用 jwt_secret")
+}
@@ -35,10 +35,10 @@ func InitDB(cfg *Config) (database.DBInterface, error) {
 	}
 
 	// 设置连接池
-	sqlDB.SetMaxOpenConns(cfg.Database.Pool.MaxOpenConns)
-	sqlDB.SetMaxIdleConns(cfg.Database.Pool.MaxIdleConns)
-	sqlDB.SetConnMaxLifetime(cfg.Database.Pool.ConnMaxLifetime)
-	sqlDB.SetConnMaxIdleTime(cfg.Database.Pool.ConnMaxIdleTime)
+	sqlDB.SetMaxOpenConns(cfg.Database.AuthDB.Pool.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.AuthDB.Pool.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.AuthDB.Pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.Database.AuthDB.Pool.ConnMaxIdleTime)
 
 	db := database.NewGORMWrapper(g)
 	return db, nil